@@ -24,7 +24,7 @@ CREATE DATABASE oeasy_nft;
 --   - maker: 订单创建者地址
 --   - nonce: 防重放攻击的唯一标识
 --   - side: 订单方向 (ask=卖单, bid=买单)
---   - status: 订单状态 (active=活跃, filled=已成交, cancelled=已取消)
+--   - status: 订单状态 (active=活跃, settling=已成交待确认, filled=已成交, cancelled=已取消, invalid=链上已不可成交, unfunded=买方余额或授权不足)
 -- ============================================
 
 CREATE TABLE IF NOT EXISTS orders (
@@ -47,7 +47,8 @@ CREATE TABLE IF NOT EXISTS orders (
     nonce NUMERIC(78, 0) NOT NULL,                 -- 唯一 nonce (防重放)
     side VARCHAR(4) NOT NULL CHECK (side IN ('ask', 'bid')),  -- 订单方向
     status VARCHAR(16) NOT NULL DEFAULT 'active'   -- 订单状态
-        CHECK (status IN ('active', 'filled', 'cancelled')),
+        CHECK (status IN ('active', 'settling', 'filled', 'cancelled', 'invalid', 'unfunded')),
+    invalid_reason VARCHAR(255),                   -- 订单不可成交原因（status=invalid / unfunded 时填写）
     
     -- 签名和哈希
     signature VARCHAR(132) NOT NULL,               -- EIP-712 签名 (0x + 130 字符)
@@ -80,7 +81,8 @@ COMMENT ON COLUMN orders.price IS '订单价格（wei 单位）';
 COMMENT ON COLUMN orders.expiry IS '订单过期时间';
 COMMENT ON COLUMN orders.nonce IS '唯一 nonce，防止重放攻击';
 COMMENT ON COLUMN orders.side IS '订单方向: ask=卖单, bid=买单';
-COMMENT ON COLUMN orders.status IS '订单状态: active=活跃, filled=已成交, cancelled=已取消';
+COMMENT ON COLUMN orders.status IS '订单状态: active=活跃, settling=已成交待确认, filled=已成交, cancelled=已取消, invalid=链上已不可成交, unfunded=买方余额或授权不足';
+COMMENT ON COLUMN orders.invalid_reason IS '订单失效原因（如 nonce 已消费、卖方不再持有 NFT、买方余额不足）';
 COMMENT ON COLUMN orders.signature IS 'EIP-712 签名';
 COMMENT ON COLUMN orders.hash IS '订单哈希值';
 
@@ -100,11 +102,16 @@ CREATE TABLE IF NOT EXISTS trade_events (
     transaction_hash VARCHAR(66) NOT NULL,         -- 交易哈希
     log_index INTEGER NOT NULL,                    -- 日志索引（同一交易可能有多个事件）
     block_number BIGINT NOT NULL,                  -- 区块号
+    block_hash VARCHAR(66),                        -- 区块哈希（用于链重组检测）
     
     -- 交易参与方
     maker VARCHAR(66) NOT NULL,                    -- 卖方地址
     taker VARCHAR(66) NOT NULL,                    -- 买方地址
     
+    -- 成交订单（解码 executeTrade 输入后重新计算的 EIP-712 哈希，关联 orders.hash）
+    maker_order_hash VARCHAR(66),                  -- maker 订单哈希（无法解码时为空）
+    taker_order_hash VARCHAR(66),                  -- taker 订单哈希（无法解码时为空）
+    
     -- NFT 信息
     nft_address VARCHAR(66) NOT NULL,              -- NFT 合约地址
     token_id NUMERIC(78, 0) NOT NULL,              -- NFT Token ID
@@ -117,6 +124,11 @@ CREATE TABLE IF NOT EXISTS trade_events (
     side SMALLINT NOT NULL CHECK (side IN (0, 1)), -- 订单方向 (0=Ask, 1=Bid)
     fee NUMERIC(78, 0) NOT NULL,                   -- 平台手续费
     
+    -- 最终性
+    status VARCHAR(16) NOT NULL DEFAULT 'confirmed'
+        CHECK (status IN ('pending', 'confirmed')),  -- 确认数不足为 pending，达到 INDEXER_CONFIRMATIONS 后为 confirmed
+    confirmed_at TIMESTAMP,                        -- 达到确认深度的时间
+    
     -- 时间戳
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     
@@ -131,20 +143,27 @@ CREATE INDEX idx_trade_events_maker ON trade_events(maker);             -- 按
 CREATE INDEX idx_trade_events_taker ON trade_events(taker);             -- 按买方查询
 CREATE INDEX idx_trade_events_nft ON trade_events(nft_address);         -- 按 NFT 查询
 CREATE INDEX idx_trade_events_created_at ON trade_events(created_at DESC); -- 按时间倒序
+CREATE INDEX idx_trade_events_status ON trade_events(status);            -- 查找待确认事件
+CREATE INDEX idx_trade_events_maker_order ON trade_events(maker_order_hash); -- 按订单查成交
+CREATE INDEX idx_trade_events_taker_order ON trade_events(taker_order_hash); -- 按订单查成交
 
 -- 添加表注释
 COMMENT ON TABLE trade_events IS '交易事件表 - 记录链上执行的 TradeExecuted 事件';
 COMMENT ON COLUMN trade_events.transaction_hash IS '交易哈希';
 COMMENT ON COLUMN trade_events.log_index IS '事件在交易中的索引';
 COMMENT ON COLUMN trade_events.block_number IS '区块号';
+COMMENT ON COLUMN trade_events.block_hash IS '区块哈希，链重组回滚时按区块号删除';
 COMMENT ON COLUMN trade_events.maker IS '卖方地址';
 COMMENT ON COLUMN trade_events.taker IS '买方地址';
+COMMENT ON COLUMN trade_events.maker_order_hash IS 'maker 订单哈希，关联 orders.hash（成交订单可能不在本平台订单表中，因此不加外键约束）';
+COMMENT ON COLUMN trade_events.taker_order_hash IS 'taker 订单哈希，关联 orders.hash';
 COMMENT ON COLUMN trade_events.nft_address IS 'NFT 合约地址';
 COMMENT ON COLUMN trade_events.token_id IS 'NFT Token ID';
 COMMENT ON COLUMN trade_events.payment_token IS '支付代币地址';
 COMMENT ON COLUMN trade_events.price IS '成交价格（wei）';
 COMMENT ON COLUMN trade_events.side IS '订单方向: 0=Ask, 1=Bid';
 COMMENT ON COLUMN trade_events.fee IS '平台手续费';
+COMMENT ON COLUMN trade_events.status IS '最终性: pending=确认数不足（可能被链重组撤销）, confirmed=已确认';
 
 -- ============================================
 -- 表 3: indexer_status (索引器状态表)
@@ -173,6 +192,422 @@ ON CONFLICT (id) DO NOTHING;
 COMMENT ON TABLE indexer_status IS '索引器状态表 - 记录区块同步进度';
 COMMENT ON COLUMN indexer_status.last_processed_block IS '最后处理的区块号，用于断点续传';
 
+-- ============================================
+-- 表 4: executions (执行交易表)
+-- ============================================
+-- 功能: 记录执行服务广播的每一笔撮合交易及其最终状态
+-- 数据源: 执行服务提交交易后写入，回执追踪器轮询后更新
+-- 用途: 交易生命周期追踪、排查卡住/回滚/丢弃的交易
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS executions (
+    -- 主键
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 交易信息
+    tx_hash VARCHAR(66) NOT NULL UNIQUE,           -- 交易哈希
+    executor VARCHAR(42) NOT NULL,                 -- 执行钱包地址
+    nonce BIGINT NOT NULL,                         -- 交易 nonce
+    gas_limit BIGINT NOT NULL,                     -- Gas 上限
+    gas_price NUMERIC(78, 0) NOT NULL,             -- Gas 价格（wei，EIP-1559 交易为 maxFeePerGas）
+    gas_tip_cap NUMERIC(78, 0),                    -- EIP-1559 maxPriorityFeePerGas（legacy 交易为空）
+    
+    -- 撮合订单
+    maker_order_hash VARCHAR(66) NOT NULL,         -- 卖单（maker）哈希
+    taker_order_hash VARCHAR(66) NOT NULL,         -- 买单（taker）哈希
+    execution_key VARCHAR(66) NOT NULL,            -- 订单对幂等键 keccak256(maker_order_hash || taker_order_hash)
+    nft_address VARCHAR(66) NOT NULL,              -- NFT 合约地址
+    token_id NUMERIC(78, 0) NOT NULL,              -- NFT Token ID
+    
+    -- 执行结果
+    status VARCHAR(16) NOT NULL DEFAULT 'submitted'
+        CHECK (status IN ('submitted', 'confirmed', 'reverted', 'dropped', 'cancelled')),
+    final_tx_hash VARCHAR(66),                     -- 实际上链的交易哈希（原交易或替换交易）
+    block_number BIGINT,                           -- 打包区块号
+    gas_used BIGINT,                               -- 实际消耗 Gas
+    error TEXT,                                    -- 失败原因
+    
+    -- 时间戳
+    submitted_at TIMESTAMP NOT NULL,               -- 广播时间
+    finalized_at TIMESTAMP,                        -- 确定最终状态的时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX idx_executions_status ON executions(status, submitted_at); -- 追踪器查询待确认交易
+CREATE INDEX idx_executions_executor_nonce ON executions(executor, nonce); -- 按钱包和 nonce 查询
+CREATE INDEX idx_executions_maker_order ON executions(maker_order_hash); -- 按卖单查询
+CREATE INDEX idx_executions_taker_order ON executions(taker_order_hash); -- 按买单查询
+-- 同一订单对只允许一笔有效执行；dropped / cancelled 的交易未上链，允许重新提交
+CREATE UNIQUE INDEX idx_executions_execution_key ON executions(execution_key)
+    WHERE status NOT IN ('dropped', 'cancelled');
+
+-- 添加表注释
+COMMENT ON TABLE executions IS '执行交易表 - 记录撮合交易的提交与链上结果';
+COMMENT ON COLUMN executions.tx_hash IS '交易哈希';
+COMMENT ON COLUMN executions.executor IS '发送交易的执行钱包地址';
+COMMENT ON COLUMN executions.nonce IS '交易 nonce';
+COMMENT ON COLUMN executions.gas_price IS 'Gas 价格（wei）；EIP-1559 交易为 maxFeePerGas';
+COMMENT ON COLUMN executions.gas_tip_cap IS 'EIP-1559 maxPriorityFeePerGas；legacy 交易为空';
+COMMENT ON COLUMN executions.maker_order_hash IS '卖单 EIP-712 哈希';
+COMMENT ON COLUMN executions.taker_order_hash IS '买单 EIP-712 哈希';
+COMMENT ON COLUMN executions.execution_key IS '订单对幂等键，重复的执行请求返回已有交易';
+COMMENT ON COLUMN executions.status IS '执行状态: submitted=已提交, confirmed=已确认, reverted=已回滚, dropped=已丢弃, cancelled=已取消';
+COMMENT ON COLUMN executions.final_tx_hash IS '该 nonce 最终上链的交易哈希';
+COMMENT ON COLUMN executions.error IS '回滚、丢弃或取消的原因';
+
+-- ============================================
+-- 表 5: execution_replacements (交易替换表)
+-- ============================================
+-- 功能: 记录为卡住交易发送的同 nonce 替换交易（提高 gas 重发 / 取消）
+-- 用途: 追踪替换历史，按任一替换交易哈希查询原执行记录
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS execution_replacements (
+    -- 主键
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 关联的执行记录
+    execution_id BIGINT NOT NULL REFERENCES executions(id) ON DELETE CASCADE,
+    
+    -- 替换交易信息
+    tx_hash VARCHAR(66) NOT NULL UNIQUE,           -- 替换交易哈希
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('speedup', 'cancel')), -- 替换类型
+    gas_price NUMERIC(78, 0) NOT NULL,             -- 替换交易的 Gas 价格（wei，EIP-1559 交易为 maxFeePerGas）
+    gas_tip_cap NUMERIC(78, 0),                    -- EIP-1559 maxPriorityFeePerGas（legacy 交易为空）
+    
+    -- 时间戳
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX idx_execution_replacements_execution ON execution_replacements(execution_id); -- 按执行记录查询
+
+-- 添加表注释
+COMMENT ON TABLE execution_replacements IS '交易替换表 - 记录卡住交易的加价重发与取消';
+COMMENT ON COLUMN execution_replacements.kind IS '替换类型: speedup=提高 gas 重发, cancel=0 值自转账取消';
+
+-- ============================================
+-- 表 6: executor_nonces (执行钱包 nonce 表)
+-- ============================================
+-- 功能: 持久化每个执行钱包的下一个可用 nonce
+-- 用途: 以单条原子语句分配 nonce，服务重启或多实例部署时不会重复使用
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS executor_nonces (
+    -- 执行钱包地址（小写）
+    executor VARCHAR(42) PRIMARY KEY,
+    
+    -- 下一个待分配的 nonce
+    next_nonce BIGINT NOT NULL,
+    
+    -- 时间戳
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 添加表注释
+COMMENT ON TABLE executor_nonces IS '执行钱包 nonce 表 - 原子分配下一个 nonce，启动时与链上 pending nonce 同步';
+
+-- ============================================
+-- 表 7: executor_nonce_gaps (nonce 空洞表)
+-- ============================================
+-- 功能: 记录已分配但未能广播、且不是最新分配的 nonce
+-- 用途: 后续分配优先复用空洞；无法复用时由执行服务发送 0 值自转账填补
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS executor_nonce_gaps (
+    executor VARCHAR(42) NOT NULL,                 -- 执行钱包地址（小写）
+    nonce BIGINT NOT NULL,                         -- 空洞 nonce
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    PRIMARY KEY (executor, nonce)
+);
+
+-- 添加表注释
+COMMENT ON TABLE executor_nonce_gaps IS 'nonce 空洞表 - 广播失败释放的 nonce，优先复用，启动同步时清空';
+
+-- ============================================
+-- 表 8: execution_jobs (执行队列表)
+-- ============================================
+-- 功能: 持久化撮合引擎提交的执行请求，由执行服务的 worker 池异步处理
+-- 数据源: /internal/execute 入队（返回 202 和执行 ID），worker 领取后签名广播
+-- 用途: 限制并发与队列容量（超出返回 429），进程重启后任务不丢失
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS execution_jobs (
+    -- 主键（即返回给调用方的执行 ID）
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 请求
+    execution_key VARCHAR(66) NOT NULL,            -- 订单对幂等键，同一订单对只保留一个有效任务
+    request TEXT NOT NULL,                         -- 执行请求 JSON
+    
+    -- 队列状态
+    status VARCHAR(16) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'submitted', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,           -- 已尝试次数
+    tx_hash VARCHAR(66),                           -- 广播的交易哈希（关联 executions.tx_hash）
+    error_code VARCHAR(64),                        -- 最近一次失败的错误码（如合约自定义错误名）
+    error TEXT,                                    -- 最近一次失败原因
+    permanent BOOLEAN NOT NULL DEFAULT FALSE,      -- 最终失败时订单对重新提交是否也不会成功（撮合引擎据此移入死信）
+    
+    -- 时间戳
+    available_at TIMESTAMP NOT NULL,               -- 最早可被领取的时间（重试延迟）
+    started_at TIMESTAMP,                          -- 最近一次被领取的时间
+    finished_at TIMESTAMP,                         -- 提交成功或最终失败的时间
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX idx_execution_jobs_claim ON execution_jobs(status, available_at, id); -- worker 领取任务
+CREATE INDEX idx_execution_jobs_key ON execution_jobs(execution_key); -- 按订单对查询
+-- 每个订单对最多一个排队或处理中的任务，多个执行服务实例并发入队时由此去重
+CREATE UNIQUE INDEX idx_execution_jobs_active_key ON execution_jobs(execution_key)
+    WHERE status IN ('queued', 'running');
+
+-- 添加表注释
+COMMENT ON TABLE execution_jobs IS '执行队列表 - 异步执行请求，worker 池按顺序领取处理';
+COMMENT ON COLUMN execution_jobs.status IS '任务状态: queued=排队中, running=处理中, submitted=已广播, failed=最终失败';
+
+-- ============================================
+-- 表 9: execution_economics (执行盈利性决策表)
+-- ============================================
+-- 功能: 记录每笔成交广播前的 gas 成本与手续费收入对比及策略决定
+-- 数据源: 执行服务在估算 gas 后、分配 nonce 前写入（配置 EXECUTION_PRICE_FEED_FILE 时启用）
+-- 用途: 财务对账，统计平台为成交垫付的 gas 与手续费收入
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS execution_economics (
+    -- 主键
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 关联
+    execution_key VARCHAR(66) NOT NULL,            -- 订单对幂等键（关联 execution_jobs.execution_key）
+    tx_hash VARCHAR(66),                           -- 放行并广播后的交易哈希（关联 executions.tx_hash）
+    
+    -- 收入（支付代币最小单位）
+    payment_token VARCHAR(42) NOT NULL,            -- 支付代币地址
+    price NUMERIC(78, 0) NOT NULL,                 -- 成交价格
+    fee_bps BIGINT,                                -- 链上 feeBps（未设置 feeRecipient 时收入为 0）
+    fee_revenue NUMERIC(78, 0),                    -- 手续费收入 = price * feeBps / 10000
+    
+    -- 成本
+    gas_estimate BIGINT NOT NULL,                  -- EstimateGas 估算值（不含安全余量）
+    gas_price NUMERIC(78, 0),                      -- 当前 gas 价格（wei，base fee + tip）
+    gas_cost_wei NUMERIC(78, 0),                   -- gas 成本（wei）
+    gas_cost_token NUMERIC(78, 0),                 -- gas 成本折算为支付代币最小单位
+    native_usd NUMERIC(38, 8),                     -- 价格源中原生币 USD 价格
+    token_usd NUMERIC(38, 8),                      -- 价格源中支付代币 USD 价格
+    
+    -- 决策
+    policy VARCHAR(16) NOT NULL,                   -- 当时生效的策略
+    decision VARCHAR(16) NOT NULL
+        CHECK (decision IN ('allow', 'reject', 'defer')),
+    reason TEXT,                                   -- 亏损或无法评估的原因
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX idx_execution_economics_key ON execution_economics(execution_key);
+CREATE INDEX idx_execution_economics_tx_hash ON execution_economics(tx_hash);
+CREATE INDEX idx_execution_economics_decision ON execution_economics(decision, created_at);
+CREATE INDEX idx_execution_economics_created_at ON execution_economics(created_at);
+
+-- 添加表注释
+COMMENT ON TABLE execution_economics IS '执行盈利性决策表 - gas 成本与手续费收入对比，用于财务报表';
+COMMENT ON COLUMN execution_economics.decision IS '决策: allow=放行, reject=拒绝, defer=延后到 gas 较低时重试';
+
+-- ============================================
+-- 表 10: indexed_blocks (已索引区块哈希表)
+-- ============================================
+-- 功能: 记录索引器最近处理过的区块哈希（最多 INDEXER_REORG_WINDOW 个）
+-- 用途: 每轮轮询与链上哈希比较，检测链重组并定位分叉点
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS indexed_blocks (
+    block_number BIGINT PRIMARY KEY,               -- 区块号
+    block_hash VARCHAR(66) NOT NULL,               -- 区块哈希
+    parent_hash VARCHAR(66) NOT NULL,              -- 父区块哈希
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 添加表注释
+COMMENT ON TABLE indexed_blocks IS '已索引区块哈希表 - 重组窗口内的区块哈希，超出窗口的记录自动清理';
+
+-- ============================================
+-- 表 11: order_status_changes (订单状态变更表)
+-- ============================================
+-- 功能: 记录索引器根据链上事件对订单状态的每次修改
+-- 用途: 链重组或事件被移除时，按相反顺序恢复订单状态
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS order_status_changes (
+    -- 主键
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 触发修改的事件
+    block_number BIGINT NOT NULL,                  -- 事件所在区块
+    transaction_hash VARCHAR(66) NOT NULL,         -- 事件交易哈希
+    log_index INTEGER NOT NULL,                    -- 事件日志索引
+    
+    -- 状态修改
+    order_id BIGINT NOT NULL,                      -- 订单 ID（关联 orders.id）
+    previous_status VARCHAR(16) NOT NULL,          -- 修改前状态
+    new_status VARCHAR(16) NOT NULL,               -- 修改后状态
+    previous_reason VARCHAR(255) NOT NULL DEFAULT '', -- 修改前的 invalid_reason（回滚时一并恢复）
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX idx_order_status_changes_block ON order_status_changes(block_number);
+CREATE INDEX idx_change_tx_log ON order_status_changes(transaction_hash, log_index);
+CREATE INDEX idx_order_status_changes_order ON order_status_changes(order_id);
+
+-- 添加表注释
+COMMENT ON TABLE order_status_changes IS '订单状态变更表 - 索引器修改订单状态的日志，用于链重组回滚';
+
+-- ============================================
+-- 表 12: cancel_events (链上取消事件表)
+-- ============================================
+-- 功能: 记录 maker 直接调用合约 cancelOrder 产生的 OrderCancelled 事件
+-- 数据源: 智能合约发出的 OrderCancelled(maker, nonce) 事件
+-- 用途: 将对应 (maker, nonce) 的订单标记为 cancelled 并移出 Redis 活跃订单簿
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS cancel_events (
+    -- 主键
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 区块链信息
+    transaction_hash VARCHAR(66) NOT NULL,         -- 交易哈希
+    log_index INTEGER NOT NULL,                    -- 日志索引
+    block_number BIGINT NOT NULL,                  -- 区块号
+    block_hash VARCHAR(66),                        -- 区块哈希
+    
+    -- 取消信息
+    maker VARCHAR(66) NOT NULL,                    -- 订单创建者地址（小写）
+    nonce NUMERIC(78, 0) NOT NULL,                 -- 被取消的 nonce
+    
+    -- 最终性
+    status VARCHAR(16) NOT NULL DEFAULT 'confirmed'
+        CHECK (status IN ('pending', 'confirmed')),
+    confirmed_at TIMESTAMP,                        -- 达到确认深度的时间
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- 唯一约束: 防止同一事件被重复处理
+    CONSTRAINT uk_cancel_events_tx_log UNIQUE (transaction_hash, log_index)
+);
+
+-- 创建索引
+CREATE INDEX idx_cancel_events_block ON cancel_events(block_number);
+CREATE INDEX idx_cancel_events_maker_nonce ON cancel_events(maker, nonce);
+CREATE INDEX idx_cancel_events_status ON cancel_events(status);
+
+-- 添加表注释
+COMMENT ON TABLE cancel_events IS '链上取消事件表 - 记录 OrderCancelled 事件';
+
+-- ============================================
+-- 表 13: nft_transfers (NFT 转移事件表)
+-- ============================================
+-- 功能: 记录 INDEXER_NFT_COLLECTIONS 中 NFT 合约的 Transfer 事件
+-- 数据源: ERC-721 Transfer(from, to, tokenId) 事件
+-- 用途: 维护 token_ownership；链重组删除事件后据此重建持有者
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS nft_transfers (
+    -- 主键
+    id BIGSERIAL PRIMARY KEY,
+    
+    -- 区块链信息
+    transaction_hash VARCHAR(66) NOT NULL,         -- 交易哈希
+    log_index INTEGER NOT NULL,                    -- 日志索引
+    block_number BIGINT NOT NULL,                  -- 区块号
+    block_hash VARCHAR(66),                        -- 区块哈希
+    
+    -- 转移信息
+    nft_address VARCHAR(66) NOT NULL,              -- NFT 合约地址（小写）
+    token_id NUMERIC(78, 0) NOT NULL,              -- Token ID
+    from_address VARCHAR(66) NOT NULL,             -- 转出地址（铸造时为零地址）
+    to_address VARCHAR(66) NOT NULL,               -- 转入地址（销毁时为零地址）
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    -- 唯一约束: 防止同一事件被重复处理
+    CONSTRAINT uk_nft_transfers_tx_log UNIQUE (transaction_hash, log_index)
+);
+
+-- 创建索引
+CREATE INDEX idx_nft_transfers_block ON nft_transfers(block_number);
+CREATE INDEX idx_nft_transfers_token ON nft_transfers(nft_address, token_id);
+
+-- 添加表注释
+COMMENT ON TABLE nft_transfers IS 'NFT 转移事件表 - 记录已跟踪 NFT 合约的 Transfer 事件';
+
+-- ============================================
+-- 表 14: token_ownership (NFT 持有者表)
+-- ============================================
+-- 功能: 记录每个已跟踪 token 的当前持有者
+-- 数据源: nft_transfers 中每个 token 最新的一次转移
+-- 用途: 链下查询持有者；token 离开原持有者时其活跃卖单标记为 invalid
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS token_ownership (
+    nft_address VARCHAR(66) NOT NULL,              -- NFT 合约地址（小写）
+    token_id NUMERIC(78, 0) NOT NULL,              -- Token ID
+    owner VARCHAR(66) NOT NULL,                    -- 当前持有者（小写，销毁后为零地址）
+    
+    -- 最近一次转移（只有更新的转移会覆盖）
+    block_number BIGINT NOT NULL,                  -- 区块号
+    transaction_hash VARCHAR(66) NOT NULL,         -- 交易哈希
+    log_index INTEGER NOT NULL,                    -- 日志索引
+    
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    PRIMARY KEY (nft_address, token_id)
+);
+
+-- 创建索引
+CREATE INDEX idx_token_ownership_owner ON token_ownership(owner);  -- 查询某地址持有的 token
+
+-- 添加表注释
+COMMENT ON TABLE token_ownership IS 'NFT 持有者表 - 由 Transfer 事件维护的 token 当前持有者';
+
+-- ============================================
+-- 表 15: payment_token_balances (买方资金快照表)
+-- ============================================
+-- 功能: 记录买单 maker 在支付代币上的余额和对 marketplace 的授权额度
+-- 数据源: INDEXER_PAYMENT_TOKENS 中代币的 Transfer / Approval 事件触发链上读取
+-- 用途: 无法支付的买单标记为 unfunded，资金恢复后重新变为 active
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS payment_token_balances (
+    token_address VARCHAR(66) NOT NULL,            -- 支付代币地址（小写）
+    holder VARCHAR(66) NOT NULL,                   -- 买单 maker 地址（小写）
+    balance NUMERIC(78, 0) NOT NULL,               -- 代币余额
+    allowance NUMERIC(78, 0) NOT NULL,             -- 对 marketplace 的授权额度
+    
+    -- 触发刷新的事件
+    block_number BIGINT NOT NULL,                  -- 区块号
+    block_hash VARCHAR(66),                        -- 区块哈希（和解轮询据此撤销分叉上的快照）
+    transaction_hash VARCHAR(66) NOT NULL,         -- 交易哈希
+    log_index INTEGER NOT NULL,                    -- 日志索引
+    
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    PRIMARY KEY (token_address, holder)
+);
+
+-- 创建索引
+CREATE INDEX idx_payment_token_balances_block ON payment_token_balances(block_number);  -- 链重组回滚
+
+-- 添加表注释
+COMMENT ON TABLE payment_token_balances IS '买方资金快照表 - 买单 maker 的支付代币余额和授权额度';
+
 -- ============================================
 -- 触发器: 自动更新 updated_at
 -- ============================================
@@ -198,6 +633,18 @@ BEFORE UPDATE ON indexer_status
 FOR EACH ROW
 EXECUTE FUNCTION update_updated_at_column();
 
+-- 为 executions 表创建触发器
+CREATE TRIGGER trg_executions_updated_at
+BEFORE UPDATE ON executions
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- 为 execution_jobs 表创建触发器
+CREATE TRIGGER trg_execution_jobs_updated_at
+BEFORE UPDATE ON execution_jobs
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
 -- ============================================
 -- 视图: 活跃订单视图
 -- ============================================
@@ -315,7 +762,7 @@ BEGIN
     RAISE NOTICE 'Oeasy-NFT 数据库初始化完成！';
     RAISE NOTICE '========================================';
     RAISE NOTICE '已创建:';
-    RAISE NOTICE '  - 3 张表: orders, trade_events, indexer_status';
+    RAISE NOTICE '  - 15 张表: orders, trade_events, indexer_status, executions, execution_replacements, executor_nonces, executor_nonce_gaps, execution_jobs, execution_economics, indexed_blocks, order_status_changes, cancel_events, nft_transfers, token_ownership, payment_token_balances';
     RAISE NOTICE '  - 2 个视图: v_active_orders, v_trade_statistics';
     RAISE NOTICE '  - 2 个函数: get_user_order_stats, cleanup_expired_orders';
     RAISE NOTICE '  - 多个索引以优化查询性能';
@@ -21,7 +21,7 @@ export interface Order {
   expiry: string
   nonce: string
   side: 'ask' | 'bid'
-  status: 'active' | 'cancelled' | 'filled'
+  status: 'active' | 'settling' | 'unfunded' | 'invalid' | 'cancelled' | 'filled'
   signature: string
   hash: string
   createdAt: string
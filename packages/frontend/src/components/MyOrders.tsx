@@ -29,12 +29,17 @@ export function MyOrders() {
     if (!address) return
 
     try {
-      // 获取所有状态的订单（active, filled, cancelled）
+      // 获取所有状态的订单（active, settling, unfunded, invalid, filled, cancelled）
+      // settling 为链上已成交但未达到确认深度的订单，unfunded 为余额或授权不足的买单，
+      // invalid 为链上已不可成交的订单（如卖方已转走 NFT）
       const activeOrders = await fetchOrders({ status: 'active' })
+      const settlingOrders = await fetchOrders({ status: 'settling' })
+      const unfundedOrders = await fetchOrders({ status: 'unfunded' })
+      const invalidOrders = await fetchOrders({ status: 'invalid' })
       const filledOrders = await fetchOrders({ status: 'filled' })
       const cancelledOrders = await fetchOrders({ status: 'cancelled' })
       
-      const allOrders = [...activeOrders, ...filledOrders, ...cancelledOrders]
+      const allOrders = [...activeOrders, ...settlingOrders, ...unfundedOrders, ...invalidOrders, ...filledOrders, ...cancelledOrders]
       
       // 筛选当前用户的订单
       const myOrders = allOrders.filter(order => 
@@ -203,6 +208,9 @@ export function MyOrders() {
                   </span>
                   <span className={`status-badge status-${order.status}`}>
                     {order.status === 'active' ? '活跃' : 
+                     order.status === 'settling' ? '结算中' :
+                     order.status === 'unfunded' ? '余额不足' :
+                     order.status === 'invalid' ? '已失效' :
                      order.status === 'filled' ? '已成交' : '已取消'}
                   </span>
                 </div>
@@ -244,7 +252,7 @@ export function MyOrders() {
                 </div>
               </div>
 
-              {order.status === 'active' && (
+              {(order.status === 'active' || order.status === 'unfunded') && (
                 <div className="order-actions">
                   <button 
                     className="btn-cancel" 
@@ -19,7 +19,7 @@ export interface OrderResponse {
   expiry: string // ISO 时间字符串
   nonce: string
   side: 'ask' | 'bid'
-  status: 'active' | 'filled' | 'cancelled'
+  status: 'active' | 'settling' | 'unfunded' | 'invalid' | 'filled' | 'cancelled'
   signature: Hex
   hash: Hex
   createdAt: string
@@ -48,7 +48,7 @@ export interface CreateOrderRequest {
 export interface OrderFilters {
   side?: 'ask' | 'bid'
   collection?: Address
-  status?: 'active' | 'filled' | 'cancelled'
+  status?: 'active' | 'settling' | 'unfunded' | 'invalid' | 'filled' | 'cancelled'
 }
 
 /**
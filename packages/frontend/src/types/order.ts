@@ -16,6 +16,9 @@ export enum OrderSide {
  */
 export enum OrderStatus {
   ACTIVE = 'active',       // 活跃
+  SETTLING = 'settling',   // 已成交，等待区块确认
+  UNFUNDED = 'unfunded',   // 买方余额或授权不足，资金恢复后重新活跃
+  INVALID = 'invalid',     // 链上已不可成交（如 NFT 已转走），条件恢复后可能重新活跃
   FILLED = 'filled',       // 已成交
   CANCELLED = 'cancelled', // 已取消
 }
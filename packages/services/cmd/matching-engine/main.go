@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 
 	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/execution"
 	"github.com/Oeasy-NFT/services/internal/matching"
 	"github.com/joho/godotenv"
 )
@@ -12,6 +15,19 @@ func main() {
 	// 自动加载 .env 文件
 	_ = godotenv.Load()
 
+	// replay 子命令：离线回放订单簿快照，不连接 Redis、不提交交易
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("❌ 回放失败: %v", err)
+		}
+		return
+	}
+
+	// 单进程部署：执行服务与撮合引擎运行在同一进程中，撮合结果直接交给执行服务处理，不经过网络
+	inProcess := flag.Bool("in-process-execution", false,
+		"在本进程中运行执行服务（忽略 EXECUTION_URLS），其内部接口仍监听 EXECUTION_SERVICE_PORT")
+	flag.Parse()
+
 	log.Println("🚀 正在启动撮合引擎...")
 
 	cfg, err := config.Load()
@@ -21,12 +37,32 @@ func main() {
 
 	log.Printf("✅ 配置加载成功 - Redis: %s\n", cfg.RedisAddr)
 
-	engine, err := matching.NewEngine(cfg)
-	if err != nil {
-		log.Fatalf("❌ 初始化撮合引擎失败: %v", err)
+	var engine *matching.Engine
+	if *inProcess {
+		execSvc, err := execution.NewService(cfg)
+		if err != nil {
+			log.Fatalf("❌ 初始化执行服务失败: %v", err)
+		}
+		// 执行队列 worker、交易跟踪和钱包刷新由 Run 启动
+		go func() {
+			if err := execSvc.Run(); err != nil {
+				log.Fatalf("❌ 执行服务停止: %v", err)
+			}
+		}()
+		log.Printf("✅ 执行服务已在进程内启动，内部接口端口 %s\n", cfg.ExecutionServicePort)
+
+		engine, err = matching.NewEngineInProcess(cfg, execSvc.Handler())
+		if err != nil {
+			log.Fatalf("❌ 初始化撮合引擎失败: %v", err)
+		}
+	} else {
+		engine, err = matching.NewEngine(cfg)
+		if err != nil {
+			log.Fatalf("❌ 初始化撮合引擎失败: %v", err)
+		}
 	}
 
-	log.Println("✅ 撮合引擎初始化完成，开始扫描订单簿（每 5 秒）...")
+	log.Printf("✅ 撮合引擎初始化完成，管理接口端口 %s，开始扫描订单簿（每 5 秒）...\n", cfg.MatchingServicePort)
 
 	if err := engine.Run(); err != nil {
 		log.Fatalf("❌ 撮合引擎停止: %v", err)
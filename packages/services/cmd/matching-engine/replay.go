@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/matching"
+	"github.com/Oeasy-NFT/services/internal/postgres"
+)
+
+const replayUsage = `用法: matching-engine replay [选项]
+
+离线回放撮合：加载订单簿快照，按指定策略运行撮合并打印结果，不会提交任何交易。
+
+快照来源（二选一）:
+  --snapshot <file>        Redis orders:active:* 的 JSON 导出（"-" 表示标准输入）
+  --from <time> --to <time> 从 Postgres 查询该窗口内存在过的订单（RFC3339）：窗口终点前创建、且未在窗口起点前成交、取消、失效或资金不足
+
+选项:
+`
+
+// runReplay 执行 replay 子命令
+func runReplay(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), replayUsage)
+		fs.PrintDefaults()
+	}
+
+	snapshotPath := fs.String("snapshot", "", "订单簿快照 JSON 文件")
+	dsn := fs.String("dsn", os.Getenv("POSTGRES_DSN"), "Postgres DSN（默认读取 POSTGRES_DSN）")
+	fromStr := fs.String("from", "", "查询窗口起点（RFC3339）")
+	toStr := fs.String("to", "", "查询窗口终点（RFC3339）")
+	statusStr := fs.String("status", "", "仅包含这些状态的订单，逗号分隔（默认全部）")
+	policyStr := fs.String("policy", string(matching.PolicyFirstFit), "撮合策略: first-fit | price-time")
+	atStr := fs.String("at", "", "判断订单是否过期的时间点（RFC3339，默认窗口终点或当前时间）")
+	format := fs.String("format", "text", "输出格式: text | json")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	policy, err := matching.ParseMatchPolicy(*policyStr)
+	if err != nil {
+		return err
+	}
+
+	var (
+		snap *matching.Snapshot
+		at   = time.Now()
+	)
+
+	switch {
+	case *snapshotPath != "":
+		snap, err = loadSnapshotFile(*snapshotPath)
+		if err != nil {
+			return err
+		}
+	case *fromStr != "" && *toStr != "":
+		from, err := time.Parse(time.RFC3339, *fromStr)
+		if err != nil {
+			return fmt.Errorf("无效的 --from: %w", err)
+		}
+		to, err := time.Parse(time.RFC3339, *toStr)
+		if err != nil {
+			return fmt.Errorf("无效的 --to: %w", err)
+		}
+		if *dsn == "" {
+			return errors.New("从数据库回放需要 --dsn 或 POSTGRES_DSN")
+		}
+
+		db, err := postgres.New(*dsn)
+		if err != nil {
+			return fmt.Errorf("连接数据库失败: %w", err)
+		}
+		var statuses []string
+		if *statusStr != "" {
+			statuses = strings.Split(*statusStr, ",")
+		}
+		snap, err = matching.LoadSnapshotFromDB(context.Background(), db, from, to, statuses)
+		if err != nil {
+			return fmt.Errorf("查询订单失败: %w", err)
+		}
+		at = to
+	default:
+		fs.Usage()
+		return errors.New("必须指定 --snapshot 或 --from/--to")
+	}
+
+	if *atStr != "" {
+		at, err = time.Parse(time.RFC3339, *atStr)
+		if err != nil {
+			return fmt.Errorf("无效的 --at: %w", err)
+		}
+	}
+
+	result := matching.Replay(snap, policy, at)
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case "text":
+		return printReplayText(stdout, result)
+	default:
+		return fmt.Errorf("未知的输出格式 %q", *format)
+	}
+}
+
+// loadSnapshotFile 从文件或标准输入读取快照
+func loadSnapshotFile(path string) (*matching.Snapshot, error) {
+	if path == "-" {
+		return matching.LoadSnapshot(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return matching.LoadSnapshot(f)
+}
+
+// printReplayText 以表格形式打印回放结果
+func printReplayText(w io.Writer, result *matching.ReplayResult) error {
+	fmt.Fprintf(w, "策略: %s  时间点: %s  卖单: %d  买单: %d  匹配: %d\n\n",
+		result.Policy, result.At.Format(time.RFC3339), result.Asks, result.Bids, len(result.Matches))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NFT\tTOKEN\tASK_PRICE\tBID_PRICE\tSELLER\tBUYER\tASK_HASH\tBID_HASH")
+	for _, m := range result.Matches {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			m.Ask.NFTAddress, m.Ask.TokenID, m.Ask.Price, m.Bid.Price,
+			m.Ask.Maker, m.Bid.Maker, m.Ask.Hash, m.Bid.Hash)
+	}
+	return tw.Flush()
+}
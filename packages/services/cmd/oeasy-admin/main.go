@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `用法: oeasy-admin <子命令> [选项]
+
+OeasyMarketplace 合约所有者运维工具。RPC、链 ID、合约地址与签名方式读取与执行服务相同的配置
+（RPC_URL、CHAIN_ID、MARKETPLACE_ADDRESS、EXECUTOR_KEYSTORE_FILE / EXECUTOR_REMOTE_SIGNER_URL 等）。
+
+子命令:
+  status               读取 owner、手续费配置、暂停状态与 EIP-712 domain
+  pause                暂停市场合约
+  unpause              恢复市场合约
+  set-fee              修改手续费（--recipient <地址> --bps <基点>）
+  transfer-ownership   转移合约所有权（--to <地址>）
+
+写操作的通用选项:
+  --dry-run            只打印 calldata 与模拟执行结果，不发送交易
+  --yes                跳过确认提示
+  --from <地址>        配置了多个签名账户时指定使用哪一个（默认第一个）
+
+使用 "oeasy-admin <子命令> -h" 查看子命令选项。
+`
+
+func main() {
+	// 自动加载 .env 文件
+	_ = godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		stop()
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// run 按子命令分发
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	cmd, args := args[0], args[1:]
+	if cmd == "status" {
+		return runStatus(ctx, args, stdout)
+	}
+	op, ok := ownerOps[cmd]
+	if !ok {
+		fmt.Fprint(stdout, usage)
+		return errors.New("未知子命令: " + cmd)
+	}
+	return runOwnerOp(ctx, cmd, op, args, stdin, stdout)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/stretchr/testify/require"
+)
+
+var testMarketplace = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+// adminChain is a simulated chain served over HTTP, as oeasy-admin dials RPC_URL itself.
+type adminChain struct {
+	backend *simulated.Backend
+	owner   common.Address
+	other   common.Address
+}
+
+// newAdminChain starts the chain with a marketplace stand-in owned by the first configured
+// key, and points the oeasy-admin configuration at it.
+func newAdminChain(t *testing.T) *adminChain {
+	t.Helper()
+	ownerKey, otherKey := newKey(t), newKey(t)
+	owner := crypto.PubkeyToAddress(ownerKey.PublicKey)
+	other := crypto.PubkeyToAddress(otherKey.PublicKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		owner:           {Balance: big.NewInt(1e18)},
+		other:           {Balance: big.NewInt(1e18)},
+		testMarketplace: {Code: marketplaceStub(t, owner), Balance: big.NewInt(0)},
+	}, func(nodeConf *node.Config, _ *ethconfig.Config) {
+		nodeConf.HTTPHost = "127.0.0.1"
+		nodeConf.HTTPPort = port
+		nodeConf.HTTPModules = []string{"eth", "net", "web3"}
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	t.Setenv("POSTGRES_DSN", "postgres://unused")
+	t.Setenv("RPC_URL", "http://127.0.0.1:"+strconv.Itoa(port))
+	t.Setenv("CHAIN_ID", "1337")
+	t.Setenv("MARKETPLACE_ADDRESS", testMarketplace.Hex())
+	t.Setenv("EXECUTOR_PRIVATE_KEY", hexKey(ownerKey)+","+hexKey(otherKey))
+	return &adminChain{backend: backend, owner: owner, other: other}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return key
+}
+
+func hexKey(key *ecdsa.PrivateKey) string {
+	return hex.EncodeToString(crypto.FromECDSA(key))
+}
+
+// marketplaceStub is runtime bytecode answering the marketplace's view functions with fixed
+// values. Owner-only functions succeed for owner and revert with
+// OwnableUnauthorizedAccount(caller) for anyone else; any other call reverts.
+func marketplaceStub(t *testing.T, owner common.Address) []byte {
+	t.Helper()
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	require.NoError(t, err)
+	pack := func(method string, values ...any) []byte {
+		out, err := parsed.Methods[method].Outputs.Pack(values...)
+		require.NoError(t, err)
+		return out
+	}
+	views := map[string][]byte{
+		"owner":        pack("owner", owner),
+		"feeBps":       pack("feeBps", big.NewInt(250)),
+		"feeRecipient": pack("feeRecipient", owner),
+		"paused":       pack("paused", false),
+		"eip712Domain": pack("eip712Domain", [1]byte{0x0f}, "OeasyMarketplace", "1", big.NewInt(1337),
+			testMarketplace, [32]byte{}, []*big.Int{}),
+	}
+	ownerOnly := []string{"pause", "unpause", "setFeeConfiguration", "transferOwnership"}
+
+	var (
+		code    []byte
+		patches = map[int]func() uint16{} // PUSH2 operand offset -> resolved value
+	)
+	push2 := func(value func() uint16) {
+		code = append(code, 0x61, 0, 0)
+		patches[len(code)-2] = value
+	}
+	at := func(pos *int) func() uint16 { return func() uint16 { return uint16(*pos) } }
+
+	// selector := calldata[0:4]
+	code = append(code, 0x60, 0x00, 0x35, 0x60, 0xe0, 0x1c)
+	targets := map[string]*int{}
+	names := append(append([]string{}, ownerOnly...), "owner", "feeBps", "feeRecipient", "paused", "eip712Domain")
+	for _, name := range names {
+		targets[name] = new(int)
+		// DUP1 PUSH4 <selector> EQ PUSH2 <target> JUMPI
+		code = append(code, 0x80, 0x63)
+		code = append(code, parsed.Methods[name].ID...)
+		code = append(code, 0x14)
+		push2(at(targets[name]))
+		code = append(code, 0x57)
+	}
+	// Unknown selector: REVERT(0, 0)
+	code = append(code, 0x60, 0x00, 0x80, 0xfd)
+
+	unauthorized := parsed.Errors["OwnableUnauthorizedAccount"].ID
+	for _, name := range ownerOnly {
+		*targets[name] = len(code)
+		ok := new(int)
+		// JUMPDEST PUSH20 <owner> CALLER EQ PUSH2 <ok> JUMPI
+		code = append(code, 0x5b, 0x73)
+		code = append(code, owner.Bytes()...)
+		code = append(code, 0x33, 0x14)
+		push2(at(ok))
+		code = append(code, 0x57)
+		// MSTORE(0, selector << 224) MSTORE(4, CALLER) REVERT(0, 0x24)
+		code = append(code, 0x63)
+		code = append(code, unauthorized[:4]...)
+		code = append(code, 0x60, 0xe0, 0x1b, 0x60, 0x00, 0x52, 0x33, 0x60, 0x04, 0x52, 0x60, 0x24, 0x60, 0x00, 0xfd)
+		// ok: JUMPDEST STOP
+		*ok = len(code)
+		code = append(code, 0x5b, 0x00)
+	}
+
+	blobs := map[string]*int{}
+	for name, out := range views {
+		*targets[name] = len(code)
+		blobs[name] = new(int)
+		size := uint16(len(out))
+		// JUMPDEST CODECOPY(0, <blob>, size) RETURN(0, size)
+		code = append(code, 0x5b)
+		push2(func() uint16 { return size })
+		push2(at(blobs[name]))
+		code = append(code, 0x60, 0x00, 0x39)
+		push2(func() uint16 { return size })
+		code = append(code, 0x60, 0x00, 0xf3)
+	}
+	for name, out := range views {
+		*blobs[name] = len(code)
+		code = append(code, out...)
+	}
+
+	for pos, value := range patches {
+		binary.BigEndian.PutUint16(code[pos:], value())
+	}
+	return code
+}
+
+func (c *adminChain) pendingNonce(t *testing.T, addr common.Address) uint64 {
+	t.Helper()
+	nonce, err := c.backend.Client().PendingNonceAt(context.Background(), addr)
+	require.NoError(t, err)
+	return nonce
+}
+
+func runAdmin(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout)
+	return stdout.String(), err
+}
+
+func TestRun_Status(t *testing.T) {
+	chain := newAdminChain(t)
+
+	out, err := runAdmin(t, "", "status", "--format", "json")
+	require.NoError(t, err)
+	var status marketplaceStatus
+	require.NoError(t, json.Unmarshal([]byte(out), &status))
+	require.Equal(t, testMarketplace, status.Marketplace)
+	require.Equal(t, "1337", status.ChainID)
+	require.Equal(t, chain.owner, status.Owner)
+	require.Equal(t, "250", status.FeeBps)
+	require.False(t, status.Paused)
+	require.Equal(t, "OeasyMarketplace", status.Domain.Name)
+	require.Equal(t, testMarketplace, status.Domain.VerifyingContract)
+
+	out, err = runAdmin(t, "", "status")
+	require.NoError(t, err)
+	require.Contains(t, out, chain.owner.Hex())
+	require.NotContains(t, out, "不一致")
+}
+
+func TestRun_PauseDryRunSendsNothing(t *testing.T) {
+	chain := newAdminChain(t)
+
+	out, err := runAdmin(t, "", "pause", "--dry-run")
+	require.NoError(t, err)
+	require.Contains(t, out, "calldata: 0x8456cb59")
+	require.Contains(t, out, "模拟执行: 成功")
+	require.Contains(t, out, "--dry-run: 未发送交易")
+	require.Zero(t, chain.pendingNonce(t, chain.owner))
+}
+
+func TestRun_PauseDeclinedPromptSendsNothing(t *testing.T) {
+	chain := newAdminChain(t)
+
+	out, err := runAdmin(t, "n\n", "pause")
+	require.EqualError(t, err, "已取消")
+	require.Contains(t, out, "[y/N]")
+	require.Zero(t, chain.pendingNonce(t, chain.owner))
+}
+
+func TestRun_PauseByNonOwnerFailsSimulation(t *testing.T) {
+	chain := newAdminChain(t)
+
+	out, err := runAdmin(t, "", "pause", "--yes", "--from", chain.other.Hex())
+	require.EqualError(t, err, "模拟执行失败，未发送交易")
+	require.Contains(t, out, "OwnableUnauthorizedAccount")
+	require.Contains(t, out, strings.ToLower(chain.other.Hex()))
+	require.Zero(t, chain.pendingNonce(t, chain.other))
+}
+
+func TestRun_PauseConfirmedSendsTransaction(t *testing.T) {
+	chain := newAdminChain(t)
+
+	out, err := runAdmin(t, "y\n", "pause", "--wait=false")
+	require.NoError(t, err)
+	require.Contains(t, out, "交易已发送")
+	require.Equal(t, uint64(1), chain.pendingNonce(t, chain.owner))
+
+	chain.backend.Commit()
+	mined, err := chain.backend.Client().NonceAt(context.Background(), chain.owner, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), mined)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/execution"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxFeeBps 与合约中的上限一致（10000 = 100%）
+const maxFeeBps = 10000
+
+// ownerCall 描述一次所有者写操作
+type ownerCall struct {
+	method  string
+	args    []any
+	summary string
+	send    func(t *contracts.OeasyMarketplaceTransactor, opts *bind.TransactOpts) (*types.Transaction, error)
+}
+
+// ownerOp 描述一个写操作子命令：注册专属选项，解析后构造调用
+type ownerOp struct {
+	usage string
+	flags func(fs *flag.FlagSet) func() (*ownerCall, error)
+}
+
+var ownerOps = map[string]ownerOp{
+	"pause": {
+		usage: "暂停市场合约，暂停期间所有成交都会 revert",
+		flags: func(fs *flag.FlagSet) func() (*ownerCall, error) {
+			return func() (*ownerCall, error) {
+				return &ownerCall{
+					method:  "pause",
+					summary: "pause()",
+					send: func(t *contracts.OeasyMarketplaceTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+						return t.Pause(opts)
+					},
+				}, nil
+			}
+		},
+	},
+	"unpause": {
+		usage: "恢复市场合约",
+		flags: func(fs *flag.FlagSet) func() (*ownerCall, error) {
+			return func() (*ownerCall, error) {
+				return &ownerCall{
+					method:  "unpause",
+					summary: "unpause()",
+					send: func(t *contracts.OeasyMarketplaceTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+						return t.Unpause(opts)
+					},
+				}, nil
+			}
+		},
+	},
+	"set-fee": {
+		usage: "修改手续费接收地址与费率",
+		flags: func(fs *flag.FlagSet) func() (*ownerCall, error) {
+			recipientStr := fs.String("recipient", "", "手续费接收地址（必填）")
+			bps := fs.Int64("bps", -1, "手续费率，单位基点（必填，0-10000）")
+			return func() (*ownerCall, error) {
+				recipient, err := parseAddress("--recipient", *recipientStr)
+				if err != nil {
+					return nil, err
+				}
+				if *bps < 0 || *bps > maxFeeBps {
+					return nil, fmt.Errorf("--bps 必须在 0 到 %d 之间", maxFeeBps)
+				}
+				feeBps := big.NewInt(*bps)
+				return &ownerCall{
+					method:  "setFeeConfiguration",
+					args:    []any{recipient, feeBps},
+					summary: fmt.Sprintf("setFeeConfiguration(%s, %s)", recipient.Hex(), feeBps),
+					send: func(t *contracts.OeasyMarketplaceTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+						return t.SetFeeConfiguration(opts, recipient, feeBps)
+					},
+				}, nil
+			}
+		},
+	},
+	"transfer-ownership": {
+		usage: "将合约所有权转移到新地址，转移后当前账户将失去所有者权限",
+		flags: func(fs *flag.FlagSet) func() (*ownerCall, error) {
+			toStr := fs.String("to", "", "新的 owner 地址（必填）")
+			return func() (*ownerCall, error) {
+				to, err := parseAddress("--to", *toStr)
+				if err != nil {
+					return nil, err
+				}
+				if to == (common.Address{}) {
+					return nil, errors.New("--to 不能是零地址，如需放弃所有权请直接调用合约")
+				}
+				return &ownerCall{
+					method:  "transferOwnership",
+					args:    []any{to},
+					summary: fmt.Sprintf("transferOwnership(%s)", to.Hex()),
+					send: func(t *contracts.OeasyMarketplaceTransactor, opts *bind.TransactOpts) (*types.Transaction, error) {
+						return t.TransferOwnership(opts, to)
+					},
+				}, nil
+			}
+		},
+	},
+}
+
+// runOwnerOp 执行写操作子命令：打印 calldata、模拟执行，确认后使用执行器的签名配置发送交易
+func runOwnerOp(ctx context.Context, name string, op ownerOp, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: oeasy-admin %s [选项]\n\n%s。\n\n选项:\n", name, op.usage)
+		fs.PrintDefaults()
+	}
+	dryRun := fs.Bool("dry-run", false, "只打印 calldata 与模拟执行结果，不发送交易")
+	yes := fs.Bool("yes", false, "跳过确认提示")
+	fromStr := fs.String("from", "", "使用的签名账户地址（默认第一个配置的账户）")
+	wait := fs.Bool("wait", true, "发送后等待交易上链")
+	build := op.flags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	call, err := build()
+	if err != nil {
+		return err
+	}
+
+	a, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.client.Close()
+
+	signer, err := selectSigner(ctx, a, *fromStr)
+	if err != nil {
+		return err
+	}
+	defer closeSigner(signer)
+
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	if err != nil {
+		return fmt.Errorf("解析合约 ABI 失败: %w", err)
+	}
+	calldata, err := parsed.Pack(call.method, call.args...)
+	if err != nil {
+		return fmt.Errorf("编码 calldata 失败: %w", err)
+	}
+
+	from := signer.Address()
+	fmt.Fprintf(stdout, "合约:     %s\n", a.marketplace.Hex())
+	fmt.Fprintf(stdout, "调用:     %s\n", call.summary)
+	fmt.Fprintf(stdout, "发送方:   %s\n", from.Hex())
+	fmt.Fprintf(stdout, "calldata: %s\n", hexutil.Encode(calldata))
+
+	// 模拟执行：非 owner、重复 pause 等情况会在这里以解码后的 revert 暴露出来
+	msg := ethereum.CallMsg{From: from, To: &a.marketplace, Data: calldata}
+	if _, err := a.client.CallContract(ctx, msg, nil); err != nil {
+		fmt.Fprintf(stdout, "模拟执行: 失败 - %v\n", execution.AsRevert(err))
+		return errors.New("模拟执行失败，未发送交易")
+	}
+	gas, err := a.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("估算 gas 失败: %w", execution.AsRevert(err))
+	}
+	fmt.Fprintf(stdout, "模拟执行: 成功（预估 gas %d）\n", gas)
+
+	if *dryRun {
+		fmt.Fprintln(stdout, "--dry-run: 未发送交易")
+		return nil
+	}
+	if !*yes && !confirm(stdin, stdout, fmt.Sprintf("确认在链 %s 上以 %s 发送 %s？", a.chainID, from.Hex(), call.summary)) {
+		return errors.New("已取消")
+	}
+
+	transactor, err := contracts.NewOeasyMarketplaceTransactor(a.marketplace, a.client)
+	if err != nil {
+		return fmt.Errorf("绑定市场合约失败: %w", err)
+	}
+	tx, err := call.send(transactor, execution.TransactOpts(ctx, signer, a.chainID))
+	if err != nil {
+		return fmt.Errorf("发送交易失败: %w", execution.AsRevert(err))
+	}
+	fmt.Fprintf(stdout, "✅ 交易已发送: %s\n", tx.Hash().Hex())
+	if !*wait {
+		return nil
+	}
+
+	receipt, err := bind.WaitMined(ctx, a.client, tx)
+	if err != nil {
+		return fmt.Errorf("等待交易上链失败: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("交易在区块 %s 中执行失败", receipt.BlockNumber)
+	}
+	fmt.Fprintf(stdout, "✅ 交易已上链: 区块 %s，gas %d\n", receipt.BlockNumber, receipt.GasUsed)
+	return nil
+}
+
+// selectSigner 从执行器签名配置中选出发送账户，未选中的账户（远程签名连接）立即关闭
+func selectSigner(ctx context.Context, a *admin, from string) (execution.Signer, error) {
+	signers, err := execution.NewSignersFromConfig(ctx, a.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("加载签名账户失败: %w", err)
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("未配置签名账户（EXECUTOR_KEYSTORE_FILE / EXECUTOR_REMOTE_SIGNER_URL）")
+	}
+
+	selected := -1
+	if from == "" {
+		selected = 0
+	} else {
+		addr, err := parseAddress("--from", from)
+		if err != nil {
+			closeSigners(signers, -1)
+			return nil, err
+		}
+		for i, signer := range signers {
+			if signer.Address() == addr {
+				selected = i
+				break
+			}
+		}
+		if selected < 0 {
+			closeSigners(signers, -1)
+			return nil, fmt.Errorf("签名配置中没有账户 %s", addr.Hex())
+		}
+	}
+	closeSigners(signers, selected)
+	return signers[selected], nil
+}
+
+// closeSigners 关闭除 keep 以外的签名账户
+func closeSigners(signers []execution.Signer, keep int) {
+	for i, signer := range signers {
+		if i != keep {
+			closeSigner(signer)
+		}
+	}
+}
+
+// closeSigner 释放签名账户持有的连接（远程签名服务），本地密钥无需关闭
+func closeSigner(signer execution.Signer) {
+	if c, ok := signer.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+func parseAddress(flagName, value string) (common.Address, error) {
+	if value == "" {
+		return common.Address{}, fmt.Errorf("缺少 %s", flagName)
+	}
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("无效的 %s: %q", flagName, value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+// confirm 打印提示并读取一行输入，只有 y/yes 视为确认
+func confirm(stdin io.Reader, stdout io.Writer, prompt string) bool {
+	fmt.Fprintf(stdout, "%s [y/N]: ", prompt)
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const statusUsage = `用法: oeasy-admin status [选项]
+
+读取 OeasyMarketplace 的 owner、手续费配置、暂停状态与 EIP-712 domain，不发送交易。
+
+选项:
+`
+
+// admin 持有与链和市场合约的连接
+type admin struct {
+	cfg         *config.Config
+	client      *ethclient.Client
+	marketplace common.Address
+	chainID     *big.Int
+}
+
+// connect 加载配置并连接 RPC 节点，校验节点链 ID 与配置一致
+func connect(ctx context.Context) (*admin, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	if !common.IsHexAddress(cfg.MarketplaceAddr) {
+		return nil, fmt.Errorf("无效的 MARKETPLACE_ADDRESS: %q", cfg.MarketplaceAddr)
+	}
+
+	client, err := ethclient.DialContext(ctx, cfg.RPCURL)
+	if err != nil {
+		return nil, fmt.Errorf("连接 RPC 失败: %w", err)
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("查询链 ID 失败: %w", err)
+	}
+	if chainID.Uint64() != cfg.ChainID {
+		client.Close()
+		return nil, fmt.Errorf("RPC 节点链 ID %s 与 CHAIN_ID %d 不一致", chainID, cfg.ChainID)
+	}
+
+	return &admin{
+		cfg:         cfg,
+		client:      client,
+		marketplace: common.HexToAddress(cfg.MarketplaceAddr),
+		chainID:     chainID,
+	}, nil
+}
+
+// marketplaceStatus 是 status 子命令的输出
+type marketplaceStatus struct {
+	Marketplace  common.Address `json:"marketplace"`
+	ChainID      string         `json:"chainId"`
+	Owner        common.Address `json:"owner"`
+	FeeBps       string         `json:"feeBps"`
+	FeeRecipient common.Address `json:"feeRecipient"`
+	Paused       bool           `json:"paused"`
+	Domain       eip712Domain   `json:"eip712Domain"`
+}
+
+type eip712Domain struct {
+	Fields            string         `json:"fields"`
+	Name              string         `json:"name"`
+	Version           string         `json:"version"`
+	ChainID           string         `json:"chainId"`
+	VerifyingContract common.Address `json:"verifyingContract"`
+	Salt              string         `json:"salt"`
+	Extensions        []string       `json:"extensions"`
+}
+
+// runStatus 执行 status 子命令
+func runStatus(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), statusUsage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "text", "输出格式: text | json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	a, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.client.Close()
+
+	status, err := readStatus(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	case "text":
+		printStatus(stdout, status)
+		return nil
+	default:
+		return fmt.Errorf("未知的输出格式: %s", *format)
+	}
+}
+
+// readStatus 通过 OeasyMarketplaceCaller 读取合约状态
+func readStatus(ctx context.Context, a *admin) (*marketplaceStatus, error) {
+	caller, err := contracts.NewOeasyMarketplaceCaller(a.marketplace, a.client)
+	if err != nil {
+		return nil, fmt.Errorf("绑定市场合约失败: %w", err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	status := &marketplaceStatus{Marketplace: a.marketplace, ChainID: a.chainID.String()}
+	if status.Owner, err = caller.Owner(opts); err != nil {
+		return nil, fmt.Errorf("读取 owner 失败: %w", err)
+	}
+	feeBps, err := caller.FeeBps(opts)
+	if err != nil {
+		return nil, fmt.Errorf("读取 feeBps 失败: %w", err)
+	}
+	status.FeeBps = feeBps.String()
+	if status.FeeRecipient, err = caller.FeeRecipient(opts); err != nil {
+		return nil, fmt.Errorf("读取 feeRecipient 失败: %w", err)
+	}
+	if status.Paused, err = caller.Paused(opts); err != nil {
+		return nil, fmt.Errorf("读取 paused 失败: %w", err)
+	}
+
+	domain, err := caller.Eip712Domain(opts)
+	if err != nil {
+		return nil, fmt.Errorf("读取 eip712Domain 失败: %w", err)
+	}
+	status.Domain = eip712Domain{
+		Fields:            hexutil.Encode(domain.Fields[:]),
+		Name:              domain.Name,
+		Version:           domain.Version,
+		ChainID:           domain.ChainId.String(),
+		VerifyingContract: domain.VerifyingContract,
+		Salt:              hexutil.Encode(domain.Salt[:]),
+		Extensions:        []string{},
+	}
+	for _, ext := range domain.Extensions {
+		status.Domain.Extensions = append(status.Domain.Extensions, ext.String())
+	}
+	return status, nil
+}
+
+func printStatus(w io.Writer, s *marketplaceStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "合约地址\t%s\n", s.Marketplace.Hex())
+	fmt.Fprintf(tw, "链 ID\t%s\n", s.ChainID)
+	fmt.Fprintf(tw, "owner\t%s\n", s.Owner.Hex())
+	fmt.Fprintf(tw, "feeBps\t%s\n", s.FeeBps)
+	fmt.Fprintf(tw, "feeRecipient\t%s\n", s.FeeRecipient.Hex())
+	fmt.Fprintf(tw, "paused\t%t\n", s.Paused)
+	fmt.Fprintf(tw, "EIP-712 name\t%s\n", s.Domain.Name)
+	fmt.Fprintf(tw, "EIP-712 version\t%s\n", s.Domain.Version)
+	fmt.Fprintf(tw, "EIP-712 chainId\t%s\n", s.Domain.ChainID)
+	fmt.Fprintf(tw, "EIP-712 verifyingContract\t%s\n", s.Domain.VerifyingContract.Hex())
+	fmt.Fprintf(tw, "EIP-712 fields\t%s\n", s.Domain.Fields)
+	fmt.Fprintf(tw, "EIP-712 salt\t%s\n", s.Domain.Salt)
+	if len(s.Domain.Extensions) > 0 {
+		fmt.Fprintf(tw, "EIP-712 extensions\t%s\n", strings.Join(s.Domain.Extensions, ","))
+	}
+	tw.Flush()
+
+	// 签名域与配置不一致时，前端和订单服务生成的签名会全部失效
+	if s.Domain.ChainID != s.ChainID || s.Domain.VerifyingContract != s.Marketplace {
+		fmt.Fprintln(w, "⚠️  EIP-712 domain 的 chainId/verifyingContract 与当前配置不一致")
+	}
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v10"
 )
 
@@ -20,6 +22,118 @@ type Config struct {
 	RPCURL          string `env:"RPC_URL,notEmpty"`
 	PrivateKeyHex   string `env:"EXECUTOR_PRIVATE_KEY"`
 	ChainID         uint64 `env:"CHAIN_ID,notEmpty"`
+
+	// Executor signers. Keystore files take precedence over a remote signer; EXECUTOR_PRIVATE_KEY
+	// is only a development fallback. Each list entry (comma-separated) adds one wallet to the
+	// executor pool; a single password file is shared by all keystores. The remote method is
+	// eth_signTransaction or, for clef, account_signTransaction.
+	ExecutorKeystoreFiles         []string `env:"EXECUTOR_KEYSTORE_FILE" envSeparator:","`
+	ExecutorKeystorePasswordFiles []string `env:"EXECUTOR_KEYSTORE_PASSWORD_FILE" envSeparator:","`
+	ExecutorRemoteSignerURL       string   `env:"EXECUTOR_REMOTE_SIGNER_URL"`
+	ExecutorRemoteSignerAddresses []string `env:"EXECUTOR_REMOTE_SIGNER_ADDRESS" envSeparator:","`
+	ExecutorRemoteSignerMethod    string   `env:"EXECUTOR_REMOTE_SIGNER_METHOD" envDefault:"eth_signTransaction"`
+	// With no signer configured the execution service runs in simulate-only mode: trades are
+	// validated and eth_call'ed from this address (zero address when empty), never broadcast.
+	ExecutionSimulateFrom string `env:"EXECUTION_SIMULATE_FROM"`
+
+	// Executor wallet pool health: balances and mined nonces are refreshed every interval; a
+	// wallet below the minimum balance, or after ExecutionWalletMaxFailures consecutive
+	// submission failures (for ExecutionWalletCooldown), receives no new trades.
+	ExecutionWalletRefreshInterval time.Duration `env:"EXECUTION_WALLET_REFRESH_INTERVAL" envDefault:"30s"`
+	ExecutionWalletMinBalanceEth   float64       `env:"EXECUTION_WALLET_MIN_BALANCE_ETH" envDefault:"0.01"`
+	ExecutionWalletMaxFailures     int           `env:"EXECUTION_WALLET_MAX_FAILURES" envDefault:"3"`
+	ExecutionWalletCooldown        time.Duration `env:"EXECUTION_WALLET_COOLDOWN" envDefault:"1m"`
+
+	// Indexer reorg detection: hashes of the last IndexerReorgWindow blocks are kept and compared
+	// with the chain on every poll; on a fork everything indexed from the fork point is rolled
+	// back and reindexed. 0 disables detection.
+	IndexerReorgWindow uint64 `env:"INDEXER_REORG_WINDOW" envDefault:"128"`
+	// IndexerConfirmations is how many blocks must be built on top of an event's block before
+	// it is final. Newer trades are stored as pending and their orders marked settling; 0 treats
+	// every mined event as final.
+	IndexerConfirmations uint64 `env:"INDEXER_CONFIRMATIONS" envDefault:"12"`
+	// IndexerNFTCollections lists the NFT contracts whose Transfer events are indexed into
+	// token_ownership; an ask whose token leaves the maker is marked invalid.
+	IndexerNFTCollections []string `env:"INDEXER_NFT_COLLECTIONS" envSeparator:","`
+	// IndexerPaymentTokens lists the ERC-20 payment tokens whose Transfer and Approval events
+	// refresh bidders' balance and allowance snapshots; bids they cannot cover become unfunded.
+	IndexerPaymentTokens []string `env:"INDEXER_PAYMENT_TOKENS" envSeparator:","`
+
+	// MatchPolicy selects the pairing rule used by the matching engine (first-fit, price-time).
+	MatchPolicy string `env:"MATCH_POLICY" envDefault:"first-fit"`
+
+	// Matching engine failure handling: per-pair exponential backoff and dead-letter threshold.
+	MatchMaxFailures int           `env:"MATCH_MAX_FAILURES" envDefault:"5"`
+	MatchBackoffBase time.Duration `env:"MATCH_BACKOFF_BASE" envDefault:"5s"`
+	MatchBackoffMax  time.Duration `env:"MATCH_BACKOFF_MAX" envDefault:"5m"`
+
+	// Pre-submission on-chain fillability check (ownership, approvals, balances, nonces).
+	MatchFillabilityCheck    bool          `env:"MATCH_FILLABILITY_CHECK" envDefault:"true"`
+	MatchFillabilityCacheTTL time.Duration `env:"MATCH_FILLABILITY_CACHE_TTL" envDefault:"10s"`
+
+	// Execution transport used by the matching engine. When ExecutionURLs is empty the engine
+	// falls back to http://localhost:$EXECUTION_SERVICE_PORT/internal/execute.
+	ExecutionURLs       []string      `env:"EXECUTION_URLS" envSeparator:","`
+	ExecutionTimeout    time.Duration `env:"EXECUTION_TIMEOUT" envDefault:"30s"`
+	ExecutionMaxRetries int           `env:"EXECUTION_MAX_RETRIES" envDefault:"3"`
+
+	// Shared secret authenticating the matching engine <-> execution service channel with
+	// HMAC-signed requests and responses. Required by both services; requests whose timestamp
+	// is more than InternalAuthWindow away, or whose nonce was already seen, are rejected.
+	InternalAuthSecret string        `env:"INTERNAL_AUTH_SECRET"`
+	InternalAuthWindow time.Duration `env:"INTERNAL_AUTH_WINDOW" envDefault:"30s"`
+
+	// Bearer token required by the matching engine's mutating admin routes (pause/resume and
+	// dead-letter retry/drop). When empty those routes are disabled; read-only routes stay open.
+	MatchingAdminToken string `env:"MATCHING_ADMIN_TOKEN"`
+
+	// Execution service receipt tracker: how often pending transactions are polled and how long
+	// a transaction unknown to the node may stay pending before it is marked dropped.
+	ExecutionReceiptPollInterval time.Duration `env:"EXECUTION_RECEIPT_POLL_INTERVAL" envDefault:"5s"`
+	ExecutionDropTimeout         time.Duration `env:"EXECUTION_DROP_TIMEOUT" envDefault:"10m"`
+
+	// Stuck-transaction replacement: after ExecutionStuckTimeout a pending transaction is resent
+	// with fees bumped by ExecutionGasBumpPercent (min 10); after ExecutionMaxGasBumps speed-ups
+	// the nonce is cancelled with a zero-value self-transfer. A zero timeout disables replacement.
+	ExecutionStuckTimeout   time.Duration `env:"EXECUTION_STUCK_TIMEOUT" envDefault:"2m"`
+	ExecutionGasBumpPercent int           `env:"EXECUTION_GAS_BUMP_PERCENT" envDefault:"15"`
+	ExecutionMaxGasBumps    int           `env:"EXECUTION_MAX_GAS_BUMPS" envDefault:"3"`
+
+	// Trade transaction pricing. On London chains the fee cap and priority fee are clamped to
+	// these gwei caps (0 = uncapped); pre-London chains use a legacy gas price capped by the max fee.
+	// The gas limit is the per-trade estimate plus ExecutionGasLimitMarginPercent.
+	ExecutionMaxFeeGwei            float64 `env:"EXECUTION_MAX_FEE_GWEI" envDefault:"0"`
+	ExecutionMaxPriorityFeeGwei    float64 `env:"EXECUTION_MAX_PRIORITY_FEE_GWEI" envDefault:"0"`
+	ExecutionGasLimitMarginPercent int     `env:"EXECUTION_GAS_LIMIT_MARGIN_PERCENT" envDefault:"20"`
+
+	// Execution circuit breakers, evaluated at most once per ExecutionBreakerCacheTTL: no trade
+	// is accepted or sent while the marketplace is paused, no executor wallet holds
+	// ExecutionWalletMinBalanceEth, or the market gas price exceeds ExecutionGasPriceCeilingGwei
+	// (0 disables the gas breaker).
+	ExecutionBreakerCacheTTL     time.Duration `env:"EXECUTION_BREAKER_CACHE_TTL" envDefault:"10s"`
+	ExecutionGasPriceCeilingGwei float64       `env:"EXECUTION_GAS_PRICE_CEILING_GWEI" envDefault:"0"`
+
+	// Profitability guard, enabled by ExecutionPriceFeedFile (a JSON file of native and payment
+	// token USD prices, reloaded when it changes). A trade whose estimated gas cost, in payment
+	// token units, exceeds its marketplace fee is sent anyway (allow), failed (reject), or kept
+	// queued and retried every ExecutionProfitDeferDelay for up to ExecutionProfitDeferMaxWait
+	// (defer). Every decision is stored in execution_economics.
+	ExecutionPriceFeedFile      string        `env:"EXECUTION_PRICE_FEED_FILE"`
+	ExecutionProfitPolicy       string        `env:"EXECUTION_PROFIT_POLICY" envDefault:"allow"`
+	ExecutionProfitDeferDelay   time.Duration `env:"EXECUTION_PROFIT_DEFER_DELAY" envDefault:"5m"`
+	ExecutionProfitDeferMaxWait time.Duration `env:"EXECUTION_PROFIT_DEFER_MAX_WAIT" envDefault:"1h"`
+
+	// Execution queue: /internal/execute enqueues into Postgres and ExecutionQueueWorkers drain
+	// it. Enqueue is rejected with 429 once ExecutionQueueCapacity jobs are waiting or running
+	// (a soft cap: concurrent requests may overshoot it slightly).
+	// Failed attempts are retried after ExecutionQueueRetryDelay up to ExecutionQueueMaxAttempts;
+	// a job left running longer than ExecutionQueueStaleTimeout (e.g. after a crash) is requeued.
+	ExecutionQueueWorkers      int           `env:"EXECUTION_QUEUE_WORKERS" envDefault:"4"`
+	ExecutionQueueCapacity     int           `env:"EXECUTION_QUEUE_CAPACITY" envDefault:"1000"`
+	ExecutionQueueMaxAttempts  int           `env:"EXECUTION_QUEUE_MAX_ATTEMPTS" envDefault:"3"`
+	ExecutionQueueRetryDelay   time.Duration `env:"EXECUTION_QUEUE_RETRY_DELAY" envDefault:"10s"`
+	ExecutionQueuePollInterval time.Duration `env:"EXECUTION_QUEUE_POLL_INTERVAL" envDefault:"1s"`
+	ExecutionQueueStaleTimeout time.Duration `env:"EXECUTION_QUEUE_STALE_TIMEOUT" envDefault:"5m"`
 }
 
 // Load parses environment variables into Config.
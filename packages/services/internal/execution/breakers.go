@@ -0,0 +1,166 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// Circuit breaker names, also used as the error codes returned while a breaker is tripped.
+const (
+	BreakerMarketplacePaused  = "MarketplacePaused"
+	BreakerExecutorBalanceLow = "ExecutorBalanceLow"
+	BreakerGasPriceTooHigh    = "GasPriceTooHigh"
+)
+
+// BreakerError is returned while a circuit breaker is tripped. Nothing was queued or sent;
+// the caller should back off and try again later.
+type BreakerError struct {
+	Code   string
+	Reason string
+}
+
+func (e *BreakerError) Error() string {
+	return fmt.Sprintf("execution paused by %s breaker: %s", e.Code, e.Reason)
+}
+
+// BreakerState is the per-breaker view exposed on /internal/health.
+type BreakerState struct {
+	Name    string `json:"name"`
+	Tripped bool   `json:"tripped"`
+	Reason  string `json:"reason,omitempty"`
+	// CheckError is set when the precondition could not be evaluated; the breaker then stays
+	// closed, since the submission itself will surface the same RPC failure.
+	CheckError string `json:"checkError,omitempty"`
+}
+
+// PausedChecker reports whether the marketplace contract is paused.
+type PausedChecker interface {
+	Paused(opts *bind.CallOpts) (bool, error)
+}
+
+// breakers evaluates submission preconditions and caches the result for a short TTL, so the
+// checks cost a few RPC calls per interval rather than per trade.
+type breakers struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	states    []BreakerState
+}
+
+// checkBreakers returns a *BreakerError for the first tripped breaker, re-evaluating them if the
+// cached result has expired.
+func (s *Service) checkBreakers(ctx context.Context) error {
+	for _, state := range s.breakerStates(ctx) {
+		if state.Tripped {
+			return &BreakerError{Code: state.Name, Reason: state.Reason}
+		}
+	}
+	return nil
+}
+
+// breakerStates returns the cached breaker states, refreshing them when stale.
+func (s *Service) breakerStates(ctx context.Context) []BreakerState {
+	b := s.breakers
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.states != nil && now.Sub(b.checkedAt) < b.ttl {
+		return b.states
+	}
+
+	states := []BreakerState{
+		s.pausedBreaker(ctx),
+		s.balanceBreaker(ctx),
+		s.gasBreaker(ctx),
+	}
+	for i, state := range states {
+		var was bool
+		if i < len(b.states) {
+			was = b.states[i].Tripped
+		}
+		switch {
+		case state.Tripped && !was:
+			logger.Warn("execution circuit breaker tripped", "breaker", state.Name, "reason", state.Reason)
+		case !state.Tripped && was:
+			logger.Info("execution circuit breaker reset", "breaker", state.Name)
+		}
+	}
+	b.states = states
+	b.checkedAt = now
+	return states
+}
+
+// pausedBreaker trips while OeasyMarketplace.paused() is true; every trade would revert.
+func (s *Service) pausedBreaker(ctx context.Context) BreakerState {
+	state := BreakerState{Name: BreakerMarketplacePaused}
+	paused, err := s.pausedChecker.Paused(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		state.CheckError = err.Error()
+		return state
+	}
+	if paused {
+		state.Tripped = true
+		state.Reason = "marketplace contract is paused"
+	}
+	return state
+}
+
+// balanceBreaker trips when no executor wallet holds the minimum balance needed for gas.
+func (s *Service) balanceBreaker(ctx context.Context) BreakerState {
+	state := BreakerState{Name: BreakerExecutorBalanceLow}
+	s.wallets.refresh(ctx, s.client)
+	if !s.wallets.funded() {
+		state.Tripped = true
+		state.Reason = fmt.Sprintf("no executor wallet holds at least %g ETH", s.cfg.ExecutionWalletMinBalanceEth)
+	}
+	return state
+}
+
+// gasBreaker trips while the market gas price (base fee plus suggested tip, or the legacy
+// gas price) is above the configured ceiling. Fee caps alone would let trades through at a
+// price the network ignores, leaving them stuck.
+func (s *Service) gasBreaker(ctx context.Context) BreakerState {
+	state := BreakerState{Name: BreakerGasPriceTooHigh}
+	ceiling := gweiToWei(s.cfg.ExecutionGasPriceCeilingGwei)
+	if ceiling == nil {
+		return state
+	}
+	price, err := s.marketGasPrice(ctx)
+	if err != nil {
+		state.CheckError = err.Error()
+		return state
+	}
+	if price.Cmp(ceiling) > 0 {
+		state.Tripped = true
+		state.Reason = fmt.Sprintf("gas price %s wei above ceiling %s wei", price, ceiling)
+	}
+	return state
+}
+
+// marketGasPrice returns the uncapped price a transaction has to pay to be included now.
+func (s *Service) marketGasPrice(ctx context.Context) (*big.Int, error) {
+	head, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch latest header: %w", err)
+	}
+	if head.BaseFee == nil {
+		return s.client.SuggestGasPrice(ctx)
+	}
+	tip, err := s.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Add(head.BaseFee, tip), nil
+}
@@ -0,0 +1,99 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePausedChecker struct{ paused bool }
+
+func (f *fakePausedChecker) Paused(*bind.CallOpts) (bool, error) { return f.paused, nil }
+
+// TestBreakers_RejectTradesWhileTripped trips each breaker in turn and expects enqueue to be
+// refused with its code, the state on /internal/health, and results cached for the TTL.
+func TestBreakers_RejectTradesWhileTripped(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewKeySigner(key)
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		signer.Address(): {Balance: big.NewInt(1e18)},
+	})
+	defer backend.Close()
+
+	svc := newTestService(t, 10)
+	svc.client = backend.Client()
+	svc.cfg.ExecutionWalletMinBalanceEth = 0.5
+	svc.cfg.ExecutionGasPriceCeilingGwei = 1000
+	svc.wallets = newWalletPool([]Signer{signer}, NewNonceStore(svc.repo.db), ethToWei(0.5), 3, time.Minute, nil)
+	paused := &fakePausedChecker{}
+	svc.pausedChecker = paused
+	now := time.Now()
+	svc.breakers = &breakers{ttl: 10 * time.Second, now: func() time.Time { return now }}
+
+	expectRejected := func(code string) {
+		t.Helper()
+		rec := postExecute(t, svc, testTradeRequest(2))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+		var body struct {
+			Code      string `json:"code"`
+			Permanent bool   `json:"permanent"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Equal(t, code, body.Code)
+		require.False(t, body.Permanent)
+
+		rec = httptest.NewRecorder()
+		svc.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/health", nil))
+		var health struct {
+			Status   string         `json:"status"`
+			Breakers []BreakerState `json:"breakers"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+		require.Equal(t, "degraded", health.Status)
+		tripped := map[string]bool{}
+		for _, b := range health.Breakers {
+			tripped[b.Name] = b.Tripped
+		}
+		require.True(t, tripped[code], "%+v", health.Breakers)
+	}
+
+	// All preconditions hold.
+	require.NoError(t, svc.checkBreakers(ctx))
+	rec := postExecute(t, svc, testTradeRequest(1))
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	// A pause is only noticed once the cached result expires.
+	paused.paused = true
+	require.NoError(t, svc.checkBreakers(ctx))
+	now = now.Add(11 * time.Second)
+	expectRejected(BreakerMarketplacePaused)
+	paused.paused = false
+
+	// The simulated chain's gas price is far above a sub-wei ceiling.
+	svc.cfg.ExecutionGasPriceCeilingGwei = 1e-9
+	now = now.Add(11 * time.Second)
+	expectRejected(BreakerGasPriceTooHigh)
+	svc.cfg.ExecutionGasPriceCeilingGwei = 1000
+
+	// The only wallet holds 1 ETH, below a 2 ETH minimum.
+	svc.cfg.ExecutionWalletMinBalanceEth = 2
+	svc.wallets.minBalance = ethToWei(2)
+	now = now.Add(11 * time.Second)
+	expectRejected(BreakerExecutorBalanceLow)
+
+	svc.wallets.minBalance = ethToWei(0.5)
+	now = now.Add(11 * time.Second)
+	require.NoError(t, svc.checkBreakers(ctx))
+}
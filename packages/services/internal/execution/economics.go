@@ -0,0 +1,310 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// percentageScale is the marketplace's basis-point denominator (10000 = 100%).
+const percentageScale = 10_000
+
+// Profitability policies, applied when a trade's gas cost exceeds the fee it earns.
+const (
+	// ProfitPolicyAllow submits unprofitable trades anyway; decisions are only recorded.
+	ProfitPolicyAllow = "allow"
+	// ProfitPolicyReject fails unprofitable trades permanently.
+	ProfitPolicyReject = "reject"
+	// ProfitPolicyDefer keeps unprofitable trades queued until gas is cheap enough.
+	ProfitPolicyDefer = "defer"
+)
+
+// ProfitDecision is the outcome of one profitability evaluation.
+type ProfitDecision string
+
+const (
+	ProfitDecisionAllow  ProfitDecision = "allow"
+	ProfitDecisionReject ProfitDecision = "reject"
+	ProfitDecisionDefer  ProfitDecision = "defer"
+)
+
+// UnprofitableError is returned when the profitability policy rejects or defers a trade.
+// Nothing was signed or broadcast.
+type UnprofitableError struct {
+	Decision ProfitDecision
+	// Cost and Revenue are in the payment token's base units.
+	Cost    *big.Int
+	Revenue *big.Int
+}
+
+func (e *UnprofitableError) Error() string {
+	return fmt.Sprintf("trade %s: gas cost %s exceeds fee revenue %s", e.Decision, e.Cost, e.Revenue)
+}
+
+// FeeReader reads the marketplace fee configuration.
+type FeeReader interface {
+	FeeBps(opts *bind.CallOpts) (*big.Int, error)
+	FeeRecipient(opts *bind.CallOpts) (common.Address, error)
+}
+
+// EconomicsDecision records one profitability evaluation for finance reporting. Amounts are
+// decimal strings: wei for gas, base units of the payment token otherwise. Inputs that could
+// not be determined are left NULL and Reason says why.
+type EconomicsDecision struct {
+	ID           uint64 `gorm:"primaryKey" json:"id"`
+	ExecutionKey string `gorm:"type:varchar(66);index;column:execution_key" json:"executionKey"`
+	// TxHash is set once the trade is broadcast.
+	TxHash       string         `gorm:"type:varchar(66);index;column:tx_hash" json:"txHash,omitempty"`
+	PaymentToken string         `gorm:"type:varchar(42);column:payment_token" json:"paymentToken"`
+	Price        string         `gorm:"type:numeric" json:"price"`
+	FeeBps       *int64         `gorm:"column:fee_bps" json:"feeBps,omitempty"`
+	FeeRevenue   *string        `gorm:"type:numeric;column:fee_revenue" json:"feeRevenue,omitempty"`
+	GasEstimate  uint64         `gorm:"column:gas_estimate" json:"gasEstimate"`
+	GasPrice     *string        `gorm:"type:numeric;column:gas_price" json:"gasPrice,omitempty"`
+	GasCostWei   *string        `gorm:"type:numeric;column:gas_cost_wei" json:"gasCostWei,omitempty"`
+	GasCostToken *string        `gorm:"type:numeric;column:gas_cost_token" json:"gasCostToken,omitempty"`
+	NativeUSD    *string        `gorm:"type:numeric;column:native_usd" json:"nativeUsd,omitempty"`
+	TokenUSD     *string        `gorm:"type:numeric;column:token_usd" json:"tokenUsd,omitempty"`
+	Policy       string         `gorm:"type:varchar(16)" json:"policy"`
+	Decision     ProfitDecision `gorm:"type:varchar(16);index" json:"decision"`
+	Reason       string         `gorm:"type:text" json:"reason,omitempty"`
+	CreatedAt    time.Time      `gorm:"index" json:"createdAt"`
+}
+
+// TableName overrides default table name.
+func (EconomicsDecision) TableName() string {
+	return "execution_economics"
+}
+
+// economics compares each trade's gas cost with the marketplace fee it earns and applies the
+// configured policy. A nil *economics disables the guard.
+type economics struct {
+	policy string
+	feed   *priceFeed
+	fees   FeeReader
+	repo   *Repository
+}
+
+// newEconomics builds the profitability guard, or returns nil when no price feed is configured.
+func newEconomics(cfg *config.Config, fees FeeReader, repo *Repository) (*economics, error) {
+	if cfg.ExecutionPriceFeedFile == "" {
+		return nil, nil
+	}
+	switch cfg.ExecutionProfitPolicy {
+	case ProfitPolicyAllow, ProfitPolicyReject, ProfitPolicyDefer:
+	default:
+		return nil, fmt.Errorf("invalid EXECUTION_PROFIT_POLICY %q (allow, reject or defer)", cfg.ExecutionProfitPolicy)
+	}
+	feed := &priceFeed{path: cfg.ExecutionPriceFeedFile}
+	if err := feed.reload(); err != nil {
+		return nil, err
+	}
+	return &economics{policy: cfg.ExecutionProfitPolicy, feed: feed, fees: fees, repo: repo}, nil
+}
+
+// evaluateProfitability prices a trade about to be sent and records the decision. It returns
+// the recorded decision, or an *UnprofitableError when the policy holds the trade back.
+// The guard fails open: when the fee configuration, gas price or token price is unavailable
+// the trade is allowed and the reason recorded.
+func (s *Service) evaluateProfitability(ctx context.Context, pair *preparedTrade, gasEstimate uint64) (*EconomicsDecision, error) {
+	e := s.economics
+	if e == nil {
+		return nil, nil
+	}
+
+	decision := &EconomicsDecision{
+		ExecutionKey: pair.key,
+		PaymentToken: strings.ToLower(pair.maker.PaymentToken.Hex()),
+		Price:        pair.maker.Price.String(),
+		GasEstimate:  gasEstimate,
+		Policy:       e.policy,
+		Decision:     ProfitDecisionAllow,
+	}
+	cost, revenue, reason := s.tradeEconomics(ctx, pair, decision)
+	decision.Reason = reason
+	if cost != nil && cost.Cmp(revenue) > 0 {
+		switch e.policy {
+		case ProfitPolicyReject:
+			decision.Decision = ProfitDecisionReject
+		case ProfitPolicyDefer:
+			decision.Decision = ProfitDecisionDefer
+		}
+		if decision.Reason == "" {
+			decision.Reason = "gas cost exceeds fee revenue"
+		}
+	}
+
+	if err := e.repo.CreateEconomicsDecision(ctx, decision); err != nil {
+		logger.Error("failed to record profitability decision", err, "executionKey", pair.key)
+	}
+	if decision.Decision != ProfitDecisionAllow {
+		return decision, &UnprofitableError{Decision: decision.Decision, Cost: cost, Revenue: revenue}
+	}
+	return decision, nil
+}
+
+// tradeEconomics fills in the decision's inputs and returns the gas cost and fee revenue in
+// payment token units. cost is nil, with a reason, when it cannot be determined.
+func (s *Service) tradeEconomics(ctx context.Context, pair *preparedTrade, d *EconomicsDecision) (cost, revenue *big.Int, reason string) {
+	e := s.economics
+	opts := &bind.CallOpts{Context: ctx}
+
+	feeBps, err := e.fees.FeeBps(opts)
+	if err != nil {
+		return nil, nil, "read feeBps: " + err.Error()
+	}
+	recipient, err := e.fees.FeeRecipient(opts)
+	if err != nil {
+		return nil, nil, "read feeRecipient: " + err.Error()
+	}
+	bps := feeBps.Int64()
+	d.FeeBps = &bps
+	// The contract only charges a fee when a recipient is set.
+	revenue = new(big.Int)
+	if recipient != (common.Address{}) {
+		revenue.Mul(pair.maker.Price, feeBps)
+		revenue.Quo(revenue, big.NewInt(percentageScale))
+	}
+	d.FeeRevenue = decimal(revenue.String())
+
+	gasPrice, err := s.marketGasPrice(ctx)
+	if err != nil {
+		return nil, revenue, "gas price: " + err.Error()
+	}
+	costWei := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(d.GasEstimate))
+	d.GasPrice = decimal(gasPrice.String())
+	d.GasCostWei = decimal(costWei.String())
+
+	if err := e.feed.reload(); err != nil {
+		logger.Warn("failed to reload price feed, using last prices", "error", err.Error())
+	}
+	native, token, ok := e.feed.prices(pair.maker.PaymentToken)
+	if !ok {
+		return nil, revenue, "no price feed entry for payment token"
+	}
+	d.NativeUSD = decimal(native.FloatString(8))
+	d.TokenUSD = decimal(token.usd.FloatString(8))
+
+	cost = token.fromNative(costWei, native)
+	d.GasCostToken = decimal(cost.String())
+	return cost, revenue, ""
+}
+
+// priceFeedFile is the price feed format: USD prices of the native currency and of each
+// payment token, keyed by token address. Token decimals are required: a missing value
+// would silently price the token in whole units.
+//
+//	{
+//	  "native": {"usd": "2500.00"},
+//	  "tokens": {"0xa0b8...eb48": {"usd": "1.00", "decimals": 6}}
+//	}
+type priceFeedFile struct {
+	Native struct {
+		USD string `json:"usd"`
+	} `json:"native"`
+	Tokens map[string]struct {
+		USD      string `json:"usd"`
+		Decimals *uint8 `json:"decimals"`
+	} `json:"tokens"`
+}
+
+type tokenPrice struct {
+	usd      *big.Rat
+	decimals uint8
+}
+
+// fromNative converts an amount of wei into base units of the token, rounding down.
+func (p tokenPrice) fromNative(wei *big.Int, nativeUSD *big.Rat) *big.Int {
+	value := new(big.Rat).SetInt(wei)
+	value.Mul(value, nativeUSD)
+	value.Mul(value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.decimals)), nil)))
+	value.Quo(value, new(big.Rat).SetInt(big.NewInt(1e18)))
+	value.Quo(value, p.usd)
+	return new(big.Int).Quo(value.Num(), value.Denom())
+}
+
+// priceFeed reads prices from a JSON file and reloads it whenever its modification time
+// changes, so an external job can update prices without a restart.
+type priceFeed struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	native  *big.Rat
+	tokens  map[common.Address]tokenPrice
+}
+
+// reload re-reads the file if it changed since the last load. On error the previous prices
+// stay in effect.
+func (f *priceFeed) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("price feed: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.native != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("price feed: %w", err)
+	}
+	var file priceFeedFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("price feed %s: %w", f.path, err)
+	}
+	native, err := parseUSD(file.Native.USD)
+	if err != nil {
+		return fmt.Errorf("price feed %s: native: %w", f.path, err)
+	}
+	tokens := make(map[common.Address]tokenPrice, len(file.Tokens))
+	for addr, entry := range file.Tokens {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("price feed %s: invalid token address %q", f.path, addr)
+		}
+		usd, err := parseUSD(entry.USD)
+		if err != nil {
+			return fmt.Errorf("price feed %s: token %s: %w", f.path, addr, err)
+		}
+		if entry.Decimals == nil {
+			return fmt.Errorf("price feed %s: token %s: missing decimals", f.path, addr)
+		}
+		tokens[common.HexToAddress(addr)] = tokenPrice{usd: usd, decimals: *entry.Decimals}
+	}
+
+	f.native, f.tokens, f.modTime = native, tokens, info.ModTime()
+	logger.Info("price feed loaded", "path", f.path, "tokens", len(tokens), "nativeUsd", file.Native.USD)
+	return nil
+}
+
+// prices returns the native price and the payment token's price.
+func (f *priceFeed) prices(token common.Address) (*big.Rat, tokenPrice, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	price, ok := f.tokens[token]
+	return f.native, price, ok && f.native != nil
+}
+
+func decimal(s string) *string {
+	return &s
+}
+
+func parseUSD(s string) (*big.Rat, error) {
+	price, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || price.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid usd price %q", s)
+	}
+	return price, nil
+}
@@ -0,0 +1,180 @@
+package execution
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFeeReader struct {
+	bps       int64
+	recipient common.Address
+}
+
+func (f *fakeFeeReader) FeeBps(*bind.CallOpts) (*big.Int, error) { return big.NewInt(f.bps), nil }
+
+func (f *fakeFeeReader) FeeRecipient(*bind.CallOpts) (common.Address, error) {
+	return f.recipient, nil
+}
+
+func writePriceFeed(t *testing.T, path, body string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// TestPriceFeed_ConvertsGasCostAndReloads converts a gas cost into token base units and picks
+// up a rewritten feed file.
+func TestPriceFeed_ConvertsGasCostAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	writePriceFeed(t, path, `{"native":{"usd":"2000"},"tokens":{"0x00000000000000000000000000000000000000C1":{"usd":"1.00","decimals":6}}}`, time.Now())
+
+	feed := &priceFeed{path: path}
+	require.NoError(t, feed.reload())
+	native, price, ok := feed.prices(token)
+	require.True(t, ok)
+	// 21000 gas at 10 gwei = 0.00021 ETH = $0.42 = 420000 units of a 6-decimal dollar token.
+	cost := price.fromNative(big.NewInt(21000*10e9), native)
+	require.Equal(t, "420000", cost.String())
+
+	_, _, ok = feed.prices(common.HexToAddress("0x00000000000000000000000000000000000000c2"))
+	require.False(t, ok)
+
+	writePriceFeed(t, path, `{"native":{"usd":"4000"},"tokens":{"0x00000000000000000000000000000000000000c1":{"usd":"1.00","decimals":6}}}`, time.Now().Add(time.Minute))
+	require.NoError(t, feed.reload())
+	native, price, _ = feed.prices(token)
+	require.Equal(t, "840000", price.fromNative(big.NewInt(21000*10e9), native).String())
+
+	// A broken rewrite keeps the last good prices.
+	writePriceFeed(t, path, `{"native":{"usd":"-1"}}`, time.Now().Add(2*time.Minute))
+	require.Error(t, feed.reload())
+	native, _, ok = feed.prices(token)
+	require.True(t, ok)
+	require.Equal(t, "4000", native.RatString())
+}
+
+// TestPriceFeed_RequiresTokenDecimals rejects a feed whose token entry omits decimals instead
+// of pricing the token in whole units.
+func TestPriceFeed_RequiresTokenDecimals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	writePriceFeed(t, path, `{"native":{"usd":"2000"},"tokens":{"0x00000000000000000000000000000000000000c1":{"usd":"1.00"}}}`, time.Now())
+
+	feed := &priceFeed{path: path}
+	require.ErrorContains(t, feed.reload(), "missing decimals")
+	_, _, ok := feed.prices(common.HexToAddress("0x00000000000000000000000000000000000000c1"))
+	require.False(t, ok)
+
+	// Zero is a valid explicit value.
+	writePriceFeed(t, path, `{"native":{"usd":"2000"},"tokens":{"0x00000000000000000000000000000000000000c1":{"usd":"1.00","decimals":0}}}`, time.Now().Add(time.Minute))
+	require.NoError(t, feed.reload())
+	_, price, ok := feed.prices(common.HexToAddress("0x00000000000000000000000000000000000000c1"))
+	require.True(t, ok)
+	require.Zero(t, price.decimals)
+}
+
+// TestProfitability_PolicyDecisions prices trades on a simulated chain and checks each
+// policy's decision, the recorded rows and how deferred jobs are requeued.
+func TestProfitability_PolicyDecisions(t *testing.T) {
+	ctx := context.Background()
+	backend := simulated.NewBackend(types.GenesisAlloc{})
+	defer backend.Close()
+
+	path := filepath.Join(t.TempDir(), "prices.json")
+	writePriceFeed(t, path, `{"native":{"usd":"2000"},"tokens":{"0x00000000000000000000000000000000000000c1":{"usd":"1","decimals":6}}}`, time.Now())
+	feed := &priceFeed{path: path}
+	require.NoError(t, feed.reload())
+
+	svc := newTestService(t, 10)
+	svc.client = backend.Client()
+	svc.cfg.ExecutionProfitDeferDelay = time.Minute
+	svc.cfg.ExecutionProfitDeferMaxWait = time.Hour
+	fees := &fakeFeeReader{bps: 250, recipient: common.HexToAddress("0x00000000000000000000000000000000000000fe")}
+	svc.economics = &economics{policy: ProfitPolicyReject, feed: feed, fees: fees, repo: svc.repo}
+
+	prepare := func(nonce int, price string) *preparedTrade {
+		req := testTradeRequest(nonce)
+		req.MakerOrder.Price, req.TakerOrder.Price = price, price
+		pair, err := svc.prepareTrade(&req)
+		require.NoError(t, err)
+		return pair
+	}
+
+	// A 1000-unit trade earns 25 units against roughly 2e5 units of gas.
+	_, err := svc.evaluateProfitability(ctx, prepare(1, "1000"), 100000)
+	var unprofitable *UnprofitableError
+	require.ErrorAs(t, err, &unprofitable)
+	require.Equal(t, ProfitDecisionReject, unprofitable.Decision)
+	code, permanent := classifyExecuteError(err)
+	require.Equal(t, "Unprofitable", code)
+	require.True(t, permanent)
+
+	// A million-dollar trade easily pays for its gas.
+	decision, err := svc.evaluateProfitability(ctx, prepare(2, "1000000000000"), 100000)
+	require.NoError(t, err)
+	require.Equal(t, ProfitDecisionAllow, decision.Decision)
+	require.NoError(t, svc.repo.SetEconomicsTxHash(ctx, decision.ID, "0xabc"))
+
+	// Without a fee recipient nothing is earned; allow still sends and records it.
+	svc.economics.policy = ProfitPolicyAllow
+	fees.recipient = common.Address{}
+	decision, err = svc.evaluateProfitability(ctx, prepare(3, "1000000000000"), 100000)
+	require.NoError(t, err)
+	require.Equal(t, ProfitDecisionAllow, decision.Decision)
+	require.Equal(t, "0", *decision.FeeRevenue)
+	require.Equal(t, "gas cost exceeds fee revenue", decision.Reason)
+
+	// An unpriced payment token fails open.
+	req := testTradeRequest(4)
+	req.MakerOrder.PaymentToken = "0x00000000000000000000000000000000000000c2"
+	req.TakerOrder.PaymentToken = req.MakerOrder.PaymentToken
+	pair, err := svc.prepareTrade(&req)
+	require.NoError(t, err)
+	svc.economics.policy = ProfitPolicyReject
+	decision, err = svc.evaluateProfitability(ctx, pair, 100000)
+	require.NoError(t, err)
+	require.Nil(t, decision.GasCostToken)
+	require.Equal(t, "no price feed entry for payment token", decision.Reason)
+
+	var rows []EconomicsDecision
+	require.NoError(t, svc.repo.db.Order("id").Find(&rows).Error)
+	require.Len(t, rows, 4)
+	require.Equal(t, []ProfitDecision{ProfitDecisionReject, ProfitDecisionAllow, ProfitDecisionAllow, ProfitDecisionAllow},
+		[]ProfitDecision{rows[0].Decision, rows[1].Decision, rows[2].Decision, rows[3].Decision})
+	require.Equal(t, "0xabc", rows[1].TxHash)
+	require.NotNil(t, rows[0].GasCostToken)
+
+	// Deferred jobs go back to the queue without using an attempt, until the wait runs out.
+	svc.economics.policy = ProfitPolicyDefer
+	fees.recipient = common.HexToAddress("0x00000000000000000000000000000000000000fe")
+	_, err = svc.evaluateProfitability(ctx, prepare(5, "1000"), 100000)
+	require.ErrorAs(t, err, &unprofitable)
+	require.Equal(t, ProfitDecisionDefer, unprofitable.Decision)
+
+	_, _, err = svc.queue.Enqueue(ctx, "0xdefer", "{}")
+	require.NoError(t, err)
+	job, err := svc.queue.Claim(ctx)
+	require.NoError(t, err)
+	svc.deferJob(job, unprofitable)
+	job, err = svc.queue.Get(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, JobStatusQueued, job.Status)
+	require.Zero(t, job.Attempts)
+	require.True(t, job.AvailableAt.After(time.Now()))
+
+	job.CreatedAt = time.Now().Add(-2 * time.Hour)
+	svc.deferJob(job, unprofitable)
+	job, err = svc.queue.Get(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, JobStatusFailed, job.Status)
+	require.Equal(t, "Unprofitable", job.ErrorCode)
+}
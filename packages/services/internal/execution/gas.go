@@ -0,0 +1,170 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// feeParams carries either EIP-1559 fee caps or a legacy gas price.
+type feeParams struct {
+	gasPrice  *big.Int // legacy pricing, set only on pre-London chains
+	gasFeeCap *big.Int
+	gasTipCap *big.Int
+}
+
+// dynamic reports whether the fees describe an EIP-1559 transaction.
+func (f *feeParams) dynamic() bool {
+	return f.gasFeeCap != nil
+}
+
+// maxPrice returns the highest price per gas the transaction may pay.
+func (f *feeParams) maxPrice() *big.Int {
+	if f.dynamic() {
+		return f.gasFeeCap
+	}
+	return f.gasPrice
+}
+
+// apply copies the fees onto transaction options.
+func (f *feeParams) apply(auth *bind.TransactOpts) {
+	auth.GasPrice = f.gasPrice
+	auth.GasFeeCap = f.gasFeeCap
+	auth.GasTipCap = f.gasTipCap
+}
+
+// suggestFees prices a new transaction. On London chains the tip comes from
+// eth_maxPriorityFeePerGas and the fee cap allows the base fee to double before the
+// transaction becomes unincludable; both are clamped to the configured caps. Chains
+// without a base fee fall back to a legacy gas price.
+func (s *Service) suggestFees(ctx context.Context) (*feeParams, error) {
+	head, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch latest header: %w", err)
+	}
+
+	maxFee := gweiToWei(s.cfg.ExecutionMaxFeeGwei)
+
+	if head.BaseFee == nil {
+		gasPrice, err := s.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &feeParams{gasPrice: capAt(gasPrice, maxFee)}, nil
+	}
+
+	tip, err := s.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tip = capAt(tip, gweiToWei(s.cfg.ExecutionMaxPriorityFeeGwei))
+
+	feeCap := new(big.Int).Mul(head.BaseFee, big.NewInt(2))
+	feeCap.Add(feeCap, tip)
+	feeCap = capAt(feeCap, maxFee)
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	return &feeParams{gasFeeCap: feeCap, gasTipCap: tip}, nil
+}
+
+// tradeCall builds the executeTrade call message used for simulation and estimation.
+func (s *Service) tradeCall(from common.Address, maker, taker contracts.IMarketplaceOrder, makerSig []byte, fees *feeParams) (ethereum.CallMsg, error) {
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	if err != nil {
+		return ethereum.CallMsg{}, err
+	}
+	data, err := parsed.Pack("executeTrade", maker, taker, makerSig)
+	if err != nil {
+		return ethereum.CallMsg{}, err
+	}
+	return ethereum.CallMsg{
+		From:      from,
+		To:        &s.marketplaceAddr,
+		GasPrice:  fees.gasPrice,
+		GasFeeCap: fees.gasFeeCap,
+		GasTipCap: fees.gasTipCap,
+		Data:      data,
+	}, nil
+}
+
+// simulateTrade runs the trade through eth_call against the latest block. Reverts are
+// returned as *RevertError so the caller can report the decoded custom error.
+func (s *Service) simulateTrade(ctx context.Context, msg ethereum.CallMsg) error {
+	if _, err := s.client.CallContract(ctx, msg, nil); err != nil {
+		return fmt.Errorf("pre-flight simulation failed: %w", AsRevert(err))
+	}
+	return nil
+}
+
+// estimateTradeGas estimates executeTrade. A failed estimate means the call would revert,
+// so no transaction should be sent.
+func (s *Service) estimateTradeGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	estimate, err := s.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, fmt.Errorf("gas estimation failed: %w", AsRevert(err))
+	}
+	return estimate, nil
+}
+
+// gasLimitFor adds the configured safety margin to a gas estimate.
+func (s *Service) gasLimitFor(estimate uint64) uint64 {
+	margin := s.cfg.ExecutionGasLimitMarginPercent
+	if margin < 0 {
+		margin = 0
+	}
+	return estimate + estimate*uint64(margin)/100
+}
+
+// replacementFees prices a same-nonce replacement of prev. Every fee component is bumped
+// by at least the node replacement threshold and never falls below the current suggestion.
+// Configured caps are not applied: a replacement that does not outbid prev is rejected.
+func (s *Service) replacementFees(ctx context.Context, prev *types.Transaction) (*feeParams, error) {
+	current, err := s.suggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	percent := s.cfg.ExecutionGasBumpPercent
+
+	if prev.Type() == types.DynamicFeeTxType && current.dynamic() {
+		tip := maxBig(bumpGasPrice(prev.GasTipCap(), percent), current.gasTipCap)
+		feeCap := maxBig(bumpGasPrice(prev.GasFeeCap(), percent), current.gasFeeCap)
+		if tip.Cmp(feeCap) > 0 {
+			feeCap = new(big.Int).Set(tip)
+		}
+		return &feeParams{gasFeeCap: feeCap, gasTipCap: tip}, nil
+	}
+
+	return &feeParams{gasPrice: maxBig(bumpGasPrice(prev.GasPrice(), percent), current.maxPrice())}, nil
+}
+
+// gweiToWei converts a gwei amount to wei; zero or negative means no cap.
+func gweiToWei(gwei float64) *big.Int {
+	if gwei <= 0 {
+		return nil
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(1e9)).Int(nil)
+	return wei
+}
+
+// capAt returns v limited to limit; a nil limit leaves v unchanged.
+func capAt(v, limit *big.Int) *big.Int {
+	if limit != nil && v.Cmp(limit) > 0 {
+		return new(big.Int).Set(limit)
+	}
+	return v
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
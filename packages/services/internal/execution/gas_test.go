@@ -0,0 +1,17 @@
+package execution
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeCaps(t *testing.T) {
+	require.Nil(t, gweiToWei(0))
+	require.Equal(t, "1500000000", gweiToWei(1.5).String())
+
+	require.Equal(t, "100", capAt(big.NewInt(100), nil).String())
+	require.Equal(t, "50", capAt(big.NewInt(100), big.NewInt(50)).String())
+	require.Equal(t, "40", capAt(big.NewInt(40), big.NewInt(50)).String())
+}
@@ -0,0 +1,53 @@
+package execution
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// executionKey identifies a matched order pair: keccak256(makerOrderHash || takerOrderHash).
+// Repeated execute requests for the same pair resolve to the same key.
+func executionKey(makerHash, takerHash string) string {
+	return crypto.Keccak256Hash(common.FromHex(makerHash), common.FromHex(takerHash)).Hex()
+}
+
+// keyLocks serialises execute requests that share an execution key, so a retry that
+// arrives while the first attempt is still broadcasting waits for its result instead of
+// submitting a second transaction.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock blocks until the caller holds the lock for key and returns its unlock function.
+func (k *keyLocks) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,155 @@
+package execution
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// preflightClient answers the eth_call made before a trade is queued, failing it with revert
+// when set. Everything else is left to the nil ChainClient.
+type preflightClient struct {
+	ChainClient
+	revert error
+}
+
+func (c *preflightClient) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, c.revert
+}
+
+// newTestService builds a service with a SQLite-backed repository and queue and a chain
+// client that only answers the pre-queue simulation, for tests that stay off-chain.
+func newTestService(t *testing.T, capacity int) *Service {
+	t.Helper()
+	repo := newTestRepository(t)
+	gin.SetMode(gin.TestMode)
+	svc := &Service{
+		cfg: &config.Config{
+			ChainID:                   1337,
+			ExecutionQueueCapacity:    capacity,
+			ExecutionQueueMaxAttempts: 2,
+			ExecutionQueueRetryDelay:  time.Minute,
+		},
+		client:    &preflightClient{},
+		wallets:   newTestPool(t, newTestSigners(t, 1), nil, 0, nil),
+		repo:      repo,
+		engine:    gin.New(),
+		typedData: orders.NewTypedData(1337, common.HexToAddress("0x00000000000000000000000000000000000000aa")),
+		queue:     NewJobQueue(repo.db, capacity),
+		jobReady:  make(chan struct{}, 1),
+	}
+	svc.registerRoutes()
+	return svc
+}
+
+// testTradeRequest returns a well-formed execute request; nonce varies the order pair.
+func testTradeRequest(nonce int) ExecuteTradeRequest {
+	return ExecuteTradeRequest{
+		MakerOrder: OrderData{
+			Maker: "0x00000000000000000000000000000000000000a1", NFT: "0x00000000000000000000000000000000000000b1",
+			TokenID: "7", PaymentToken: "0x00000000000000000000000000000000000000c1",
+			Price: "1000", Expiry: 2000000000, Nonce: strconv.Itoa(nonce), Side: 0,
+		},
+		TakerOrder: OrderData{
+			Maker: "0x00000000000000000000000000000000000000a2", NFT: "0x00000000000000000000000000000000000000b1",
+			TokenID: "7", PaymentToken: "0x00000000000000000000000000000000000000c1",
+			Price: "1000", Expiry: 2000000000, Nonce: "4", Side: 1,
+		},
+		MakerSignature: "0x00",
+	}
+}
+
+// postExecute sends an execute request through the service's router.
+func postExecute(t *testing.T, svc *Service, req ExecuteTradeRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	svc.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/internal/execute", bytes.NewReader(body)))
+	return rec
+}
+
+// TestExecuteTrade_IdempotentPerOrderPair replays an execute request for a pair that was
+// already submitted: the worker returns the stored transaction without touching any wallet,
+// and later requests get the same execution back.
+func TestExecuteTrade_IdempotentPerOrderPair(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t, 10)
+	req := testTradeRequest(1)
+
+	pair, err := svc.prepareTrade(&req)
+	require.NoError(t, err)
+	require.NotEqual(t, pair.key, executionKey(pair.takerHash, pair.makerHash), "key must depend on order roles")
+
+	txHash := "0x" + strings.Repeat("ab", 32)
+	require.NoError(t, svc.repo.Create(ctx, &Execution{
+		TxHash: txHash, ExecutionKey: pair.key, MakerOrderHash: pair.makerHash, TakerOrderHash: pair.takerHash,
+		Status: ExecutionStatusConfirmed, SubmittedAt: time.Now(),
+	}))
+
+	resp, err := svc.executeTrade(ctx, &req)
+	require.NoError(t, err)
+	require.Equal(t, &ExecuteTradeResponse{
+		TxHash: txHash, Status: ExecutionStatusConfirmed, ExecutionKey: pair.key, Duplicate: true,
+	}, resp)
+
+	rec := postExecute(t, svc, req)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var queued EnqueueTradeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queued))
+	require.Equal(t, JobStatusQueued, queued.Status)
+	require.False(t, queued.Duplicate)
+
+	job, err := svc.queue.Claim(ctx)
+	require.NoError(t, err)
+	require.Equal(t, queued.ExecutionID, job.ID)
+	svc.processJob(ctx, job)
+
+	rec = postExecute(t, svc, req)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var again EnqueueTradeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &again))
+	require.Equal(t, EnqueueTradeResponse{
+		ExecutionID: queued.ExecutionID, ExecutionKey: pair.key, Status: JobStatusSubmitted, TxHash: txHash, Duplicate: true,
+	}, again)
+}
+
+// TestRepository_FindActiveByKey lets a pair whose transaction never landed be resubmitted.
+func TestRepository_FindActiveByKey(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	key := "0x" + strings.Repeat("11", 32)
+
+	found, err := repo.FindActiveByKey(ctx, key)
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	require.NoError(t, repo.Create(ctx, &Execution{
+		TxHash: "0x" + strings.Repeat("01", 32), ExecutionKey: key, Status: ExecutionStatusDropped, SubmittedAt: time.Now(),
+	}))
+	found, err = repo.FindActiveByKey(ctx, key)
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	require.NoError(t, repo.Create(ctx, &Execution{
+		TxHash: "0x" + strings.Repeat("02", 32), ExecutionKey: key, Status: ExecutionStatusSubmitted, SubmittedAt: time.Now(),
+	}))
+	found, err = repo.FindActiveByKey(ctx, key)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.Equal(t, "0x"+strings.Repeat("02", 32), found.TxHash)
+}
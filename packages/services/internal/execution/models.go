@@ -0,0 +1,81 @@
+package execution
+
+import "time"
+
+// ExecutionStatus represents the on-chain lifecycle state of a submitted trade transaction.
+type ExecutionStatus string
+
+const (
+	ExecutionStatusSubmitted ExecutionStatus = "submitted"
+	ExecutionStatusConfirmed ExecutionStatus = "confirmed"
+	ExecutionStatusReverted  ExecutionStatus = "reverted"
+	ExecutionStatusDropped   ExecutionStatus = "dropped"
+	// ExecutionStatusCancelled means a zero-value self-transfer took the nonce after the
+	// trade transaction stayed stuck through every gas bump; the trade was not executed.
+	ExecutionStatusCancelled ExecutionStatus = "cancelled"
+)
+
+// resubmittableStatuses are the outcomes that leave an order pair free to be submitted again:
+// the transaction never made it on-chain, or it reverted without settling the trade.
+var resubmittableStatuses = []ExecutionStatus{ExecutionStatusReverted, ExecutionStatusDropped, ExecutionStatusCancelled}
+
+// ReplacementKind distinguishes fee-bumped resubmissions from cancellations.
+type ReplacementKind string
+
+const (
+	ReplacementSpeedUp ReplacementKind = "speedup"
+	ReplacementCancel  ReplacementKind = "cancel"
+)
+
+// Execution records a trade transaction broadcast by the executor and its final outcome.
+type Execution struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	TxHash   string `gorm:"type:varchar(66);uniqueIndex;column:tx_hash" json:"txHash"`
+	Executor string `gorm:"type:varchar(42);index" json:"executor"`
+	Nonce    uint64 `gorm:"index" json:"nonce"`
+	GasLimit uint64 `gorm:"column:gas_limit" json:"gasLimit"`
+	// GasPrice is the maximum price per gas: the legacy gas price or the EIP-1559 fee cap.
+	GasPrice       string          `gorm:"type:numeric;column:gas_price" json:"gasPrice"`
+	GasTipCap      string          `gorm:"type:numeric;column:gas_tip_cap" json:"gasTipCap,omitempty"`
+	MakerOrderHash string          `gorm:"type:varchar(66);index;column:maker_order_hash" json:"makerOrderHash"`
+	TakerOrderHash string          `gorm:"type:varchar(66);index;column:taker_order_hash" json:"takerOrderHash"`
+	NFTAddress     string          `gorm:"type:varchar(66);column:nft_address" json:"nftAddress"`
+	TokenID        string          `gorm:"type:numeric;column:token_id" json:"tokenId"`
+	Status         ExecutionStatus `gorm:"type:varchar(16);index" json:"status"`
+	BlockNumber    *uint64         `gorm:"column:block_number" json:"blockNumber,omitempty"`
+	GasUsed        *uint64         `gorm:"column:gas_used" json:"gasUsed,omitempty"`
+	Error          string          `gorm:"type:text" json:"error,omitempty"`
+	// ExecutionKey is keccak256(makerOrderHash || takerOrderHash); repeated execute
+	// requests for the same pair return this execution instead of submitting again.
+	ExecutionKey string `gorm:"type:varchar(66);index;column:execution_key" json:"executionKey"`
+	// FinalTxHash is the hash that was actually mined for this nonce: the original
+	// transaction or one of its replacements.
+	FinalTxHash  string                 `gorm:"type:varchar(66);column:final_tx_hash" json:"finalTxHash,omitempty"`
+	Replacements []ExecutionReplacement `gorm:"foreignKey:ExecutionID" json:"replacements,omitempty"`
+	SubmittedAt  time.Time              `json:"submittedAt"`
+	FinalizedAt  *time.Time             `json:"finalizedAt,omitempty"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	UpdatedAt    time.Time              `json:"updatedAt"`
+}
+
+// TableName overrides default table name.
+func (Execution) TableName() string {
+	return "executions"
+}
+
+// ExecutionReplacement records a transaction that reused an execution's nonce,
+// either to bump fees on a stuck trade or to cancel it.
+type ExecutionReplacement struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	ExecutionID uint            `gorm:"index;column:execution_id" json:"-"`
+	TxHash      string          `gorm:"type:varchar(66);uniqueIndex;column:tx_hash" json:"txHash"`
+	Kind        ReplacementKind `gorm:"type:varchar(16)" json:"kind"`
+	GasPrice    string          `gorm:"type:numeric;column:gas_price" json:"gasPrice"`
+	GasTipCap   string          `gorm:"type:numeric;column:gas_tip_cap" json:"gasTipCap,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+// TableName overrides default table name.
+func (ExecutionReplacement) TableName() string {
+	return "execution_replacements"
+}
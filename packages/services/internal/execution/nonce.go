@@ -0,0 +1,272 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PendingNonceSource reports the node's view of an account's next nonce.
+type PendingNonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// ExecutorNonce is the persisted head of a wallet's nonce sequence.
+type ExecutorNonce struct {
+	Executor  string    `gorm:"type:varchar(42);primaryKey"`
+	NextNonce uint64    `gorm:"column:next_nonce"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName overrides default table name.
+func (ExecutorNonce) TableName() string {
+	return "executor_nonces"
+}
+
+// ExecutorNonceGap is a nonce below the head that was reserved but never broadcast.
+// Gaps are handed out again before the head advances so later transactions are not blocked.
+type ExecutorNonceGap struct {
+	Executor  string `gorm:"type:varchar(42);primaryKey"`
+	Nonce     uint64 `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// TableName overrides default table name.
+func (ExecutorNonceGap) TableName() string {
+	return "executor_nonce_gaps"
+}
+
+// NonceStore persists per-wallet nonce sequences in Postgres. Every operation is a single
+// atomic statement, so concurrent reservations (across goroutines or processes) never
+// receive the same nonce.
+type NonceStore struct {
+	db *gorm.DB
+}
+
+// NewNonceStore constructs the nonce store.
+func NewNonceStore(db *gorm.DB) *NonceStore {
+	return &NonceStore{db: db}
+}
+
+// Sync moves a wallet's sequence forward to the chain's pending nonce and returns the head.
+// The head never moves back and only gaps below the pending nonce (already used on chain)
+// are discarded: reservations above it may still be on their way to the node.
+func (s *NonceStore) Sync(ctx context.Context, executor common.Address, pending uint64) (uint64, error) {
+	return s.sync(ctx, executor, pending, false)
+}
+
+// Recover is Sync for startup, when no reservation of this process is in flight. Every
+// nonce in [pending, head) that no execution was sent with is additionally recorded as a
+// gap: its reservation was lost before broadcast (e.g. in a crash) and would otherwise hold
+// back every later transaction. Gaps are handed out again before the head advances.
+func (s *NonceStore) Recover(ctx context.Context, executor common.Address, pending uint64) (uint64, error) {
+	return s.sync(ctx, executor, pending, true)
+}
+
+func (s *NonceStore) sync(ctx context.Context, executor common.Address, pending uint64, reclaim bool) (uint64, error) {
+	key := executorKey(executor)
+	var head ExecutorNonce
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("executor = ? AND nonce < ?", key, pending).Delete(&ExecutorNonceGap{}).Error; err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "executor"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"next_nonce": gorm.Expr("CASE WHEN executor_nonces.next_nonce > excluded.next_nonce THEN executor_nonces.next_nonce ELSE excluded.next_nonce END"),
+				"updated_at": gorm.Expr("excluded.updated_at"),
+			}),
+		}).Create(&ExecutorNonce{Executor: key, NextNonce: pending, UpdatedAt: time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("executor = ?", key).First(&head).Error; err != nil {
+			return err
+		}
+		if !reclaim {
+			return nil
+		}
+		return recordLostReservations(tx, key, pending, head.NextNonce)
+	})
+	return head.NextNonce, err
+}
+
+// recordLostReservations records the nonces in [from, to) that no execution was sent with as gaps.
+func recordLostReservations(tx *gorm.DB, key string, from, to uint64) error {
+	if from >= to {
+		return nil
+	}
+	var used []uint64
+	if err := tx.Model(&Execution{}).
+		Where("executor = ? AND nonce >= ? AND nonce < ?", key, from, to).
+		Distinct().Pluck("nonce", &used).Error; err != nil {
+		return err
+	}
+	sent := make(map[uint64]bool, len(used))
+	for _, n := range used {
+		sent[n] = true
+	}
+
+	var lost []ExecutorNonceGap
+	for n := from; n < to; n++ {
+		if !sent[n] {
+			lost = append(lost, ExecutorNonceGap{Executor: key, Nonce: n, CreatedAt: time.Now()})
+		}
+	}
+	if len(lost) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lost).Error
+}
+
+// Reserve atomically hands out the lowest recorded gap, or else the head of the sequence.
+func (s *NonceStore) Reserve(ctx context.Context, executor common.Address) (uint64, error) {
+	key := executorKey(executor)
+
+	var gaps []ExecutorNonceGap
+	err := s.db.WithContext(ctx).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "nonce"}}}).
+		Where("executor = ? AND nonce = (SELECT MIN(nonce) FROM executor_nonce_gaps WHERE executor = ?)", key, key).
+		Delete(&gaps).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(gaps) > 0 {
+		return gaps[0].Nonce, nil
+	}
+
+	var heads []ExecutorNonce
+	result := s.db.WithContext(ctx).Model(&heads).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "next_nonce"}}}).
+		Where("executor = ?", key).
+		Updates(map[string]any{
+			"next_nonce": gorm.Expr("next_nonce + 1"),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(heads) == 0 {
+		return 0, errNonceNotSynced
+	}
+	return heads[0].NextNonce - 1, nil
+}
+
+// Release returns a reserved nonce that was never broadcast. If it is the most recent
+// reservation the head moves back; otherwise the nonce is recorded as a gap and gap is true,
+// meaning later transactions are queued behind it until it is filled.
+func (s *NonceStore) Release(ctx context.Context, executor common.Address, nonce uint64) (gap bool, err error) {
+	key := executorKey(executor)
+	result := s.db.WithContext(ctx).Model(&ExecutorNonce{}).
+		Where("executor = ? AND next_nonce = ?", key, nonce+1).
+		Updates(map[string]any{"next_nonce": nonce, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return false, nil
+	}
+	err = s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&ExecutorNonceGap{Executor: key, Nonce: nonce, CreatedAt: time.Now()}).Error
+	return err == nil, err
+}
+
+// Claim removes a specific gap so the caller can fill it. It returns false when the gap
+// was already taken by a concurrent reservation.
+func (s *NonceStore) Claim(ctx context.Context, executor common.Address, nonce uint64) (bool, error) {
+	result := s.db.WithContext(ctx).
+		Where("executor = ? AND nonce = ?", executorKey(executor), nonce).
+		Delete(&ExecutorNonceGap{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Next returns the head of the sequence.
+func (s *NonceStore) Next(ctx context.Context, executor common.Address) (uint64, error) {
+	var head ExecutorNonce
+	if err := s.db.WithContext(ctx).Where("executor = ?", executorKey(executor)).First(&head).Error; err != nil {
+		return 0, err
+	}
+	return head.NextNonce, nil
+}
+
+var errNonceNotSynced = errors.New("executor nonce sequence not initialised; sync from chain first")
+
+func executorKey(addr common.Address) string {
+	return strings.ToLower(addr.Hex())
+}
+
+// nonceManager is a wallet's view of its persisted nonce sequence. It caches the head for
+// scheduling decisions; the store remains the source of truth for reservations.
+type nonceManager struct {
+	store   *NonceStore
+	address common.Address
+
+	mu   sync.Mutex
+	next uint64
+}
+
+// sync moves the sequence forward to the chain's pending nonce; see NonceStore.Sync.
+func (m *nonceManager) sync(ctx context.Context, source PendingNonceSource) error {
+	return m.resync(ctx, source, m.store.Sync)
+}
+
+// recover resynchronises the sequence on startup; see NonceStore.Recover.
+func (m *nonceManager) recover(ctx context.Context, source PendingNonceSource) error {
+	return m.resync(ctx, source, m.store.Recover)
+}
+
+func (m *nonceManager) resync(ctx context.Context, source PendingNonceSource, apply func(context.Context, common.Address, uint64) (uint64, error)) error {
+	pending, err := source.PendingNonceAt(ctx, m.address)
+	if err != nil {
+		return err
+	}
+	head, err := apply(ctx, m.address, pending)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.next = head
+	m.mu.Unlock()
+	return nil
+}
+
+// reserve hands out the next nonce for the wallet.
+func (m *nonceManager) reserve(ctx context.Context) (uint64, error) {
+	nonce, err := m.store.Reserve(ctx, m.address)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	if nonce+1 > m.next {
+		m.next = nonce + 1
+	}
+	m.mu.Unlock()
+	return nonce, nil
+}
+
+// release returns a nonce that was never broadcast; see NonceStore.Release.
+func (m *nonceManager) release(ctx context.Context, nonce uint64) (bool, error) {
+	gap, err := m.store.Release(ctx, m.address, nonce)
+	if err == nil && !gap {
+		m.mu.Lock()
+		if m.next == nonce+1 {
+			m.next = nonce
+		}
+		m.mu.Unlock()
+	}
+	return gap, err
+}
+
+// peek returns the cached head without reserving it.
+func (m *nonceManager) peek() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.next
+}
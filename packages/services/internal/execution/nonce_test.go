@@ -0,0 +1,241 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonceStore_ReserveReleaseAndGaps(t *testing.T) {
+	ctx := context.Background()
+	store := NewNonceStore(newTestRepository(t).db)
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+
+	_, err := store.Reserve(ctx, addr)
+	require.ErrorIs(t, err, errNonceNotSynced)
+
+	head, err := store.Sync(ctx, addr, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), head)
+	for want := uint64(5); want < 8; want++ {
+		got, err := store.Reserve(ctx, addr)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	// Releasing the newest reservation moves the head back.
+	gap, err := store.Release(ctx, addr, 7)
+	require.NoError(t, err)
+	require.False(t, gap)
+
+	// Releasing an older one records a gap that is handed out first.
+	gap, err = store.Release(ctx, addr, 5)
+	require.NoError(t, err)
+	require.True(t, gap)
+
+	next, err := store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), next)
+	next, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), next)
+
+	// A claimed gap cannot be reserved again; sync discards gaps below the pending nonce.
+	_, err = store.Release(ctx, addr, 6)
+	require.NoError(t, err)
+	claimed, err := store.Claim(ctx, addr, 6)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = store.Claim(ctx, addr, 6)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	_, err = store.Release(ctx, addr, 6)
+	require.NoError(t, err)
+	head, err = store.Sync(ctx, addr, 8)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), head)
+	next, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), next)
+
+	// A pending nonce behind the head neither moves the head back nor discards the gaps above
+	// it; a nonce that was sent (has an execution) is not reclaimed.
+	_, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	gap, err = store.Release(ctx, addr, 8)
+	require.NoError(t, err)
+	require.True(t, gap)
+	require.NoError(t, store.db.Create(&Execution{
+		TxHash: "0x09", Executor: executorKey(addr), Nonce: 9, GasPrice: "1", Status: ExecutionStatusSubmitted,
+	}).Error)
+	head, err = store.Recover(ctx, addr, 8)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), head)
+	next, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), next)
+	next, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), next)
+}
+
+// TestNonceStore_SyncReclaimsLostReservation reserves a nonce that is never broadcast (as if
+// the process crashed before sending) and checks the next sync hands it out again.
+func TestNonceStore_SyncReclaimsLostReservation(t *testing.T) {
+	ctx := context.Background()
+	store := NewNonceStore(newTestRepository(t).db)
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+
+	_, err := store.Sync(ctx, addr, 5)
+	require.NoError(t, err)
+	lost, err := store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), lost)
+
+	// A runtime resync leaves the reservation alone: it may still be on its way to the node.
+	head, err := store.Sync(ctx, addr, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), head)
+	next, err := store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), next)
+	_, err = store.Release(ctx, addr, 6)
+	require.NoError(t, err)
+
+	// Restart: the chain never saw nonce 5.
+	head, err = store.Recover(ctx, addr, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), head)
+	next, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, lost, next)
+	next, err = store.Reserve(ctx, addr)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), next)
+}
+
+// TestNonceManager_ConcurrentSubmissions sends hundreds of transactions from one wallet in
+// parallel against a simulated chain, failing some before broadcast. Every nonce must be
+// used exactly once: released nonces are reused or filled, and the chain ends up exactly at
+// the head of the persisted sequence with no gaps left behind.
+func TestNonceManager_ConcurrentSubmissions(t *testing.T) {
+	const (
+		trades      = 300
+		parallelism = 32 // keeps out-of-order nonces within the txpool's per-account queue
+	)
+
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewKeySigner(key)
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		signer.Address(): {Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))},
+	})
+	defer backend.Close()
+	client := backend.Client()
+
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+
+	repo := newTestRepository(t)
+	store := NewNonceStore(repo.db)
+	svc := &Service{
+		cfg:     &config.Config{ChainID: chainID.Uint64()},
+		client:  client,
+		wallets: newWalletPool([]Signer{signer}, store, nil, 0, time.Minute, nil),
+	}
+	require.NoError(t, svc.wallets.syncNonces(ctx, client))
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, parallelism)
+		mu       sync.Mutex
+		sent     int
+		released int
+		errs     []error
+	)
+	// require must not be called off the test goroutine; errors are collected and checked
+	// once every submission has finished.
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	for i := 0; i < trades; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			w, release, err := svc.wallets.acquire(ctx, "0xnft", strconv.Itoa(i))
+			if err != nil {
+				fail(fmt.Errorf("acquire wallet: %w", err))
+				return
+			}
+			defer release()
+
+			nonce, err := w.nonces.reserve(ctx)
+			if err != nil {
+				fail(fmt.Errorf("reserve nonce: %w", err))
+				return
+			}
+
+			if i%10 == 3 {
+				svc.releaseNonce(ctx, w, nonce, errors.New("simulated broadcast failure"))
+				mu.Lock()
+				released++
+				mu.Unlock()
+				return
+			}
+
+			if _, err := svc.sendSelfTransfer(ctx, w, nonce); err != nil {
+				fail(fmt.Errorf("send nonce %d: %w", nonce, err))
+				return
+			}
+			mu.Lock()
+			sent++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	require.Empty(t, errs)
+	require.Equal(t, trades, sent+released)
+
+	// Mine until the pool is drained.
+	for i := 0; i < 100; i++ {
+		backend.Commit()
+		pending, err := client.PendingNonceAt(ctx, signer.Address())
+		require.NoError(t, err)
+		mined, err := client.NonceAt(ctx, signer.Address(), nil)
+		require.NoError(t, err)
+		if pending == mined {
+			break
+		}
+	}
+
+	mined, err := client.NonceAt(ctx, signer.Address(), nil)
+	require.NoError(t, err)
+	head, err := store.Next(ctx, signer.Address())
+	require.NoError(t, err)
+	require.Equal(t, head, mined, "chain nonce must match the persisted head")
+	require.GreaterOrEqual(t, mined, uint64(sent), "every successful send must be mined")
+
+	var gaps int64
+	require.NoError(t, repo.db.Model(&ExecutorNonceGap{}).Count(&gaps).Error)
+	require.Zero(t, gaps)
+}
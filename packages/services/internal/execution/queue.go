@@ -0,0 +1,264 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQueueFull is returned by Enqueue when the queue is at capacity. Callers should back off
+// and resubmit later; nothing was stored.
+var ErrQueueFull = errors.New("execution queue is full")
+
+// JobStatus is the queue state of an execute request. It ends at submitted (a transaction
+// was broadcast and is tracked in executions) or failed.
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSubmitted JobStatus = "submitted"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// ExecutionJob is a queued execute request. Its ID is the execution ID returned to callers.
+type ExecutionJob struct {
+	ID           uint64    `gorm:"primaryKey" json:"id"`
+	ExecutionKey string    `gorm:"type:varchar(66);index;uniqueIndex:idx_execution_jobs_active_key,where:status IN ('queued'\\,'running');column:execution_key" json:"executionKey"`
+	Request      string    `gorm:"type:text" json:"-"`
+	Status       JobStatus `gorm:"type:varchar(16);index" json:"status"`
+	Attempts     int       `json:"attempts"`
+	TxHash       string    `gorm:"type:varchar(66);column:tx_hash" json:"txHash,omitempty"`
+	// ErrorCode is the decoded revert or request error of the last failed attempt.
+	ErrorCode string `gorm:"type:varchar(64);column:error_code" json:"errorCode,omitempty"`
+	Error     string `gorm:"type:text" json:"error,omitempty"`
+	// Permanent is set on failed jobs whose order pair cannot succeed if resubmitted.
+	Permanent   bool       `gorm:"not null;default:false" json:"permanent"`
+	AvailableAt time.Time  `gorm:"index;column:available_at" json:"-"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// TableName overrides default table name.
+func (ExecutionJob) TableName() string {
+	return "execution_jobs"
+}
+
+// JobQueue is a durable execution queue in Postgres. Jobs are claimed with a single
+// conditional UPDATE, so any number of workers and service instances can drain it.
+type JobQueue struct {
+	db       *gorm.DB
+	capacity int
+}
+
+// NewJobQueue constructs the queue. A non-positive capacity means unbounded.
+//
+// Capacity is a soft cap for backpressure, not a hard limit: Enqueue counts active jobs and
+// then inserts, so concurrent requests that all see room may overshoot it by at most the
+// number of requests in flight at once (one per pair, as the partial unique index still
+// applies). Making the count and insert atomic would need a table lock or serializable
+// transactions on every enqueue, which costs more than the overshoot.
+func NewJobQueue(db *gorm.DB, capacity int) *JobQueue {
+	return &JobQueue{db: db, capacity: capacity}
+}
+
+// Enqueue stores an execute request. If the order pair already has an active job (see Active)
+// that job is returned with duplicate set and nothing is stored.
+func (q *JobQueue) Enqueue(ctx context.Context, key, request string) (job *ExecutionJob, duplicate bool, err error) {
+	existing, err := q.Active(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		return existing, true, nil
+	}
+
+	// Soft cap: see NewJobQueue.
+	if q.capacity > 0 {
+		queued, running, err := q.Depth(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		if queued+running >= int64(q.capacity) {
+			return nil, false, ErrQueueFull
+		}
+	}
+
+	now := time.Now()
+	job = &ExecutionJob{
+		ExecutionKey: key,
+		Request:      request,
+		Status:       JobStatusQueued,
+		AvailableAt:  now,
+	}
+	created, err := q.create(ctx, job)
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		// A concurrent request for the pair was stored between the lookup and the insert.
+		existing, err := q.Active(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			return nil, false, fmt.Errorf("active job for %s disappeared", key)
+		}
+		return existing, true, nil
+	}
+	return job, false, nil
+}
+
+// create inserts a queued job unless the pair already has a queued or running one, which
+// the partial unique index idx_execution_jobs_active_key enforces across service instances.
+// It reports whether the job was stored.
+func (q *JobQueue) create(ctx context.Context, job *ExecutionJob) (bool, error) {
+	result := q.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "execution_key"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status IN ('queued','running')"}}},
+		DoNothing:   true,
+	}).Create(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Active returns the latest job of an order pair that is waiting, running, or submitted with a
+// transaction that is pending or confirmed (not reverted, dropped or cancelled), or nil when
+// there is none.
+func (q *JobQueue) Active(ctx context.Context, key string) (*ExecutionJob, error) {
+	var jobs []ExecutionJob
+	err := q.db.WithContext(ctx).
+		Where("execution_key = ?", key).
+		Where(q.db.Where("status IN ?", []JobStatus{JobStatusQueued, JobStatusRunning}).
+			Or("status = ? AND tx_hash IN (SELECT tx_hash FROM executions WHERE status NOT IN ?)",
+				JobStatusSubmitted, resubmittableStatuses)).
+		Order("id DESC").
+		Limit(1).
+		Find(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// Claim moves the oldest available queued job to running and returns it, or nil when there
+// is nothing to do. The status check in the UPDATE makes a job claimable only once.
+func (q *JobQueue) Claim(ctx context.Context) (*ExecutionJob, error) {
+	now := time.Now()
+	var jobs []ExecutionJob
+	err := q.db.WithContext(ctx).Model(&jobs).
+		Clauses(clause.Returning{}).
+		Where("id = (SELECT id FROM execution_jobs WHERE status = ? AND available_at <= ? ORDER BY id LIMIT 1) AND status = ?",
+			JobStatusQueued, now, JobStatusQueued).
+		Updates(map[string]any{
+			"status":     JobStatusRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+			"updated_at": now,
+		}).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// Complete records the transaction broadcast for a job.
+func (q *JobQueue) Complete(ctx context.Context, id uint64, txHash string) error {
+	now := time.Now()
+	return q.db.WithContext(ctx).Model(&ExecutionJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":      JobStatusSubmitted,
+			"tx_hash":     txHash,
+			"error_code":  "",
+			"error":       "",
+			"finished_at": &now,
+		}).Error
+}
+
+// Retry puts a job back in the queue after a transient failure; it becomes claimable after delay.
+func (q *JobQueue) Retry(ctx context.Context, id uint64, code string, cause error, delay time.Duration) error {
+	return q.db.WithContext(ctx).Model(&ExecutionJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":       JobStatusQueued,
+			"error_code":   code,
+			"error":        cause.Error(),
+			"available_at": time.Now().Add(delay),
+		}).Error
+}
+
+// Defer puts a job back in the queue without counting the attempt, for trades held back until
+// conditions change rather than because they failed.
+func (q *JobQueue) Defer(ctx context.Context, id uint64, code string, cause error, delay time.Duration) error {
+	return q.db.WithContext(ctx).Model(&ExecutionJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":       JobStatusQueued,
+			"attempts":     gorm.Expr("attempts - 1"),
+			"error_code":   code,
+			"error":        cause.Error(),
+			"available_at": time.Now().Add(delay),
+		}).Error
+}
+
+// Fail marks a job as finally failed. permanent records whether resubmitting the order pair
+// can succeed, so the matching engine can decide between backing off and dead-lettering it.
+func (q *JobQueue) Fail(ctx context.Context, id uint64, code string, permanent bool, cause error) error {
+	now := time.Now()
+	return q.db.WithContext(ctx).Model(&ExecutionJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":      JobStatusFailed,
+			"error_code":  code,
+			"permanent":   permanent,
+			"error":       cause.Error(),
+			"finished_at": &now,
+		}).Error
+}
+
+// RequeueStale returns jobs that have been running since before cutoff to the queue. Their
+// worker is presumed dead; if it did broadcast, the execution key makes the rerun return
+// that transaction instead of sending another.
+func (q *JobQueue) RequeueStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := q.db.WithContext(ctx).Model(&ExecutionJob{}).
+		Where("status = ? AND started_at < ?", JobStatusRunning, cutoff).
+		Updates(map[string]any{"status": JobStatusQueued, "available_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
+// Get retrieves a job by ID.
+func (q *JobQueue) Get(ctx context.Context, id uint64) (*ExecutionJob, error) {
+	var job ExecutionJob
+	if err := q.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Depth returns the number of queued and running jobs.
+func (q *JobQueue) Depth(ctx context.Context) (queued, running int64, err error) {
+	var rows []struct {
+		Status JobStatus
+		Count  int64
+	}
+	err = q.db.WithContext(ctx).Model(&ExecutionJob{}).
+		Select("status, COUNT(*) AS count").
+		Where("status IN ?", []JobStatus{JobStatusQueued, JobStatusRunning}).
+		Group("status").
+		Scan(&rows).Error
+	for _, row := range rows {
+		switch row.Status {
+		case JobStatusQueued:
+			queued = row.Count
+		case JobStatusRunning:
+			running = row.Count
+		}
+	}
+	return queued, running, err
+}
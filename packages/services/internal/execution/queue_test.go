@@ -0,0 +1,212 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJobQueue_Lifecycle covers claiming, retries, failure, stale requeue and resubmission
+// of an order pair whose transaction reverted or was dropped.
+func TestJobQueue_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	queue := NewJobQueue(repo.db, 0)
+
+	first, dup, err := queue.Enqueue(ctx, "0xkey1", "{}")
+	require.NoError(t, err)
+	require.False(t, dup)
+	_, _, err = queue.Enqueue(ctx, "0xkey2", "{}")
+	require.NoError(t, err)
+
+	// Jobs are claimed oldest first and only once.
+	job, err := queue.Claim(ctx)
+	require.NoError(t, err)
+	require.Equal(t, first.ID, job.ID)
+	require.Equal(t, JobStatusRunning, job.Status)
+	require.Equal(t, 1, job.Attempts)
+	second, err := queue.Claim(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, first.ID, second.ID)
+	none, err := queue.Claim(ctx)
+	require.NoError(t, err)
+	require.Nil(t, none)
+
+	// A delayed retry is not claimable until it becomes available.
+	require.NoError(t, queue.Retry(ctx, first.ID, "NoHealthyWallet", errors.New("busy"), time.Hour))
+	none, err = queue.Claim(ctx)
+	require.NoError(t, err)
+	require.Nil(t, none)
+	queued, running, err := queue.Depth(ctx)
+	require.NoError(t, err)
+	require.Equal(t, [2]int64{1, 1}, [2]int64{queued, running})
+
+	// The worker holding the second job disappears; the janitor hands it back.
+	n, err := queue.RequeueStale(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	job, err = queue.Claim(ctx)
+	require.NoError(t, err)
+	require.Equal(t, second.ID, job.ID)
+	require.Equal(t, 2, job.Attempts)
+	require.NoError(t, queue.Fail(ctx, job.ID, "NonceConsumed", true, errors.New("reverted")))
+
+	// A failed pair may be queued again.
+	retried, dup, err := queue.Enqueue(ctx, "0xkey2", "{}")
+	require.NoError(t, err)
+	require.False(t, dup)
+	require.NotEqual(t, second.ID, retried.ID)
+
+	// A submitted job stays the pair's execution until its transaction is dropped.
+	txHash := "0x" + strings.Repeat("cd", 32)
+	require.NoError(t, queue.Complete(ctx, retried.ID, txHash))
+	require.NoError(t, repo.Create(ctx, &Execution{TxHash: txHash, ExecutionKey: "0xkey2", Status: ExecutionStatusSubmitted, SubmittedAt: time.Now()}))
+	same, dup, err := queue.Enqueue(ctx, "0xkey2", "{}")
+	require.NoError(t, err)
+	require.True(t, dup)
+	require.Equal(t, retried.ID, same.ID)
+
+	require.NoError(t, repo.db.Model(&Execution{}).Where("tx_hash = ?", txHash).Update("status", ExecutionStatusReverted).Error)
+	resubmitted, dup, err := queue.Enqueue(ctx, "0xkey2", "{}")
+	require.NoError(t, err)
+	require.False(t, dup)
+	require.NotEqual(t, retried.ID, resubmitted.ID)
+	existing, err := repo.FindActiveByKey(ctx, "0xkey2")
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// The same holds for a dropped transaction.
+	job, err = queue.Claim(ctx)
+	require.NoError(t, err)
+	require.Equal(t, resubmitted.ID, job.ID)
+	droppedHash := "0x" + strings.Repeat("ef", 32)
+	require.NoError(t, queue.Complete(ctx, job.ID, droppedHash))
+	require.NoError(t, repo.Create(ctx, &Execution{TxHash: droppedHash, ExecutionKey: "0xkey2", Status: ExecutionStatusDropped, SubmittedAt: time.Now()}))
+	again, dup, err := queue.Enqueue(ctx, "0xkey2", "{}")
+	require.NoError(t, err)
+	require.False(t, dup)
+	require.NotEqual(t, resubmitted.ID, again.ID)
+}
+
+// TestJobQueue_OneActiveJobPerPair relies on the partial unique index when two requests for
+// the same pair both miss the lookup: only one job is stored and the other gets it back.
+func TestJobQueue_OneActiveJobPerPair(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	queue := NewJobQueue(repo.db, 0)
+
+	first := &ExecutionJob{ExecutionKey: "0xkey", Request: "{}", Status: JobStatusQueued, AvailableAt: time.Now()}
+	created, err := queue.create(ctx, first)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	second := &ExecutionJob{ExecutionKey: "0xkey", Request: "{}", Status: JobStatusQueued, AvailableAt: time.Now()}
+	created, err = queue.create(ctx, second)
+	require.NoError(t, err)
+	require.False(t, created)
+
+	job, dup, err := queue.Enqueue(ctx, "0xkey", "{}")
+	require.NoError(t, err)
+	require.True(t, dup)
+	require.Equal(t, first.ID, job.ID)
+
+	// Finished jobs do not hold the key.
+	require.NoError(t, queue.Fail(ctx, first.ID, "NonceConsumed", true, errors.New("reverted")))
+	third := &ExecutionJob{ExecutionKey: "0xkey", Request: "{}", Status: JobStatusQueued, AvailableAt: time.Now()}
+	created, err = queue.create(ctx, third)
+	require.NoError(t, err)
+	require.True(t, created)
+}
+
+// TestExecuteTrade_QueueBackpressure fills the queue and expects further pairs to be
+// rejected with 429 while repeats of queued pairs are still answered.
+func TestExecuteTrade_QueueBackpressure(t *testing.T) {
+	svc := newTestService(t, 2)
+
+	ids := make([]uint64, 0, 2)
+	for i := 1; i <= 2; i++ {
+		rec := postExecute(t, svc, testTradeRequest(i))
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		var resp EnqueueTradeResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		ids = append(ids, resp.ExecutionID)
+	}
+
+	rec := postExecute(t, svc, testTradeRequest(3))
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, queueFullRetryAfter, rec.Header().Get("Retry-After"))
+	var errBody struct {
+		Code      string `json:"code"`
+		Permanent bool   `json:"permanent"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errBody))
+	require.Equal(t, "QueueFull", errBody.Code)
+	require.False(t, errBody.Permanent)
+
+	rec = postExecute(t, svc, testTradeRequest(1))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	// Malformed orders are rejected before they reach the queue.
+	bad := testTradeRequest(4)
+	bad.MakerOrder.Price = "abc"
+	rec = postExecute(t, svc, bad)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	svc.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/internal/jobs/%d", ids[0]), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var view JobView
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &view))
+	require.Equal(t, JobStatusQueued, view.Status)
+	require.Nil(t, view.Execution)
+
+	rec = httptest.NewRecorder()
+	svc.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/jobs/999", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestExecuteTrade_PreflightRevertIsNotQueued answers a pair that would revert with the
+// decoded 422 before anything is queued, while a pair that already has a job is still
+// answered with that job.
+func TestExecuteTrade_PreflightRevertIsNotQueued(t *testing.T) {
+	svc := newTestService(t, 10)
+
+	rec := postExecute(t, svc, testTradeRequest(1))
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var queued EnqueueTradeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queued))
+
+	data := revertData(t, contracts.OeasyMarketplaceMetaData.ABI, "NonceConsumed")
+	svc.client = &preflightClient{revert: rpcRevert{data: hexutil.Encode(data)}}
+
+	rec = postExecute(t, svc, testTradeRequest(2))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	var errBody struct {
+		Code      string `json:"code"`
+		Permanent bool   `json:"permanent"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errBody))
+	require.Equal(t, "NonceConsumed", errBody.Code)
+	require.True(t, errBody.Permanent)
+
+	queuedJobs, _, err := svc.queue.Depth(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), queuedJobs)
+
+	rec = postExecute(t, svc, testTradeRequest(1))
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var again EnqueueTradeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &again))
+	require.Equal(t, queued.ExecutionID, again.ExecutionID)
+	require.True(t, again.Duplicate)
+}
@@ -0,0 +1,148 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// minReplacementBumpPercent is the smallest fee increase geth-compatible nodes accept
+	// for a same-nonce replacement (txpool.pricebump default).
+	minReplacementBumpPercent = 10
+
+	// cancelGasLimit covers a plain value transfer.
+	cancelGasLimit = 21000
+)
+
+var errNoSigner = errors.New("no executor wallet configured")
+
+// Replace implements Replacer. It re-signs prev's nonce with fees bumped by the configured
+// percentage (never below the node's replacement threshold nor the current suggestion),
+// keeping the EIP-1559 or legacy format of prev. With cancel set, the replacement is a
+// zero-value transfer to the executor itself.
+func (s *Service) Replace(ctx context.Context, prev *types.Transaction, cancel bool) (*types.Transaction, error) {
+	chainID := new(big.Int).SetUint64(s.cfg.ChainID)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), prev)
+	if err != nil {
+		return nil, err
+	}
+	if s.wallets == nil {
+		return nil, errNoSigner
+	}
+	w, ok := s.wallets.wallet(sender)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", errNoSigner, sender.Hex())
+	}
+
+	fees, err := s.replacementFees(ctx, prev)
+	if err != nil {
+		return nil, err
+	}
+
+	to, gas, value, data := prev.To(), prev.Gas(), prev.Value(), prev.Data()
+	if cancel {
+		self := sender
+		to, gas, value, data = &self, cancelGasLimit, new(big.Int), nil
+	}
+
+	tx, err := w.signer.SignTx(ctx, newTx(chainID, prev.Nonce(), fees, to, gas, value, data), chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// FillNonce implements Replacer. It sends a zero-value self-transfer from executor with the
+// given nonce, whose original transaction the node no longer knows about.
+func (s *Service) FillNonce(ctx context.Context, executor common.Address, nonce uint64) (*types.Transaction, error) {
+	if s.wallets == nil {
+		return nil, errNoSigner
+	}
+	w, ok := s.wallets.wallet(executor)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", errNoSigner, executor.Hex())
+	}
+	return s.sendSelfTransfer(ctx, w, nonce)
+}
+
+// fillNonceGap sends a zero-value self-transfer with a released nonce that later
+// transactions are queued behind. If the gap was already reused by another reservation
+// nothing is sent; if sending fails the nonce is released again for the next reservation.
+func (s *Service) fillNonceGap(ctx context.Context, w *wallet, nonce uint64) {
+	addr := w.signer.Address()
+	claimed, err := w.nonces.store.Claim(ctx, addr, nonce)
+	if err != nil || !claimed {
+		return
+	}
+
+	tx, err := s.sendSelfTransfer(ctx, w, nonce)
+	if err != nil {
+		logger.Error("failed to fill nonce gap", err, "wallet", addr.Hex(), "nonce", nonce)
+		if _, relErr := w.nonces.release(ctx, nonce); relErr != nil {
+			logger.Error("failed to release nonce", relErr, "wallet", addr.Hex(), "nonce", nonce)
+		}
+		return
+	}
+	logger.Warn("filled nonce gap with self-transfer", "wallet", addr.Hex(), "nonce", nonce, "txHash", tx.Hash().Hex())
+}
+
+// sendSelfTransfer signs and broadcasts a zero-value transfer to the wallet itself.
+func (s *Service) sendSelfTransfer(ctx context.Context, w *wallet, nonce uint64) (*types.Transaction, error) {
+	fees, err := s.suggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chainID := new(big.Int).SetUint64(s.cfg.ChainID)
+	self := w.signer.Address()
+	tx, err := w.signer.SignTx(ctx, newTx(chainID, nonce, fees, &self, cancelGasLimit, new(big.Int), nil), chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// newTx builds an unsigned EIP-1559 or legacy transaction depending on fees.
+func newTx(chainID *big.Int, nonce uint64, fees *feeParams, to *common.Address, gas uint64, value *big.Int, data []byte) *types.Transaction {
+	if fees.dynamic() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: fees.gasTipCap,
+			GasFeeCap: fees.gasFeeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: fees.gasPrice,
+		Gas:      gas,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	})
+}
+
+// bumpGasPrice raises price by percent, rounding up, with the node minimum as a floor.
+func bumpGasPrice(price *big.Int, percent int) *big.Int {
+	if percent < minReplacementBumpPercent {
+		percent = minReplacementBumpPercent
+	}
+	bumped := new(big.Int).Mul(price, big.NewInt(int64(100+percent)))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
@@ -0,0 +1,143 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository provides persistence for execution records.
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository constructs the execution repository.
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FinalState describes the terminal outcome of an execution.
+type FinalState struct {
+	Status      ExecutionStatus
+	TxHash      string
+	BlockNumber *uint64
+	GasUsed     *uint64
+	Error       string
+}
+
+// CreateEconomicsDecision records a profitability evaluation.
+func (r *Repository) CreateEconomicsDecision(ctx context.Context, d *EconomicsDecision) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+// SetEconomicsTxHash links a profitability decision to the transaction it allowed.
+func (r *Repository) SetEconomicsTxHash(ctx context.Context, id uint64, txHash string) error {
+	return r.db.WithContext(ctx).Model(&EconomicsDecision{}).Where("id = ?", id).Update("tx_hash", txHash).Error
+}
+
+// Create persists a newly submitted execution.
+func (r *Repository) Create(ctx context.Context, exec *Execution) error {
+	return r.db.WithContext(ctx).Create(exec).Error
+}
+
+// FindByTxHash retrieves an execution by the hash of its original transaction
+// or of any replacement sent for the same nonce.
+func (r *Repository) FindByTxHash(ctx context.Context, txHash string) (*Execution, error) {
+	var exec Execution
+	err := r.withReplacements(ctx).Where("tx_hash = ?", txHash).First(&exec).Error
+	if err == nil {
+		return &exec, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var replacement ExecutionReplacement
+	if err := r.db.WithContext(ctx).Where("tx_hash = ?", txHash).First(&replacement).Error; err != nil {
+		return nil, err
+	}
+	if err := r.withReplacements(ctx).First(&exec, replacement.ExecutionID).Error; err != nil {
+		return nil, err
+	}
+	return &exec, nil
+}
+
+// FindActiveByKey returns the latest execution for an order pair that still holds or
+// has settled its transaction, or nil. Dropped and cancelled executions never made it
+// on-chain and reverted ones did not settle the trade, so the pair may be submitted again;
+// the pre-submit simulation rejects it if the revert still applies.
+func (r *Repository) FindActiveByKey(ctx context.Context, key string) (*Execution, error) {
+	var exec Execution
+	err := r.db.WithContext(ctx).
+		Where("execution_key = ? AND status NOT IN ?", key, resubmittableStatuses).
+		Order("id DESC").
+		First(&exec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &exec, nil
+}
+
+// ListByStatus returns executions in the given state, oldest first.
+func (r *Repository) ListByStatus(ctx context.Context, status ExecutionStatus, limit int) ([]Execution, error) {
+	var result []Execution
+	if err := r.withReplacements(ctx).
+		Where("status = ?", status).
+		Order("submitted_at ASC").
+		Limit(limit).
+		Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PendingExecutor returns the executor wallet of the most recent still-submitted execution
+// for an NFT token, or "" when none is pending.
+func (r *Repository) PendingExecutor(ctx context.Context, nft, tokenID string) (string, error) {
+	var exec Execution
+	err := r.db.WithContext(ctx).
+		Select("executor").
+		Where("nft_address = ? AND token_id = ? AND status = ?", nft, tokenID, ExecutionStatusSubmitted).
+		Order("id DESC").
+		First(&exec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return exec.Executor, nil
+}
+
+// AddReplacement records a fee-bump or cancel transaction sent for an execution's nonce.
+func (r *Repository) AddReplacement(ctx context.Context, replacement *ExecutionReplacement) error {
+	return r.db.WithContext(ctx).Create(replacement).Error
+}
+
+// MarkFinal records the terminal state of an execution. Only executions still in
+// the submitted state are updated, so a late poll cannot overwrite a final outcome.
+func (r *Repository) MarkFinal(ctx context.Context, id uint, state FinalState) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&Execution{}).
+		Where("id = ? AND status = ?", id, ExecutionStatusSubmitted).
+		Updates(map[string]any{
+			"status":        state.Status,
+			"final_tx_hash": state.TxHash,
+			"block_number":  state.BlockNumber,
+			"gas_used":      state.GasUsed,
+			"error":         state.Error,
+			"finalized_at":  &now,
+		}).Error
+}
+
+// withReplacements preloads replacement transactions in submission order.
+func (r *Repository) withReplacements(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Replacements", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
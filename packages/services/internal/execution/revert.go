@@ -0,0 +1,145 @@
+package execution
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RevertError is a decoded contract revert returned by pre-flight simulation or estimation.
+// Permanent reverts will fail again for the same order pair until some on-chain state the
+// makers control changes (signature, nonce, ownership, balances, approvals); the others
+// (e.g. the marketplace being paused) may succeed on a later attempt.
+type RevertError struct {
+	Code      string            `json:"code"`
+	Args      map[string]string `json:"args,omitempty"`
+	Permanent bool              `json:"permanent"`
+	Data      string            `json:"data,omitempty"`
+}
+
+func (e *RevertError) Error() string {
+	if len(e.Args) == 0 {
+		return "execution reverted: " + e.Code
+	}
+	parts := make([]string, 0, len(e.Args))
+	for k, v := range e.Args {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("execution reverted: %s(%s)", e.Code, strings.Join(parts, ", "))
+}
+
+// permanentReverts lists custom errors caused by the order pair itself.
+var permanentReverts = map[string]bool{
+	"InvalidSignature":            true,
+	"InvalidOrder":                true,
+	"NonceConsumed":               true,
+	"MakerCannotBeTaker":          true,
+	"UnsupportedSide":             true,
+	"TransferFailed":              true,
+	"ECDSAInvalidSignature":       true,
+	"ECDSAInvalidSignatureLength": true,
+	"ECDSAInvalidSignatureS":      true,
+	"ERC20InsufficientBalance":    true,
+	"ERC20InsufficientAllowance":  true,
+	"ERC721IncorrectOwner":        true,
+	"ERC721InsufficientApproval":  true,
+	"ERC721NonexistentToken":      true,
+}
+
+// revertErrors indexes custom errors by selector. The marketplace bubbles up OpenZeppelin
+// errors raised by the NFT and payment token transfers, so their ABIs are included too.
+var revertErrors = buildRevertRegistry(
+	contracts.OeasyMarketplaceMetaData,
+	contracts.OeasyNFTMetaData,
+	contracts.MockUSDCMetaData,
+)
+
+func buildRevertRegistry(metas ...interface{ GetAbi() (*abi.ABI, error) }) map[[4]byte]abi.Error {
+	registry := make(map[[4]byte]abi.Error)
+	for _, meta := range metas {
+		parsed, err := meta.GetAbi()
+		if err != nil {
+			panic(fmt.Sprintf("parse contract ABI: %v", err))
+		}
+		for _, e := range parsed.Errors {
+			var selector [4]byte
+			copy(selector[:], e.ID[:4])
+			registry[selector] = e
+		}
+	}
+	return registry
+}
+
+// decodeRevert decodes revert data into a RevertError. Unknown selectors are reported
+// with their raw data and treated as non-permanent.
+func decodeRevert(data []byte) *RevertError {
+	revert := &RevertError{Data: hexutil.Encode(data)}
+	if len(data) < 4 {
+		revert.Code = "Reverted"
+		return revert
+	}
+
+	var selector [4]byte
+	copy(selector[:], data[:4])
+	if custom, ok := revertErrors[selector]; ok {
+		revert.Code = custom.Name
+		revert.Permanent = permanentReverts[custom.Name]
+		if values, err := custom.Inputs.Unpack(data[4:]); err == nil {
+			revert.Args = make(map[string]string, len(values))
+			for i, v := range values {
+				revert.Args[custom.Inputs[i].Name] = formatRevertArg(v)
+			}
+		}
+		return revert
+	}
+
+	// Error(string) and Panic(uint256).
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		revert.Code = "Reverted"
+		revert.Args = map[string]string{"reason": reason}
+		return revert
+	}
+
+	revert.Code = "UnknownError"
+	return revert
+}
+
+func formatRevertArg(v any) string {
+	switch val := v.(type) {
+	case common.Address:
+		return strings.ToLower(val.Hex())
+	case *big.Int:
+		return val.String()
+	case [32]byte:
+		return hexutil.Encode(val[:])
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// AsRevert converts an RPC error carrying revert data into a RevertError.
+// Errors without revert data (network failures, node errors) are returned unchanged.
+func AsRevert(err error) error {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return err
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return err
+	}
+	return decodeRevert(data)
+}
@@ -0,0 +1,73 @@
+package execution
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+// revertData ABI-encodes a custom error from the given contract metadata.
+func revertData(t *testing.T, abiJSON, name string, args ...any) []byte {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	require.NoError(t, err)
+	custom, ok := parsed.Errors[name]
+	require.True(t, ok, name)
+	packed, err := custom.Inputs.Pack(args...)
+	require.NoError(t, err)
+	return append(custom.ID[:4:4], packed...)
+}
+
+// rpcRevert mimics the JSON-RPC error ethclient returns for a reverted call.
+type rpcRevert struct{ data string }
+
+func (e rpcRevert) Error() string          { return "execution reverted" }
+func (e rpcRevert) ErrorCode() int         { return 3 }
+func (e rpcRevert) ErrorData() interface{} { return e.data }
+
+func TestDecodeRevert(t *testing.T) {
+	nonce := decodeRevert(revertData(t, contracts.OeasyMarketplaceMetaData.ABI, "NonceConsumed"))
+	require.Equal(t, "NonceConsumed", nonce.Code)
+	require.True(t, nonce.Permanent)
+
+	sender := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	balance := decodeRevert(revertData(t, contracts.MockUSDCMetaData.ABI, "ERC20InsufficientBalance",
+		sender, big.NewInt(5), big.NewInt(100)))
+	require.Equal(t, "ERC20InsufficientBalance", balance.Code)
+	require.True(t, balance.Permanent)
+	require.Equal(t, map[string]string{
+		"sender":  "0x00000000000000000000000000000000000000b1",
+		"balance": "5",
+		"needed":  "100",
+	}, balance.Args)
+	require.Equal(t, "execution reverted: ERC20InsufficientBalance(balance=5, needed=100, sender=0x00000000000000000000000000000000000000b1)", balance.Error())
+
+	paused := decodeRevert(revertData(t, contracts.OeasyMarketplaceMetaData.ABI, "EnforcedPause"))
+	require.Equal(t, "EnforcedPause", paused.Code)
+	require.False(t, paused.Permanent)
+
+	unknown := decodeRevert([]byte{0xde, 0xad, 0xbe, 0xef})
+	require.Equal(t, "UnknownError", unknown.Code)
+	require.False(t, unknown.Permanent)
+}
+
+func TestAsRevert(t *testing.T) {
+	data := revertData(t, contracts.OeasyNFTMetaData.ABI, "ERC721NonexistentToken", big.NewInt(7))
+
+	err := fmt.Errorf("pre-flight simulation failed: %w", AsRevert(rpcRevert{data: hexutil.Encode(data)}))
+	var revert *RevertError
+	require.True(t, errors.As(err, &revert))
+	require.Equal(t, "ERC721NonexistentToken", revert.Code)
+	require.Equal(t, "7", revert.Args["tokenId"])
+
+	plain := errors.New("connection refused")
+	require.Same(t, plain, AsRevert(plain))
+}
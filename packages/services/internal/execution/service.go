@@ -3,37 +3,53 @@
 // managing nonce sequencing, handling gas price estimation, and ensuring transaction reliability.
 //
 // Architecture:
-// - Maintains a hot wallet for signing and submitting transactions
+// - Maintains a pool of hot wallets, each with its own nonce sequence, for submitting transactions
 // - Implements robust nonce management to prevent transaction stuck/replacement issues
+// - Queues execute requests durably in Postgres and drains them with a bounded worker pool
 // - Monitors pending transactions and handles resubmission on failure
+// - Compares each trade's gas cost with its fee revenue and applies a profitability policy
 // - Updates order status after successful on-chain settlement
 //
 // Security:
-// - Private key loaded from environment variable (should use KMS in production)
-// - Only submits pre-validated matched orders from matching engine
+//   - Transactions are signed through a Signer: an encrypted keystore file or a remote signer
+//     (clef / eth_signTransaction); a raw key in EXECUTOR_PRIVATE_KEY is accepted for development only
+//   - Only submits pre-validated matched orders from matching engine; the internal API
+//     requires HMAC-signed requests (INTERNAL_AUTH_SECRET) and signs its responses
+//   - With no signer configured the service runs in simulate-only mode and never broadcasts
 package execution
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Oeasy-NFT/services/internal/config"
 	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/hmacauth"
 	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/Oeasy-NFT/services/internal/postgres"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// queueFullRetryAfter is the Retry-After value, in seconds, sent when the queue is full or
+// a circuit breaker is tripped.
+const queueFullRetryAfter = "5"
+
 var (
 	ErrInvalidTokenID = errors.New("invalid token ID format")
 	ErrInvalidPrice   = errors.New("invalid price format")
@@ -47,6 +63,32 @@ type ExecuteTradeRequest struct {
 	MakerSignature string    `json:"makerSignature" binding:"required"`
 }
 
+// ExecuteTradeResponse is the outcome of one execution attempt. Duplicate is set when the
+// order pair had already been submitted and the existing transaction is returned.
+type ExecuteTradeResponse struct {
+	TxHash       string          `json:"txHash"`
+	Status       ExecutionStatus `json:"status"`
+	ExecutionKey string          `json:"executionKey"`
+	Duplicate    bool            `json:"duplicate"`
+}
+
+// EnqueueTradeResponse is returned with 202 when an execute request is queued. ExecutionID
+// identifies the job at GET /internal/jobs/:id. Duplicate is set when the order pair already
+// had an active job, which is returned instead; TxHash is set once it has been broadcast.
+type EnqueueTradeResponse struct {
+	ExecutionID  uint64    `json:"executionId"`
+	ExecutionKey string    `json:"executionKey"`
+	Status       JobStatus `json:"status"`
+	TxHash       string    `json:"txHash,omitempty"`
+	Duplicate    bool      `json:"duplicate"`
+}
+
+// JobView is the status of a queued execution, with the tracked transaction once broadcast.
+type JobView struct {
+	*ExecutionJob
+	Execution *Execution `json:"execution,omitempty"`
+}
+
 // OrderData represents the order struct matching the smart contract.
 type OrderData struct {
 	Maker        string `json:"maker"`
@@ -59,18 +101,44 @@ type OrderData struct {
 	Side         uint8  `json:"side"`
 }
 
+// ChainClient is the Ethereum client surface used by the execution service.
+// *ethclient.Client and the simulated backend's client both satisfy it.
+type ChainClient interface {
+	bind.ContractBackend
+	ethereum.ChainStateReader
+	ethereum.TransactionReader
+}
+
 // Service handles submission of transactions to the blockchain.
 type Service struct {
-	cfg          *config.Config
-	cancel       context.CancelFunc
-	wg           sync.WaitGroup
-	client       *ethclient.Client
-	privateKey   *ecdsa.PrivateKey
-	fromAddress  common.Address
-	marketplace  *contracts.OeasyMarketplace
-	engine       *gin.Engine
-	nonceMu      sync.Mutex
-	pendingNonce uint64
+	cfg         *config.Config
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	client      ChainClient
+	wallets     *walletPool
+	marketplace *contracts.OeasyMarketplace
+	// marketplaceAddr is kept alongside the binding for raw calls such as gas estimation.
+	marketplaceAddr common.Address
+	engine          *gin.Engine
+	repo            *Repository
+	tracker         *Tracker
+	typedData       apitypes.TypedData
+	queue           *JobQueue
+	// jobReady wakes an idle worker when this instance enqueues a job.
+	jobReady chan struct{}
+	// pairLocks keeps concurrent requests for the same order pair from both broadcasting.
+	pairLocks keyLocks
+	// auth verifies that requests come from the matching engine and signs our responses.
+	auth *hmacauth.Authenticator
+	// breakers gate submissions on marketplace, wallet balance and gas price preconditions.
+	breakers      *breakers
+	pausedChecker PausedChecker
+	// economics holds back trades whose gas cost exceeds their fee revenue; nil when disabled.
+	economics *economics
+	// simulateOnly is set when no executor signer is configured: trades are only eth_call'ed
+	// from simulateFrom and never broadcast.
+	simulateOnly bool
+	simulateFrom common.Address
 }
 
 // NewService constructs the execution service.
@@ -81,59 +149,73 @@ func NewService(cfg *config.Config) (*Service, error) {
 		return nil, err
 	}
 
-	// Load executor private key from environment
-	// TODO: [Security] - Replace with KMS or hardware wallet for production environments
-	if cfg.PrivateKeyHex == "" {
-		logger.Info("no executor private key provided, execution service will run in read-only mode")
-		return &Service{cfg: cfg, client: client}, nil
+	// Load executor signers (keystores, remote signer accounts, or development keys)
+	signers, err := NewSignersFromConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	// 去除 0x 前缀（如果有）
-	privateKeyHex := strings.TrimPrefix(cfg.PrivateKeyHex, "0x")
-
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	// Every request to the internal API must be signed by the matching engine.
+	auth, err := hmacauth.New(cfg.InternalAuthSecret, cfg.InternalAuthWindow)
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key: %w", err)
+		return nil, fmt.Errorf("INTERNAL_AUTH_SECRET: %w", err)
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
+	// Create marketplace contract instance
+	marketplaceAddr := common.HexToAddress(cfg.MarketplaceAddr)
+	marketplace, err := contracts.NewOeasyMarketplace(marketplaceAddr, client)
+	if err != nil {
 		return nil, err
 	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 
-	// Initialize nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	if len(signers) == 0 {
+		return newSimulateService(cfg, client, auth, marketplace, marketplaceAddr)
+	}
+
+	db, err := postgres.New(cfg.PostgresDSN)
 	if err != nil {
 		return nil, err
 	}
+	repo := NewRepository(db)
 
-	// Create marketplace contract instance
-	marketplaceAddr := common.HexToAddress(cfg.MarketplaceAddr)
-	marketplace, err := contracts.NewOeasyMarketplace(marketplaceAddr, client)
-	if err != nil {
+	wallets := newWalletPool(signers, NewNonceStore(db), ethToWei(cfg.ExecutionWalletMinBalanceEth),
+		cfg.ExecutionWalletMaxFailures, cfg.ExecutionWalletCooldown, repo.PendingExecutor)
+	if err := wallets.syncNonces(context.Background(), client); err != nil {
 		return nil, err
 	}
+	wallets.refresh(context.Background(), client)
 
 	gin.SetMode(gin.ReleaseMode)
 	ginEngine := gin.New()
 	ginEngine.Use(gin.Recovery())
 
 	svc := &Service{
-		cfg:          cfg,
-		client:       client,
-		privateKey:   privateKey,
-		fromAddress:  fromAddress,
-		marketplace:  marketplace,
-		engine:       ginEngine,
-		pendingNonce: nonce,
+		cfg:             cfg,
+		client:          client,
+		wallets:         wallets,
+		marketplace:     marketplace,
+		marketplaceAddr: marketplaceAddr,
+		engine:          ginEngine,
+		repo:            repo,
+		tracker:         NewTracker(client, repo, cfg.ExecutionReceiptPollInterval, cfg.ExecutionDropTimeout),
+		typedData:       orders.NewTypedData(cfg.ChainID, marketplaceAddr),
+		queue:           NewJobQueue(db, cfg.ExecutionQueueCapacity),
+		jobReady:        make(chan struct{}, 1),
+		auth:            auth,
+		breakers:        &breakers{ttl: cfg.ExecutionBreakerCacheTTL, now: time.Now},
+		pausedChecker:   marketplace,
+	}
+
+	svc.economics, err = newEconomics(cfg, marketplace, repo)
+	if err != nil {
+		return nil, err
 	}
 
+	svc.tracker.SetReplacer(svc, cfg.ExecutionStuckTimeout, cfg.ExecutionMaxGasBumps)
 	svc.registerRoutes()
 
 	logger.Info("execution service initialized",
-		"executor", fromAddress.Hex(),
+		"wallets", len(signers),
 		"marketplace", cfg.MarketplaceAddr,
 		"chainId", cfg.ChainID,
 	)
@@ -141,16 +223,98 @@ func NewService(cfg *config.Config) (*Service, error) {
 	return svc, nil
 }
 
+// newSimulateService builds a simulate-only service: /internal/execute validates and
+// eth_calls trades but nothing is signed, queued or broadcast, so no Postgres is needed.
+func newSimulateService(cfg *config.Config, client *ethclient.Client, auth *hmacauth.Authenticator, marketplace *contracts.OeasyMarketplace, marketplaceAddr common.Address) (*Service, error) {
+	if cfg.ExecutionSimulateFrom != "" && !common.IsHexAddress(cfg.ExecutionSimulateFrom) {
+		return nil, fmt.Errorf("invalid EXECUTION_SIMULATE_FROM %q", cfg.ExecutionSimulateFrom)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	ginEngine := gin.New()
+	ginEngine.Use(gin.Recovery())
+
+	svc := &Service{
+		cfg:             cfg,
+		client:          client,
+		marketplace:     marketplace,
+		marketplaceAddr: marketplaceAddr,
+		engine:          ginEngine,
+		typedData:       orders.NewTypedData(cfg.ChainID, marketplaceAddr),
+		auth:            auth,
+		simulateOnly:    true,
+		simulateFrom:    common.HexToAddress(cfg.ExecutionSimulateFrom),
+	}
+	svc.registerRoutes()
+
+	logger.Warn("no executor signer configured, execution service running in simulate-only mode",
+		"simulateFrom", svc.simulateFrom.Hex(),
+		"marketplace", cfg.MarketplaceAddr,
+		"chainId", cfg.ChainID,
+	)
+	return svc, nil
+}
+
 // registerRoutes sets up internal API endpoints for execution requests.
+// In simulate-only mode there are no jobs or executions to look up.
 func (s *Service) registerRoutes() {
 	api := s.engine.Group("/internal")
+	api.GET("/health", s.handleHealth)
+	if s.simulateOnly {
+		api.POST("/execute", s.handleSimulateTrade)
+		return
+	}
 	api.POST("/execute", s.handleExecuteTrade)
-	api.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "executor": s.fromAddress.Hex()})
-	})
+	api.GET("/executions/:txHash", s.handleGetExecution)
+	api.GET("/jobs/:id", s.handleGetJob)
+}
+
+// Handler exposes the internal HTTP API so it can be mounted in-process,
+// e.g. by the matching engine's in-process executor in single-binary deployments.
+// Everything except the health check requires an HMAC-signed request.
+func (s *Service) Handler() http.Handler {
+	if s.auth == nil {
+		return s.engine
+	}
+	return s.auth.Middleware(s.engine, "/internal/health")
+}
+
+// handleHealth reports the service mode and status, every executor wallet and the circuit
+// breakers. The service is degraded when no wallet can currently accept trades or a breaker
+// is tripped.
+func (s *Service) handleHealth(c *gin.Context) {
+	if s.simulateOnly {
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "mode": "simulate", "simulateFrom": s.simulateFrom.Hex()})
+		return
+	}
+
+	wallets := s.wallets.statuses()
+	status := "degraded"
+	for _, w := range wallets {
+		if w.Healthy {
+			status = "ok"
+			break
+		}
+	}
+	breakers := s.breakerStates(c.Request.Context())
+	for _, b := range breakers {
+		if b.Tripped {
+			status = "degraded"
+		}
+	}
+	body := gin.H{"status": status, "mode": "execute", "wallets": wallets, "breakers": breakers}
+	if s.queue != nil {
+		queued, running, err := s.queue.Depth(c.Request.Context())
+		if err != nil {
+			logger.Error("failed to read execution queue depth", err)
+		}
+		body["queue"] = gin.H{"queued": queued, "running": running, "capacity": s.cfg.ExecutionQueueCapacity}
+	}
+	c.JSON(http.StatusOK, body)
 }
 
-// handleExecuteTrade receives matched order pairs and submits them on-chain.
+// handleExecuteTrade validates a matched order pair and queues it for on-chain submission.
+// It answers 202 with the execution ID; the worker pool signs and broadcasts the trade.
 func (s *Service) handleExecuteTrade(c *gin.Context) {
 	var req ExecuteTradeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -158,25 +322,199 @@ func (s *Service) handleExecuteTrade(c *gin.Context) {
 		return
 	}
 
-	txHash, err := s.executeTrade(c.Request.Context(), &req)
+	resp, err := s.enqueueTrade(c.Request.Context(), &req)
 	if err != nil {
-		logger.Error("failed to execute trade", err,
-			"askMaker", req.MakerOrder.Maker,
-			"bidMaker", req.TakerOrder.Maker,
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, ErrQueueFull) {
+			logger.Warn("execution queue full, rejecting trade",
+				"askMaker", req.MakerOrder.Maker,
+				"bidMaker", req.TakerOrder.Maker,
+			)
+		} else {
+			logger.Error("failed to enqueue trade", err,
+				"askMaker", req.MakerOrder.Maker,
+				"bidMaker", req.TakerOrder.Maker,
+			)
+		}
+		respondExecuteError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"txHash": txHash.Hex(),
-		"status": "submitted",
-	})
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// enqueueTrade stores a validated execute request in the queue. Requests for an order pair
+// that already has an active job return that job. A new pair is first run through eth_call,
+// so a trade that would revert is answered with its decoded error instead of being queued.
+func (s *Service) enqueueTrade(ctx context.Context, req *ExecuteTradeRequest) (*EnqueueTradeResponse, error) {
+	pair, err := s.prepareTrade(req)
+	if err != nil {
+		return nil, err
+	}
+	// Refuse work while a breaker is tripped so the engine backs off instead of queueing
+	// trades that would revert, stall, or burn nonces.
+	if err := s.checkBreakers(ctx); err != nil {
+		return nil, err
+	}
+
+	active, err := s.queue.Active(ctx, pair.key)
+	if err != nil {
+		return nil, err
+	}
+	if active == nil {
+		if err := s.preflightTrade(ctx, pair, req.MakerSignature); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := s.pairLocks.lock(pair.key)
+	job, duplicate, err := s.queue.Enqueue(ctx, pair.key, string(payload))
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !duplicate {
+		s.notifyWorkers()
+	}
+
+	return &EnqueueTradeResponse{
+		ExecutionID:  job.ID,
+		ExecutionKey: pair.key,
+		Status:       job.Status,
+		TxHash:       job.TxHash,
+		Duplicate:    duplicate,
+	}, nil
+}
+
+// preflightTrade simulates the trade from an executor wallet without fee fields, as
+// dryRunTrade does; the worker simulates again with real fees right before signing.
+func (s *Service) preflightTrade(ctx context.Context, pair *preparedTrade, makerSignature string) error {
+	call, err := s.tradeCall(s.wallets.caller(), pair.maker, pair.taker, common.FromHex(makerSignature), &feeParams{})
+	if err != nil {
+		return err
+	}
+	return s.simulateTrade(ctx, call)
+}
+
+// respondExecuteError maps execution failures to typed responses. Reverts are reported
+// as 422 with the decoded custom error; malformed orders as 400. Both carry "permanent" so
+// callers know whether retrying the same pair can succeed. A 429 means the queue is full and
+// a 503 that a circuit breaker is tripped or no executor wallet can take the trade right now.
+// Anything else is a 500.
+func respondExecuteError(c *gin.Context, err error) {
+	var revert *RevertError
+	var breaker *BreakerError
+	switch {
+	case errors.As(err, &breaker):
+		c.Header("Retry-After", queueFullRetryAfter)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": breaker.Code, "permanent": false})
+	case errors.Is(err, ErrQueueFull):
+		c.Header("Retry-After", queueFullRetryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "QueueFull", "permanent": false})
+	case errors.As(err, &revert):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     err.Error(),
+			"code":      revert.Code,
+			"args":      revert.Args,
+			"permanent": revert.Permanent,
+		})
+	case errors.Is(err, ErrInvalidTokenID), errors.Is(err, ErrInvalidPrice), errors.Is(err, ErrInvalidNonce):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     err.Error(),
+			"code":      "InvalidRequest",
+			"permanent": true,
+		})
+	case errors.Is(err, ErrNoHealthyWallet):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "permanent": false})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "permanent": false})
+	}
+}
+
+// classifyExecuteError returns the error code and whether retrying the same order pair
+// can succeed, using the same rules as respondExecuteError.
+func classifyExecuteError(err error) (code string, permanent bool) {
+	var revert *RevertError
+	var breaker *BreakerError
+	var unprofitable *UnprofitableError
+	switch {
+	case errors.As(err, &revert):
+		return revert.Code, revert.Permanent
+	case errors.As(err, &breaker):
+		return breaker.Code, false
+	case errors.Is(err, ErrInvalidTokenID), errors.Is(err, ErrInvalidPrice), errors.Is(err, ErrInvalidNonce):
+		return "InvalidRequest", true
+	case errors.Is(err, ErrNoHealthyWallet):
+		return "NoHealthyWallet", false
+	case errors.As(err, &unprofitable):
+		return "Unprofitable", unprofitable.Decision == ProfitDecisionReject
+	default:
+		return "", false
+	}
+}
+
+// handleGetJob returns the queue status of an execution and, once it has been broadcast,
+// the tracked transaction.
+func (s *Service) handleGetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid execution id"})
+		return
+	}
+
+	job, err := s.queue.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		logger.Error("failed to load execution job", err, "executionId", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	view := JobView{ExecutionJob: job}
+	if job.TxHash != "" {
+		exec, err := s.repo.FindByTxHash(c.Request.Context(), strings.ToLower(job.TxHash))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("failed to load execution", err, "txHash", job.TxHash)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		view.Execution = exec
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// handleGetExecution returns the tracked lifecycle of a submitted trade transaction.
+func (s *Service) handleGetExecution(c *gin.Context) {
+	txHash := strings.ToLower(c.Param("txHash"))
+	if !isHexHash(txHash) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction hash"})
+		return
+	}
+
+	exec, err := s.repo.FindByTxHash(c.Request.Context(), txHash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		logger.Error("failed to load execution", err, "txHash", txHash)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exec)
 }
 
 // executeTrade submits the matched order pair to the marketplace contract.
-// Returns the transaction hash if successfully broadcast.
-func (s *Service) executeTrade(ctx context.Context, req *ExecuteTradeRequest) (*common.Hash, error) {
+// A pair that was already submitted returns its existing transaction instead.
+func (s *Service) executeTrade(ctx context.Context, req *ExecuteTradeRequest) (*ExecuteTradeResponse, error) {
 	logger.Info("executing trade",
 		"maker", req.MakerOrder.Maker,
 		"taker", req.TakerOrder.Maker,
@@ -185,72 +523,237 @@ func (s *Service) executeTrade(ctx context.Context, req *ExecuteTradeRequest) (*
 		"price", req.MakerOrder.Price,
 	)
 
-	// Convert OrderData to contract Order struct
-	makerOrder, err := s.convertToContractOrder(&req.MakerOrder)
+	pair, err := s.prepareTrade(req)
 	if err != nil {
 		return nil, err
 	}
+	makerOrder, takerOrder := pair.maker, pair.taker
+	makerHash, takerHash, key := pair.makerHash, pair.takerHash, pair.key
+
+	// Requests for the same order pair are idempotent: a retry after a timeout gets the
+	// transaction the first attempt broadcast rather than a second, doomed submission.
+	unlock := s.pairLocks.lock(key)
+	defer unlock()
 
-	takerOrder, err := s.convertToContractOrder(&req.TakerOrder)
+	existing, err := s.repo.FindActiveByKey(ctx, key)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("查询执行记录失败: %w", err)
+	}
+	if existing != nil {
+		logger.Info("order pair already submitted, returning existing execution",
+			"executionKey", key,
+			"txHash", existing.TxHash,
+			"status", existing.Status,
+		)
+		return &ExecuteTradeResponse{
+			TxHash:       existing.TxHash,
+			Status:       existing.Status,
+			ExecutionKey: key,
+			Duplicate:    true,
+		}, nil
 	}
 
 	// Decode maker signature
 	makerSig := common.FromHex(req.MakerSignature)
 
-	// 从链上重新获取最新 nonce（防止 nonce 不同步）
-	// 企业级最佳实践：每次交易前都从链上获取，避免 nonce 冲突
-	currentNonce, err := s.client.PendingNonceAt(ctx, s.fromAddress)
+	// Pick an executor wallet; trades for the same NFT token stay on one wallet.
+	w, release, err := s.wallets.acquire(ctx, req.MakerOrder.NFT, req.MakerOrder.TokenID)
 	if err != nil {
-		return nil, fmt.Errorf("获取 nonce 失败: %w", err)
+		return nil, err
 	}
+	defer release()
+	from := w.signer.Address()
 
-	// 更新内存中的 nonce（同步链上状态）
-	s.nonceMu.Lock()
-	if currentNonce > s.pendingNonce {
-		s.pendingNonce = currentNonce
+	// Price, simulate and estimate before reserving a nonce so a failure leaves no gap.
+	fees, err := s.suggestFees(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取 gas 价格失败: %w", err)
 	}
-	nonce := s.pendingNonce
-	s.pendingNonce++
-	s.nonceMu.Unlock()
-
-	// Create transaction options
-	chainID := big.NewInt(int64(s.cfg.ChainID))
-	auth, err := bind.NewKeyedTransactorWithChainID(s.privateKey, chainID)
+	call, err := s.tradeCall(from, makerOrder, takerOrder, makerSig, fees)
 	if err != nil {
 		return nil, err
 	}
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Context = ctx
+	if err := s.simulateTrade(ctx, call); err != nil {
+		return nil, err
+	}
+	estimate, err := s.estimateTradeGas(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	gasLimit := s.gasLimitFor(estimate)
 
-	// TODO: [Gas] - Implement dynamic gas price estimation
-	// For MVP, use suggested gas price from node
-	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	// Hold back trades whose gas would cost more than the fee they earn, per policy.
+	decision, err := s.evaluateProfitability(ctx, pair, estimate)
 	if err != nil {
 		return nil, err
 	}
-	auth.GasPrice = gasPrice
-	auth.GasLimit = 500000 // Conservative gas limit, should estimate in production
+
+	// 每个钱包的 nonce 序列持久化在 Postgres 中，原子分配，启动时从链上重新同步
+	nonce, err := w.nonces.reserve(ctx)
+	if err != nil {
+		s.wallets.recordResult(w, err)
+		return nil, fmt.Errorf("获取 nonce 失败: %w", err)
+	}
+
+	// Create transaction options
+	chainID := big.NewInt(int64(s.cfg.ChainID))
+	auth := TransactOpts(ctx, w.signer, chainID)
+	auth.Nonce = big.NewInt(int64(nonce))
+	fees.apply(auth)
+	auth.GasLimit = gasLimit
 
 	// Call marketplace.executeTrade
 	tx, err := s.marketplace.ExecuteTrade(auth, makerOrder, takerOrder, makerSig)
 	if err != nil {
-		// Rollback nonce on failure
-		s.nonceMu.Lock()
-		s.pendingNonce--
-		s.nonceMu.Unlock()
-		return nil, fmt.Errorf("合约调用失败: %w", err)
+		// Return the unbroadcast nonce so it does not leave a gap
+		s.releaseNonce(ctx, w, nonce, err)
+		s.wallets.recordResult(w, err)
+		return nil, fmt.Errorf("合约调用失败: %w", AsRevert(err))
 	}
+	s.wallets.recordResult(w, nil)
 
 	txHash := tx.Hash()
 	logger.Info("trade transaction submitted",
 		"txHash", txHash.Hex(),
+		"executor", from.Hex(),
 		"nonce", nonce,
-		"gasPrice", gasPrice.String(),
+		"gasLimit", gasLimit,
+		"maxFeePerGas", fees.maxPrice().String(),
+		"dynamicFee", fees.dynamic(),
 	)
 
-	return &txHash, nil
+	// The transaction is already broadcast, so a bookkeeping failure must not fail the request.
+	if err := s.recordExecution(ctx, from, tx, &makerOrder, key, makerHash, takerHash); err != nil {
+		logger.Error("failed to record execution", err, "txHash", txHash.Hex())
+	}
+	if decision != nil {
+		if err := s.repo.SetEconomicsTxHash(ctx, decision.ID, strings.ToLower(txHash.Hex())); err != nil {
+			logger.Error("failed to link profitability decision", err, "txHash", txHash.Hex())
+		}
+	}
+
+	return &ExecuteTradeResponse{
+		TxHash:       txHash.Hex(),
+		Status:       ExecutionStatusSubmitted,
+		ExecutionKey: key,
+	}, nil
+}
+
+// preparedTrade is an execute request converted to contract orders, with its order hashes
+// and execution key.
+type preparedTrade struct {
+	maker, taker         contracts.IMarketplaceOrder
+	makerHash, takerHash string
+	key                  string
+}
+
+// prepareTrade converts and hashes both orders of an execute request.
+func (s *Service) prepareTrade(req *ExecuteTradeRequest) (*preparedTrade, error) {
+	maker, err := s.convertToContractOrder(&req.MakerOrder)
+	if err != nil {
+		return nil, err
+	}
+	taker, err := s.convertToContractOrder(&req.TakerOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	makerHash, err := s.hashOrder(&maker)
+	if err != nil {
+		return nil, fmt.Errorf("hash maker order: %w", err)
+	}
+	takerHash, err := s.hashOrder(&taker)
+	if err != nil {
+		return nil, fmt.Errorf("hash taker order: %w", err)
+	}
+
+	return &preparedTrade{
+		maker:     maker,
+		taker:     taker,
+		makerHash: makerHash,
+		takerHash: takerHash,
+		key:       executionKey(makerHash, takerHash),
+	}, nil
+}
+
+// releaseNonce returns a nonce whose transaction was not broadcast. A "nonce too low"
+// rejection means the sequence is behind the chain, so it is resynchronised instead; a
+// released nonce that other transactions are already queued behind is filled right away.
+func (s *Service) releaseNonce(ctx context.Context, w *wallet, nonce uint64, sendErr error) {
+	addr := w.signer.Address()
+	if strings.Contains(strings.ToLower(sendErr.Error()), "nonce too low") {
+		if err := w.nonces.sync(ctx, s.client); err != nil {
+			logger.Error("failed to resync executor nonce", err, "wallet", addr.Hex())
+		}
+		return
+	}
+
+	gap, err := w.nonces.release(ctx, nonce)
+	if err != nil {
+		logger.Error("failed to release nonce", err, "wallet", addr.Hex(), "nonce", nonce)
+		return
+	}
+	if gap {
+		s.fillNonceGap(ctx, w, nonce)
+	}
+}
+
+// recordExecution persists a broadcast transaction so the tracker can follow it to a final status.
+func (s *Service) recordExecution(ctx context.Context, from common.Address, tx *types.Transaction, maker *contracts.IMarketplaceOrder, key, makerHash, takerHash string) error {
+	return s.repo.Create(ctx, &Execution{
+		TxHash:         strings.ToLower(tx.Hash().Hex()),
+		Executor:       strings.ToLower(from.Hex()),
+		Nonce:          tx.Nonce(),
+		GasLimit:       tx.Gas(),
+		GasPrice:       tx.GasPrice().String(),
+		GasTipCap:      tipCapString(tx),
+		MakerOrderHash: makerHash,
+		TakerOrderHash: takerHash,
+		ExecutionKey:   key,
+		NFTAddress:     strings.ToLower(maker.Nft.Hex()),
+		TokenID:        maker.TokenId.String(),
+		Status:         ExecutionStatusSubmitted,
+		SubmittedAt:    time.Now(),
+	})
+}
+
+// hashOrder computes the order hash as stored by the order service.
+func (s *Service) hashOrder(order *contracts.IMarketplaceOrder) (string, error) {
+	digest, err := orders.HashOrder(s.typedData, orders.OrderFields{
+		Maker:        order.Maker,
+		NFT:          order.Nft,
+		TokenID:      order.TokenId,
+		PaymentToken: order.PaymentToken,
+		Price:        order.Price,
+		Expiry:       order.Expiry,
+		Nonce:        order.Nonce,
+		Side:         order.Side,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(digest.Hex()), nil
+}
+
+// tipCapString returns the priority fee of a dynamic-fee transaction, or "" for legacy ones.
+func tipCapString(tx *types.Transaction) string {
+	if tx.Type() != types.DynamicFeeTxType {
+		return ""
+	}
+	return tx.GasTipCap().String()
+}
+
+// isHexHash reports whether s is a 0x-prefixed 32-byte hex string.
+func isHexHash(s string) bool {
+	if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	for _, r := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
 }
 
 // convertToContractOrder converts API OrderData to contract IMarketplace.Order struct.
@@ -285,16 +788,6 @@ func (s *Service) convertToContractOrder(order *OrderData) (contracts.IMarketpla
 	}, nil
 }
 
-// getNextNonce returns and increments the pending nonce in a thread-safe manner.
-// This prevents nonce collisions when submitting multiple transactions concurrently.
-func (s *Service) getNextNonce() uint64 {
-	s.nonceMu.Lock()
-	defer s.nonceMu.Unlock()
-	nonce := s.pendingNonce
-	s.pendingNonce++
-	return nonce
-}
-
 // Run starts the execution service HTTP server to receive execution requests.
 func (s *Service) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -302,7 +795,7 @@ func (s *Service) Run() error {
 
 	srv := &http.Server{
 		Addr:              ":" + s.cfg.ExecutionServicePort,
-		Handler:           s.engine,
+		Handler:           s.Handler(),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
@@ -314,6 +807,26 @@ func (s *Service) Run() error {
 		}
 	}()
 
+	if s.tracker != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.tracker.Run(ctx)
+		}()
+	}
+
+	if s.wallets != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.refreshWallets(ctx)
+		}()
+	}
+
+	if s.queue != nil {
+		s.runQueue(ctx)
+	}
+
 	logger.Info("execution service listening", "port", s.cfg.ExecutionServicePort)
 
 	<-ctx.Done()
@@ -323,14 +836,36 @@ func (s *Service) Run() error {
 	return srv.Shutdown(shutdownCtx)
 }
 
+// refreshWallets keeps wallet balances and mined nonces current for scheduling and health.
+func (s *Service) refreshWallets(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.ExecutionWalletRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.wallets.refresh(ctx, s.client)
+		}
+	}
+}
+
 // Shutdown gracefully stops the execution service.
 func (s *Service) Shutdown() {
 	if s.cancel != nil {
 		s.cancel()
 	}
 	s.wg.Wait()
-	if s.client != nil {
-		s.client.Close()
+	if s.wallets != nil {
+		for _, w := range s.wallets.wallets {
+			if remote, ok := w.signer.(*RemoteSigner); ok {
+				remote.Close()
+			}
+		}
+	}
+	if closer, ok := s.client.(interface{ Close() }); ok {
+		closer.Close()
 	}
 	logger.Info("execution service shutdown complete")
 }
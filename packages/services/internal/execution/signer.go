@@ -0,0 +1,252 @@
+package execution
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer signs transactions on behalf of the executor account.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// NewSignersFromConfig builds one signer per configured executor wallet: encrypted keystore
+// files, accounts on a remote signer, or (deprecated, development only) raw hex keys. It
+// returns no signers when none are configured, which puts the service in simulate-only mode.
+func NewSignersFromConfig(ctx context.Context, cfg *config.Config) ([]Signer, error) {
+	var signers []Signer
+	switch {
+	case len(cfg.ExecutorKeystoreFiles) > 0:
+		passwords := cfg.ExecutorKeystorePasswordFiles
+		if len(passwords) != 1 && len(passwords) != len(cfg.ExecutorKeystoreFiles) {
+			return nil, errors.New("EXECUTOR_KEYSTORE_PASSWORD_FILE must list one shared file or one per keystore")
+		}
+		for i, keyFile := range cfg.ExecutorKeystoreFiles {
+			passwordFile := passwords[0]
+			if len(passwords) > 1 {
+				passwordFile = passwords[i]
+			}
+			signer, err := NewKeystoreSigner(strings.TrimSpace(keyFile), strings.TrimSpace(passwordFile))
+			if err != nil {
+				return nil, fmt.Errorf("keystore %s: %w", keyFile, err)
+			}
+			signers = append(signers, signer)
+		}
+	case cfg.ExecutorRemoteSignerURL != "":
+		if len(cfg.ExecutorRemoteSignerAddresses) == 0 {
+			return nil, errors.New("EXECUTOR_REMOTE_SIGNER_ADDRESS must list the signing accounts")
+		}
+		for _, addr := range cfg.ExecutorRemoteSignerAddresses {
+			addr = strings.TrimSpace(addr)
+			if !common.IsHexAddress(addr) {
+				return nil, fmt.Errorf("invalid remote signer address %q", addr)
+			}
+			signer, err := NewRemoteSigner(ctx, cfg.ExecutorRemoteSignerURL, common.HexToAddress(addr), cfg.ExecutorRemoteSignerMethod)
+			if err != nil {
+				return nil, err
+			}
+			signers = append(signers, signer)
+		}
+	case cfg.PrivateKeyHex != "":
+		logger.Warn("EXECUTOR_PRIVATE_KEY is deprecated; use EXECUTOR_KEYSTORE_FILE or EXECUTOR_REMOTE_SIGNER_URL outside development")
+		for _, hexKey := range strings.Split(cfg.PrivateKeyHex, ",") {
+			key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid private key: %w", err)
+			}
+			signers = append(signers, NewKeySigner(key))
+		}
+	}
+	return signers, nil
+}
+
+// TransactOpts builds bind.TransactOpts that sign through signer.
+func TransactOpts(ctx context.Context, signer Signer, chainID *big.Int) *bind.TransactOpts {
+	from := signer.Address()
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignTx(ctx, tx, chainID)
+		},
+		Context: ctx,
+	}
+}
+
+// KeySigner signs with an in-memory private key.
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner wraps a decrypted private key.
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore (V3) file with the passphrase read
+// from passwordFile. Trailing newlines in the password file are ignored.
+func NewKeystoreSigner(keyFile, passwordFile string) (*KeySigner, error) {
+	if passwordFile == "" {
+		return nil, errors.New("EXECUTOR_KEYSTORE_PASSWORD_FILE is required with EXECUTOR_KEYSTORE_FILE")
+	}
+	keyJSON, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore file: %w", err)
+	}
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore password file: %w", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, strings.TrimRight(string(password), "\r\n"))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keystore: %w", err)
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+// Address returns the signing account.
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs tx for chainID with the latest signer rules.
+func (s *KeySigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// RemoteSigner delegates signing to an external signer over JSON-RPC, using clef's
+// account_signTransaction or the standard eth_signTransaction. The key never enters
+// this process.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+	method  string
+}
+
+// NewRemoteSigner connects to the remote signer. method defaults to eth_signTransaction.
+func NewRemoteSigner(ctx context.Context, url string, address common.Address, method string) (*RemoteSigner, error) {
+	if method == "" {
+		method = "eth_signTransaction"
+	}
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("dial remote signer: %w", err)
+	}
+	return &RemoteSigner{client: client, address: address, method: method}, nil
+}
+
+// Address returns the signing account.
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx sends the unsigned transaction to the remote signer and verifies that the
+// returned raw transaction is the same transaction, signed by the expected account.
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := sendTxArgs(s.address, tx, chainID)
+
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, s.method, args); err != nil {
+		return nil, fmt.Errorf("remote signer: %w", err)
+	}
+	raw, err := decodeSignResult(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid transaction: %w", err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid signature: %w", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("remote signer signed with %s, expected %s", sender.Hex(), s.address.Hex())
+	}
+	if !sameTransaction(signed, tx) {
+		return nil, errors.New("remote signer returned a different transaction")
+	}
+	return signed, nil
+}
+
+// Close releases the RPC connection.
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// sendTxArgs converts tx into the argument object accepted by clef and eth_signTransaction.
+func sendTxArgs(from common.Address, tx *types.Transaction, chainID *big.Int) apitypes.SendTxArgs {
+	data := hexutil.Bytes(tx.Data())
+	args := apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(from),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if to := tx.To(); to != nil {
+		mixed := common.NewMixedcaseAddress(*to)
+		args.To = &mixed
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+	return args
+}
+
+// decodeSignResult accepts both {"raw": "0x..", "tx": {...}} (clef, geth) and a bare
+// raw transaction hex string (other eth_signTransaction implementations).
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+	var withRaw struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &withRaw); err == nil && len(withRaw.Raw) > 0 {
+		return withRaw.Raw, nil
+	}
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil && len(raw) > 0 {
+		return raw, nil
+	}
+	return nil, errors.New("remote signer returned no raw transaction")
+}
+
+// sameTransaction compares every signed field except the signature itself.
+func sameTransaction(a, b *types.Transaction) bool {
+	if a.Type() != b.Type() || a.Nonce() != b.Nonce() || a.Gas() != b.Gas() ||
+		a.GasFeeCap().Cmp(b.GasFeeCap()) != 0 || a.GasTipCap().Cmp(b.GasTipCap()) != 0 ||
+		a.Value().Cmp(b.Value()) != 0 || !bytes.Equal(a.Data(), b.Data()) {
+		return false
+	}
+	if a.To() == nil || b.To() == nil {
+		return a.To() == nil && b.To() == nil
+	}
+	return *a.To() == *b.To()
+}
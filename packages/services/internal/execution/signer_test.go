@@ -0,0 +1,141 @@
+package execution
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+)
+
+// standInSigner is a local remote signer speaking eth_signTransaction.
+type standInSigner struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	// tamper makes the signer alter the transaction before signing.
+	tamper bool
+}
+
+type signTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+func (s *standInSigner) SignTransaction(args apitypes.SendTxArgs) (*signTxResult, error) {
+	if s.tamper {
+		args.Nonce++
+	}
+	tx, err := args.ToTransaction()
+	if err != nil {
+		return nil, err
+	}
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(s.chainID), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTxResult{Raw: raw, Tx: signed}, nil
+}
+
+func newStandInRemoteSigner(t *testing.T, backend *standInSigner) *RemoteSigner {
+	t.Helper()
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", backend))
+	httpSrv := httptest.NewServer(server)
+	t.Cleanup(httpSrv.Close)
+	t.Cleanup(server.Stop)
+
+	signer, err := NewRemoteSigner(context.Background(), httpSrv.URL, crypto.PubkeyToAddress(backend.key.PublicKey), "")
+	require.NoError(t, err)
+	t.Cleanup(signer.Close)
+	return signer
+}
+
+func testDynamicTx() *types.Transaction {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(31337),
+		Nonce:     4,
+		GasTipCap: big.NewInt(1_000_000_000),
+		GasFeeCap: big.NewInt(30_000_000_000),
+		Gas:       210_000,
+		To:        &to,
+		Value:     new(big.Int),
+		Data:      []byte{0xde, 0xad, 0xbe, 0xef},
+	})
+}
+
+func TestRemoteSigner_SignsViaRPC(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chainID := big.NewInt(31337)
+	signer := newStandInRemoteSigner(t, &standInSigner{key: key, chainID: chainID})
+
+	unsigned := testDynamicTx()
+	signed, err := signer.SignTx(context.Background(), unsigned, chainID)
+	require.NoError(t, err)
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, signer.Address(), sender)
+	require.True(t, sameTransaction(unsigned, signed))
+
+	// The transactOpts adapter routes bind signing through the same signer.
+	opts := TransactOpts(context.Background(), signer, chainID)
+	viaOpts, err := opts.Signer(signer.Address(), unsigned)
+	require.NoError(t, err)
+	require.Equal(t, signed.Hash(), viaOpts.Hash())
+}
+
+func TestRemoteSigner_RejectsAlteredTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chainID := big.NewInt(31337)
+	signer := newStandInRemoteSigner(t, &standInSigner{key: key, chainID: chainID, tamper: true})
+
+	_, err = signer.SignTx(context.Background(), testDynamicTx(), chainID)
+	require.ErrorContains(t, err, "different transaction")
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	dir := t.TempDir()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(key, "correct horse")
+	require.NoError(t, err)
+
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("correct horse\n"), 0o600))
+
+	signer, err := NewKeystoreSigner(account.URL.Path, passwordFile)
+	require.NoError(t, err)
+	require.Equal(t, account.Address, signer.Address())
+
+	chainID := big.NewInt(31337)
+	signed, err := signer.SignTx(context.Background(), testDynamicTx(), chainID)
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	require.NoError(t, err)
+	require.Equal(t, account.Address, sender)
+
+	wrongPassword := filepath.Join(dir, "wrong")
+	require.NoError(t, os.WriteFile(wrongPassword, []byte("nope"), 0o600))
+	_, err = NewKeystoreSigner(account.URL.Path, wrongPassword)
+	require.ErrorContains(t, err, "decrypt keystore")
+}
@@ -0,0 +1,101 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// SimulationStatus is the status reported for trades handled in simulate-only mode.
+const SimulationStatus = "simulated"
+
+// SimulateTradeResponse is returned by /internal/execute in simulate-only mode: the trade
+// passed validation and eth_call, and this is what submitting it would have cost. Nothing
+// was signed, queued or broadcast.
+type SimulateTradeResponse struct {
+	Simulated    bool   `json:"simulated"`
+	Status       string `json:"status"`
+	ExecutionKey string `json:"executionKey"`
+	From         string `json:"from"`
+	// GasLimit is the estimate plus the configured margin, as a real submission would set it.
+	GasLimit     uint64 `json:"gasLimit"`
+	MaxFeePerGas string `json:"maxFeePerGas"`
+	// MaxCostWei is GasLimit * MaxFeePerGas, the most the trade could cost the executor.
+	MaxCostWei string `json:"maxCostWei"`
+}
+
+// handleSimulateTrade serves /internal/execute when no executor signer is configured.
+// A trade that would revert gets the same 422 response as in execute mode.
+func (s *Service) handleSimulateTrade(c *gin.Context) {
+	var req ExecuteTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	resp, err := s.dryRunTrade(c.Request.Context(), &req)
+	if err != nil {
+		logger.Warn("trade simulation failed",
+			"askMaker", req.MakerOrder.Maker,
+			"bidMaker", req.TakerOrder.Maker,
+			"error", err.Error(),
+		)
+		respondExecuteError(c, err)
+		return
+	}
+
+	logger.Info("trade simulated",
+		"executionKey", resp.ExecutionKey,
+		"from", resp.From,
+		"gasLimit", resp.GasLimit,
+		"maxFeePerGas", resp.MaxFeePerGas,
+	)
+	c.JSON(http.StatusOK, resp)
+}
+
+// dryRunTrade validates the order pair, runs executeTrade through eth_call from the
+// configured simulation address and estimates its gas and fees.
+func (s *Service) dryRunTrade(ctx context.Context, req *ExecuteTradeRequest) (*SimulateTradeResponse, error) {
+	pair, err := s.prepareTrade(req)
+	if err != nil {
+		return nil, err
+	}
+
+	fees, err := s.suggestFees(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取 gas 价格失败: %w", err)
+	}
+	call, err := s.tradeCall(s.simulateFrom, pair.maker, pair.taker, common.FromHex(req.MakerSignature), fees)
+	if err != nil {
+		return nil, err
+	}
+	// The simulation address need not hold ETH, so the call is made without fee fields;
+	// the node would otherwise reject it for insufficient funds before running the trade.
+	call.GasPrice, call.GasFeeCap, call.GasTipCap = nil, nil, nil
+
+	if err := s.simulateTrade(ctx, call); err != nil {
+		return nil, err
+	}
+	estimate, err := s.estimateTradeGas(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	gasLimit := s.gasLimitFor(estimate)
+
+	maxFee := fees.maxPrice()
+	return &SimulateTradeResponse{
+		Simulated:    true,
+		Status:       SimulationStatus,
+		ExecutionKey: pair.key,
+		From:         strings.ToLower(s.simulateFrom.Hex()),
+		GasLimit:     gasLimit,
+		MaxFeePerGas: maxFee.String(),
+		MaxCostWei:   new(big.Int).Mul(maxFee, new(big.Int).SetUint64(gasLimit)).String(),
+	}, nil
+}
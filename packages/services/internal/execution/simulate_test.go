@@ -0,0 +1,86 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimulateOnly_ExecuteReportsOutcomeWithoutBroadcasting serves /internal/execute in
+// simulate-only mode against a simulated chain: a call that succeeds reports its gas, one
+// that reverts gets the typed 422, and no transaction is ever sent.
+func TestSimulateOnly_ExecuteReportsOutcomeWithoutBroadcasting(t *testing.T) {
+	accepting := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	reverting := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		// PUSH1 0 PUSH1 0 REVERT
+		reverting: {Code: []byte{0x60, 0x00, 0x60, 0x00, 0xfd}, Balance: big.NewInt(0)},
+	})
+	defer backend.Close()
+	client := backend.Client()
+
+	newSimulateTestService := func(marketplace common.Address) *Service {
+		gin.SetMode(gin.TestMode)
+		svc := &Service{
+			cfg:             &config.Config{ChainID: 1337, ExecutionGasLimitMarginPercent: 20},
+			client:          client,
+			marketplaceAddr: marketplace,
+			engine:          gin.New(),
+			typedData:       orders.NewTypedData(1337, marketplace),
+			simulateOnly:    true,
+			simulateFrom:    common.HexToAddress("0x00000000000000000000000000000000000000f1"),
+		}
+		svc.registerRoutes()
+		return svc
+	}
+
+	svc := newSimulateTestService(accepting)
+	rec := postExecute(t, svc, testTradeRequest(1))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp SimulateTradeResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.True(t, resp.Simulated)
+	require.Equal(t, SimulationStatus, resp.Status)
+	require.Equal(t, "0x00000000000000000000000000000000000000f1", resp.From)
+	require.NotEmpty(t, resp.ExecutionKey)
+	require.Greater(t, resp.GasLimit, uint64(21000))
+	require.NotEqual(t, "0", resp.MaxCostWei)
+
+	// Job and execution lookups do not exist without a queue.
+	rec = httptest.NewRecorder()
+	svc.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/jobs/1", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	svc.engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/internal/health", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"mode":"simulate"`)
+
+	svc = newSimulateTestService(reverting)
+	rec = postExecute(t, svc, testTradeRequest(2))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	var revert struct {
+		Code string `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &revert))
+	require.Equal(t, "Reverted", revert.Code)
+
+	// Nothing reached the chain.
+	nonce, err := client.PendingNonceAt(context.Background(), svc.simulateFrom)
+	require.NoError(t, err)
+	require.Zero(t, nonce)
+	block, err := client.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, block)
+}
@@ -0,0 +1,314 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// trackerBatchSize bounds how many pending executions are inspected per poll.
+const trackerBatchSize = 100
+
+// ReceiptClient is the subset of the Ethereum client used by the tracker.
+// *ethclient.Client satisfies it; tests substitute a fake.
+type ReceiptClient interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// Replacer re-sends a stuck transaction's nonce, either with bumped fees or as a cancel.
+// FillNonce consumes the nonce of a transaction the node no longer knows about with a
+// zero-value self-transfer, so later transactions from the wallet are not queued behind it.
+type Replacer interface {
+	Replace(ctx context.Context, prev *types.Transaction, cancel bool) (*types.Transaction, error)
+	FillNonce(ctx context.Context, executor common.Address, nonce uint64) (*types.Transaction, error)
+}
+
+// Tracker polls receipts for submitted executions and records their final status.
+//
+// A transaction is considered:
+//   - confirmed / reverted once a receipt exists for the original or any replacement;
+//   - cancelled when the mined receipt belongs to a cancel replacement;
+//   - dropped when the executor's mined nonce has moved past it without a receipt for any
+//     of its transactions, or when the node no longer knows about the latest one after
+//     dropTimeout has elapsed since it was sent.
+//
+// With a Replacer configured, a transaction still pending after stuckTimeout is resubmitted
+// with bumped fees; after maxBumps speed-ups a zero-value self-transfer cancels the nonce.
+// A transaction the node has forgotten is not marked dropped while its nonce is unmined:
+// the nonce is filled with a self-transfer instead, recorded as a cancel replacement.
+type Tracker struct {
+	client      ReceiptClient
+	repo        *Repository
+	interval    time.Duration
+	dropTimeout time.Duration
+
+	replacer     Replacer
+	stuckTimeout time.Duration
+	maxBumps     int
+}
+
+// NewTracker constructs a receipt tracker.
+func NewTracker(client ReceiptClient, repo *Repository, interval, dropTimeout time.Duration) *Tracker {
+	return &Tracker{
+		client:      client,
+		repo:        repo,
+		interval:    interval,
+		dropTimeout: dropTimeout,
+	}
+}
+
+// SetReplacer enables stuck-transaction replacement. A zero stuckTimeout disables it.
+func (t *Tracker) SetReplacer(replacer Replacer, stuckTimeout time.Duration, maxBumps int) {
+	t.replacer = replacer
+	t.stuckTimeout = stuckTimeout
+	t.maxBumps = maxBumps
+}
+
+// Run polls until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Poll(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("execution tracker poll failed", err)
+			}
+		}
+	}
+}
+
+// Poll inspects every submitted execution once.
+func (t *Tracker) Poll(ctx context.Context) error {
+	pending, err := t.repo.ListByStatus(ctx, ExecutionStatusSubmitted, trackerBatchSize)
+	if err != nil {
+		return fmt.Errorf("list pending executions: %w", err)
+	}
+
+	for i := range pending {
+		if err := t.check(ctx, &pending[i]); err != nil {
+			// Transient RPC errors leave the execution pending until the next poll.
+			logger.Warn("failed to check execution status", "txHash", pending[i].TxHash, "error", err)
+		}
+	}
+	return nil
+}
+
+// attempt is one transaction sent for an execution's nonce.
+type attempt struct {
+	hash   common.Hash
+	kind   ReplacementKind // empty for the original transaction
+	sentAt time.Time
+}
+
+// attempts lists the original transaction and its replacements, most recent first.
+func (exec *Execution) attempts() []attempt {
+	result := make([]attempt, 0, len(exec.Replacements)+1)
+	for i := len(exec.Replacements) - 1; i >= 0; i-- {
+		r := exec.Replacements[i]
+		result = append(result, attempt{hash: common.HexToHash(r.TxHash), kind: r.Kind, sentAt: r.CreatedAt})
+	}
+	return append(result, attempt{hash: common.HexToHash(exec.TxHash), sentAt: exec.SubmittedAt})
+}
+
+// check resolves the status of a single execution.
+func (t *Tracker) check(ctx context.Context, exec *Execution) error {
+	attempts := exec.attempts()
+
+	receipt, mined, err := t.findReceipt(ctx, attempts)
+	if err != nil {
+		return err
+	}
+	if receipt != nil {
+		return t.finalizeFromReceipt(ctx, exec, mined, receipt)
+	}
+
+	minedNonce, err := t.client.NonceAt(ctx, common.HexToAddress(exec.Executor), nil)
+	if err != nil {
+		return err
+	}
+	if minedNonce > exec.Nonce {
+		// The nonce was consumed; re-check receipts in case one was mined between calls.
+		receipt, mined, err := t.findReceipt(ctx, attempts)
+		if err != nil {
+			return err
+		}
+		if receipt != nil {
+			return t.finalizeFromReceipt(ctx, exec, mined, receipt)
+		}
+		return t.markDropped(ctx, exec, "nonce consumed by another transaction")
+	}
+
+	latest := attempts[0]
+	age := time.Since(latest.sentAt)
+	replaceDue := t.replacer != nil && t.stuckTimeout > 0 && age >= t.stuckTimeout
+	if !replaceDue && age < t.dropTimeout {
+		return nil
+	}
+
+	tx, _, err := t.client.TransactionByHash(ctx, latest.hash)
+	if errors.Is(err, ethereum.NotFound) {
+		if age < t.dropTimeout {
+			return nil
+		}
+		if t.replacer == nil {
+			return t.markDropped(ctx, exec, "transaction not found after drop timeout")
+		}
+		// The nonce is still unmined; leaving it empty would block every later transaction.
+		return t.fillNonce(ctx, exec)
+	}
+	if err != nil {
+		return err
+	}
+	if replaceDue {
+		return t.replace(ctx, exec, tx)
+	}
+	return nil
+}
+
+// findReceipt returns the first receipt found among the attempts.
+func (t *Tracker) findReceipt(ctx context.Context, attempts []attempt) (*types.Receipt, attempt, error) {
+	for _, a := range attempts {
+		receipt, err := t.client.TransactionReceipt(ctx, a.hash)
+		if err == nil {
+			return receipt, a, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, attempt{}, err
+		}
+	}
+	return nil, attempt{}, nil
+}
+
+// replace sends the next replacement for a stuck execution: a speed-up while bumps remain,
+// then a single cancel. A stuck cancel is left for the operator.
+func (t *Tracker) replace(ctx context.Context, exec *Execution, prev *types.Transaction) error {
+	bumps := 0
+	for _, r := range exec.Replacements {
+		switch r.Kind {
+		case ReplacementSpeedUp:
+			bumps++
+		case ReplacementCancel:
+			logger.Warn("cancel transaction still pending, manual intervention required",
+				"txHash", exec.TxHash,
+				"cancelTxHash", r.TxHash,
+				"nonce", exec.Nonce,
+			)
+			return nil
+		}
+	}
+
+	kind := ReplacementSpeedUp
+	if bumps >= t.maxBumps {
+		kind = ReplacementCancel
+	}
+
+	tx, err := t.replacer.Replace(ctx, prev, kind == ReplacementCancel)
+	if err != nil {
+		return fmt.Errorf("send %s replacement: %w", kind, err)
+	}
+
+	if err := t.repo.AddReplacement(ctx, &ExecutionReplacement{
+		ExecutionID: exec.ID,
+		TxHash:      tx.Hash().Hex(),
+		Kind:        kind,
+		GasPrice:    tx.GasPrice().String(),
+		GasTipCap:   tipCapString(tx),
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		return fmt.Errorf("record %s replacement: %w", kind, err)
+	}
+
+	logger.Warn("replaced stuck trade transaction",
+		"txHash", exec.TxHash,
+		"replacementTxHash", tx.Hash().Hex(),
+		"kind", kind,
+		"nonce", exec.Nonce,
+		"maxFeePerGas", tx.GasPrice().String(),
+	)
+	return nil
+}
+
+func (t *Tracker) finalizeFromReceipt(ctx context.Context, exec *Execution, mined attempt, receipt *types.Receipt) error {
+	state := FinalState{
+		Status:  ExecutionStatusConfirmed,
+		TxHash:  mined.hash.Hex(),
+		GasUsed: &receipt.GasUsed,
+	}
+	switch {
+	case mined.kind == ReplacementCancel:
+		state.Status = ExecutionStatusCancelled
+		state.Error = "cancelled after gas bumps were exhausted"
+	case receipt.Status != types.ReceiptStatusSuccessful:
+		state.Status = ExecutionStatusReverted
+		state.Error = "transaction reverted"
+	}
+	if receipt.BlockNumber != nil {
+		bn := receipt.BlockNumber.Uint64()
+		state.BlockNumber = &bn
+	}
+
+	if err := t.repo.MarkFinal(ctx, exec.ID, state); err != nil {
+		return err
+	}
+
+	switch state.Status {
+	case ExecutionStatusConfirmed:
+		logger.Info("trade transaction confirmed", "txHash", state.TxHash, "block", receipt.BlockNumber)
+	default:
+		logger.Warn("trade transaction "+string(state.Status),
+			"txHash", exec.TxHash,
+			"finalTxHash", state.TxHash,
+			"makerOrderHash", exec.MakerOrderHash,
+			"takerOrderHash", exec.TakerOrderHash,
+		)
+	}
+	return nil
+}
+
+// fillNonce consumes a forgotten transaction's nonce with a self-transfer. The execution stays
+// submitted and resolves as cancelled once the self-transfer is mined.
+func (t *Tracker) fillNonce(ctx context.Context, exec *Execution) error {
+	tx, err := t.replacer.FillNonce(ctx, common.HexToAddress(exec.Executor), exec.Nonce)
+	if err != nil {
+		return fmt.Errorf("fill nonce of dropped transaction: %w", err)
+	}
+
+	if err := t.repo.AddReplacement(ctx, &ExecutionReplacement{
+		ExecutionID: exec.ID,
+		TxHash:      tx.Hash().Hex(),
+		Kind:        ReplacementCancel,
+		GasPrice:    tx.GasPrice().String(),
+		GasTipCap:   tipCapString(tx),
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		return fmt.Errorf("record nonce fill: %w", err)
+	}
+
+	logger.Warn("trade transaction dropped by the node, filled its nonce with self-transfer",
+		"txHash", exec.TxHash,
+		"fillTxHash", tx.Hash().Hex(),
+		"nonce", exec.Nonce,
+	)
+	return nil
+}
+
+func (t *Tracker) markDropped(ctx context.Context, exec *Execution, reason string) error {
+	if err := t.repo.MarkFinal(ctx, exec.ID, FinalState{Status: ExecutionStatusDropped, Error: reason}); err != nil {
+		return err
+	}
+	logger.Warn("trade transaction dropped", "txHash", exec.TxHash, "nonce", exec.Nonce, "reason", reason)
+	return nil
+}
@@ -0,0 +1,266 @@
+package execution
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeReceiptClient serves receipts, known transactions and mined nonces from maps.
+type fakeReceiptClient struct {
+	receipts map[common.Hash]*types.Receipt
+	known    map[common.Hash]bool
+	nonces   map[common.Address]uint64
+}
+
+func (f *fakeReceiptClient) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	if r, ok := f.receipts[hash]; ok {
+		return r, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (f *fakeReceiptClient) TransactionByHash(_ context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if f.known[hash] {
+		return types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(100), Gas: 500000}), true, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+func (f *fakeReceiptClient) NonceAt(_ context.Context, account common.Address, _ *big.Int) (uint64, error) {
+	return f.nonces[account], nil
+}
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Execution{}, &ExecutionReplacement{}, &ExecutorNonce{}, &ExecutorNonceGap{}, &ExecutionJob{}, &EconomicsDecision{}))
+	// Shared-cache SQLite rejects concurrent writers with "table is locked"; serialise them.
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return NewRepository(db)
+}
+
+// TestTracker_ResolvesFinalStatus covers confirmed, reverted, replaced and vanished transactions.
+func TestTracker_ResolvesFinalStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	executor := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+
+	hashes := map[string]common.Hash{
+		"confirmed": common.HexToHash("0x01"),
+		"reverted":  common.HexToHash("0x02"),
+		"replaced":  common.HexToHash("0x03"),
+		"vanished":  common.HexToHash("0x04"),
+		"pending":   common.HexToHash("0x05"),
+	}
+	nonces := map[string]uint64{"confirmed": 10, "reverted": 11, "replaced": 12, "vanished": 13, "pending": 14}
+	submittedAt := map[string]time.Time{"vanished": time.Now().Add(-time.Hour)}
+
+	for name, hash := range hashes {
+		at, ok := submittedAt[name]
+		if !ok {
+			at = time.Now()
+		}
+		require.NoError(t, repo.Create(ctx, &Execution{
+			TxHash:      hash.Hex(),
+			Executor:    executor.Hex(),
+			Nonce:       nonces[name],
+			GasPrice:    "1",
+			Status:      ExecutionStatusSubmitted,
+			SubmittedAt: at,
+		}))
+	}
+
+	client := &fakeReceiptClient{
+		receipts: map[common.Hash]*types.Receipt{
+			hashes["confirmed"]: {Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(100), GasUsed: 21000},
+			hashes["reverted"]:  {Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(101), GasUsed: 30000},
+		},
+		known: map[common.Hash]bool{hashes["pending"]: true},
+		// Nonce 12 has been mined by a different transaction.
+		nonces: map[common.Address]uint64{executor: 13},
+	}
+
+	tracker := NewTracker(client, repo, time.Second, 10*time.Minute)
+	require.NoError(t, tracker.Poll(ctx))
+
+	expect := map[string]ExecutionStatus{
+		"confirmed": ExecutionStatusConfirmed,
+		"reverted":  ExecutionStatusReverted,
+		"replaced":  ExecutionStatusDropped,
+		"vanished":  ExecutionStatusDropped,
+		"pending":   ExecutionStatusSubmitted,
+	}
+	for name, status := range expect {
+		exec, err := repo.FindByTxHash(ctx, hashes[name].Hex())
+		require.NoError(t, err)
+		require.Equal(t, status, exec.Status, name)
+	}
+
+	confirmed, err := repo.FindByTxHash(ctx, hashes["confirmed"].Hex())
+	require.NoError(t, err)
+	require.NotNil(t, confirmed.BlockNumber)
+	require.EqualValues(t, 100, *confirmed.BlockNumber)
+	require.EqualValues(t, 21000, *confirmed.GasUsed)
+	require.NotNil(t, confirmed.FinalizedAt)
+}
+
+// fakeReplacer returns distinct unsigned transactions and records what was requested.
+type fakeReplacer struct {
+	calls []bool
+	fills []uint64
+}
+
+func (f *fakeReplacer) FillNonce(_ context.Context, _ common.Address, nonce uint64) (*types.Transaction, error) {
+	f.fills = append(f.fills, nonce)
+	return types.NewTx(&types.LegacyTx{Nonce: nonce, GasPrice: big.NewInt(100), Gas: 21000}), nil
+}
+
+func (f *fakeReplacer) Replace(_ context.Context, prev *types.Transaction, cancel bool) (*types.Transaction, error) {
+	f.calls = append(f.calls, cancel)
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    prev.Nonce(),
+		GasPrice: bumpGasPrice(prev.GasPrice(), 15),
+		Gas:      uint64(len(f.calls)),
+	}), nil
+}
+
+// TestTracker_ReplacesStuckTransaction bumps a stuck transaction, then cancels it and
+// records the cancel as the final outcome.
+func TestTracker_ReplacesStuckTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	executor := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+	original := common.HexToHash("0x0a")
+
+	require.NoError(t, repo.Create(ctx, &Execution{
+		TxHash:      original.Hex(),
+		Executor:    executor.Hex(),
+		Nonce:       7,
+		GasPrice:    "100",
+		Status:      ExecutionStatusSubmitted,
+		SubmittedAt: time.Now().Add(-5 * time.Minute),
+	}))
+
+	client := &fakeReceiptClient{
+		receipts: map[common.Hash]*types.Receipt{},
+		known:    map[common.Hash]bool{original: true},
+		nonces:   map[common.Address]uint64{executor: 7},
+	}
+	replacer := &fakeReplacer{}
+	tracker := NewTracker(client, repo, time.Second, time.Hour)
+	tracker.SetReplacer(replacer, time.Minute, 1)
+
+	// ageReplacements makes the latest replacement look stuck as well.
+	ageReplacements := func() {
+		require.NoError(t, repo.db.Model(&ExecutionReplacement{}).Where("1 = 1").
+			Update("created_at", time.Now().Add(-5*time.Minute)).Error)
+	}
+	markKnown := func() {
+		exec, err := repo.FindByTxHash(ctx, original.Hex())
+		require.NoError(t, err)
+		for _, r := range exec.Replacements {
+			client.known[common.HexToHash(r.TxHash)] = true
+		}
+	}
+
+	require.NoError(t, tracker.Poll(ctx))
+	require.Equal(t, []bool{false}, replacer.calls)
+
+	// The fresh speed-up is not yet stuck.
+	markKnown()
+	require.NoError(t, tracker.Poll(ctx))
+	require.Len(t, replacer.calls, 1)
+
+	ageReplacements()
+	require.NoError(t, tracker.Poll(ctx))
+	require.Equal(t, []bool{false, true}, replacer.calls)
+
+	exec, err := repo.FindByTxHash(ctx, original.Hex())
+	require.NoError(t, err)
+	require.Len(t, exec.Replacements, 2)
+	require.Equal(t, ReplacementSpeedUp, exec.Replacements[0].Kind)
+	require.Equal(t, ReplacementCancel, exec.Replacements[1].Kind)
+
+	// Lookup by a replacement hash resolves to the original execution.
+	cancelHash := common.HexToHash(exec.Replacements[1].TxHash)
+	byReplacement, err := repo.FindByTxHash(ctx, cancelHash.Hex())
+	require.NoError(t, err)
+	require.Equal(t, exec.ID, byReplacement.ID)
+
+	client.receipts[cancelHash] = &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(50), GasUsed: 21000}
+	client.nonces[executor] = 8
+	require.NoError(t, tracker.Poll(ctx))
+
+	exec, err = repo.FindByTxHash(ctx, original.Hex())
+	require.NoError(t, err)
+	require.Equal(t, ExecutionStatusCancelled, exec.Status)
+	require.Equal(t, cancelHash.Hex(), exec.FinalTxHash)
+}
+
+func TestBumpGasPrice(t *testing.T) {
+	require.Equal(t, "115", bumpGasPrice(big.NewInt(100), 15).String())
+	// Bumps below the node replacement threshold are raised to 10%, rounding up.
+	require.Equal(t, "112", bumpGasPrice(big.NewInt(101), 5).String())
+}
+
+// TestTracker_FillsNonceOfForgottenTransaction fills the unmined nonce of a transaction the node
+// no longer knows about instead of leaving a gap, and resolves the execution as cancelled.
+func TestTracker_FillsNonceOfForgottenTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	executor := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+	original := common.HexToHash("0x0b")
+
+	require.NoError(t, repo.Create(ctx, &Execution{
+		TxHash:      original.Hex(),
+		Executor:    executor.Hex(),
+		Nonce:       7,
+		GasPrice:    "100",
+		Status:      ExecutionStatusSubmitted,
+		SubmittedAt: time.Now().Add(-time.Hour),
+	}))
+
+	client := &fakeReceiptClient{
+		receipts: map[common.Hash]*types.Receipt{},
+		known:    map[common.Hash]bool{},
+		nonces:   map[common.Address]uint64{executor: 7},
+	}
+	replacer := &fakeReplacer{}
+	tracker := NewTracker(client, repo, time.Second, 10*time.Minute)
+	tracker.SetReplacer(replacer, 0, 1)
+
+	require.NoError(t, tracker.Poll(ctx))
+	require.Equal(t, []uint64{7}, replacer.fills)
+
+	exec, err := repo.FindByTxHash(ctx, original.Hex())
+	require.NoError(t, err)
+	require.Equal(t, ExecutionStatusSubmitted, exec.Status)
+	require.Len(t, exec.Replacements, 1)
+	require.Equal(t, ReplacementCancel, exec.Replacements[0].Kind)
+
+	// The fresh self-transfer is not refilled while it waits to be mined.
+	require.NoError(t, tracker.Poll(ctx))
+	require.Len(t, replacer.fills, 1)
+
+	fillHash := common.HexToHash(exec.Replacements[0].TxHash)
+	client.receipts[fillHash] = &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(60), GasUsed: 21000}
+	client.nonces[executor] = 8
+	require.NoError(t, tracker.Poll(ctx))
+
+	exec, err = repo.FindByTxHash(ctx, original.Hex())
+	require.NoError(t, err)
+	require.Equal(t, ExecutionStatusCancelled, exec.Status)
+	require.Equal(t, fillHash.Hex(), exec.FinalTxHash)
+}
@@ -0,0 +1,350 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrNoHealthyWallet = errors.New("no healthy executor wallet available")
+
+// WalletStateClient is the subset of the Ethereum client used to refresh wallet state.
+type WalletStateClient interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// wallet is one executor account in the pool with its own nonce sequence.
+type wallet struct {
+	signer Signer
+	nonces nonceManager
+
+	mu            sync.Mutex
+	inflight      int
+	balance       *big.Int
+	minedNonce    uint64
+	refreshedAt   time.Time
+	failures      int
+	cooldownUntil time.Time
+	lastError     string
+}
+
+// WalletStatus is the per-wallet view exposed on /internal/health.
+type WalletStatus struct {
+	Address       string     `json:"address"`
+	Healthy       bool       `json:"healthy"`
+	Balance       string     `json:"balance"`
+	NextNonce     uint64     `json:"nextNonce"`
+	MinedNonce    uint64     `json:"minedNonce"`
+	Inflight      int        `json:"inflight"`
+	Failures      int        `json:"failures"`
+	CooldownUntil *time.Time `json:"cooldownUntil,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	RefreshedAt   time.Time  `json:"refreshedAt"`
+}
+
+// PendingExecutorLookup returns the executor address of a still-pending execution for the
+// given NFT token, or "" if there is none.
+type PendingExecutorLookup func(ctx context.Context, nft, tokenID string) (string, error)
+
+// walletPool schedules trades across executor wallets.
+//
+// Trades for the same NFT token stay on one wallet while an earlier trade for it is being
+// submitted or is still pending on-chain, so their nonces order them. Other trades go to the
+// healthy wallet with the lowest load (in-flight submissions plus unmined nonces).
+type walletPool struct {
+	wallets     []*wallet
+	byAddress   map[common.Address]*wallet
+	minBalance  *big.Int
+	maxFailures int
+	cooldown    time.Duration
+	pendingFor  PendingExecutorLookup
+
+	mu       sync.Mutex
+	affinity map[string]*affinityEntry
+	now      func() time.Time
+}
+
+type affinityEntry struct {
+	wallet *wallet
+	refs   int
+}
+
+func newWalletPool(signers []Signer, nonces *NonceStore, minBalance *big.Int, maxFailures int, cooldown time.Duration, pendingFor PendingExecutorLookup) *walletPool {
+	pool := &walletPool{
+		byAddress:   make(map[common.Address]*wallet, len(signers)),
+		minBalance:  minBalance,
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+		pendingFor:  pendingFor,
+		affinity:    make(map[string]*affinityEntry),
+		now:         time.Now,
+	}
+	for _, signer := range signers {
+		w := &wallet{signer: signer, nonces: nonceManager{store: nonces, address: signer.Address()}}
+		pool.wallets = append(pool.wallets, w)
+		pool.byAddress[signer.Address()] = w
+	}
+	return pool
+}
+
+// nftKey identifies an NFT token for wallet affinity.
+func nftKey(nft, tokenID string) string {
+	return strings.ToLower(nft) + ":" + tokenID
+}
+
+// acquire selects a wallet for a trade on the given NFT token. The returned release
+// function must be called once the trade has been submitted (or has failed).
+func (p *walletPool) acquire(ctx context.Context, nft, tokenID string) (*wallet, func(), error) {
+	key := nftKey(nft, tokenID)
+
+	// The pending-execution lookup is a database query, so it runs without the pool lock and
+	// only when no in-process trade already holds the token.
+	p.mu.Lock()
+	_, held := p.affinity[key]
+	p.mu.Unlock()
+	var pending *wallet
+	if !held {
+		var err error
+		if pending, err = p.pendingWallet(ctx, nft, tokenID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Re-check under the lock: a concurrent acquire may have claimed the token meanwhile.
+	w := p.affine(key, pending)
+	if w == nil {
+		w = p.leastLoaded()
+	}
+	if w == nil {
+		return nil, nil, ErrNoHealthyWallet
+	}
+
+	entry := p.affinity[key]
+	if entry == nil {
+		entry = &affinityEntry{wallet: w}
+		p.affinity[key] = entry
+	}
+	entry.refs++
+	w.mu.Lock()
+	w.inflight++
+	w.mu.Unlock()
+
+	release := func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if entry.refs--; entry.refs == 0 {
+			delete(p.affinity, key)
+		}
+		w.mu.Lock()
+		w.inflight--
+		w.mu.Unlock()
+	}
+	return w, release, nil
+}
+
+// pendingWallet returns the wallet of the latest still-pending execution for the NFT token,
+// or nil when there is none.
+func (p *walletPool) pendingWallet(ctx context.Context, nft, tokenID string) (*wallet, error) {
+	if p.pendingFor == nil {
+		return nil, nil
+	}
+	executor, err := p.pendingFor(ctx, strings.ToLower(nft), tokenID)
+	if err != nil || executor == "" {
+		return nil, err
+	}
+	return p.byAddress[common.HexToAddress(executor)], nil
+}
+
+// affine returns the wallet already handling the NFT token in this process, falling back to
+// the wallet of its pending execution. Affinity holds even when that wallet is unhealthy:
+// moving the trade would race the pending transaction. The caller holds p.mu.
+func (p *walletPool) affine(key string, pending *wallet) *wallet {
+	if entry, ok := p.affinity[key]; ok {
+		return entry.wallet
+	}
+	return pending
+}
+
+// leastLoaded picks the healthy wallet with the lowest load; ties keep configuration order.
+func (p *walletPool) leastLoaded() *wallet {
+	now := p.now()
+	var best *wallet
+	bestLoad := uint64(0)
+	for _, w := range p.wallets {
+		if !p.healthy(w, now) {
+			continue
+		}
+		load := w.load()
+		if best == nil || load < bestLoad {
+			best, bestLoad = w, load
+		}
+	}
+	return best
+}
+
+// healthy reports whether w may receive new trades.
+func (p *walletPool) healthy(w *wallet, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Before(w.cooldownUntil) {
+		return false
+	}
+	if p.minBalance != nil && w.balance != nil && w.balance.Cmp(p.minBalance) < 0 {
+		return false
+	}
+	return true
+}
+
+// load counts in-flight submissions plus transactions sent but not yet mined.
+func (w *wallet) load() uint64 {
+	next := w.nonces.peek()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	load := uint64(w.inflight)
+	if next > w.minedNonce {
+		load += next - w.minedNonce
+	}
+	return load
+}
+
+// recordResult updates the failure streak after a submission attempt.
+func (p *walletPool) recordResult(w *wallet, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil {
+		w.failures = 0
+		w.lastError = ""
+		return
+	}
+	w.failures++
+	w.lastError = err.Error()
+	if p.maxFailures > 0 && w.failures >= p.maxFailures {
+		w.cooldownUntil = p.now().Add(p.cooldown)
+		w.failures = 0
+		logger.Warn("executor wallet cooling down after repeated failures",
+			"wallet", w.signer.Address().Hex(),
+			"until", w.cooldownUntil,
+			"lastError", w.lastError,
+		)
+	}
+}
+
+// syncNonces resynchronises every wallet's persisted nonce sequence from the chain on startup,
+// reclaiming reservations lost before broadcast.
+func (p *walletPool) syncNonces(ctx context.Context, source PendingNonceSource) error {
+	for _, w := range p.wallets {
+		if err := w.nonces.recover(ctx, source); err != nil {
+			return fmt.Errorf("sync nonce for %s: %w", w.signer.Address().Hex(), err)
+		}
+	}
+	return nil
+}
+
+// refresh updates balances and mined nonces for every wallet.
+func (p *walletPool) refresh(ctx context.Context, client WalletStateClient) {
+	for _, w := range p.wallets {
+		addr := w.signer.Address()
+		balance, err := client.BalanceAt(ctx, addr, nil)
+		if err != nil {
+			logger.Warn("failed to refresh executor wallet balance", "wallet", addr.Hex(), "error", err)
+			continue
+		}
+		mined, err := client.NonceAt(ctx, addr, nil)
+		if err != nil {
+			logger.Warn("failed to refresh executor wallet nonce", "wallet", addr.Hex(), "error", err)
+			continue
+		}
+
+		w.mu.Lock()
+		w.balance = balance
+		w.minedNonce = mined
+		w.refreshedAt = p.now()
+		w.mu.Unlock()
+
+		if p.minBalance != nil && balance.Cmp(p.minBalance) < 0 {
+			logger.Warn("executor wallet balance below minimum", "wallet", addr.Hex(), "balance", balance.String())
+		}
+	}
+}
+
+// funded reports whether at least one wallet holds the minimum balance. Wallets whose
+// balance has not been read yet count as funded.
+func (p *walletPool) funded() bool {
+	for _, w := range p.wallets {
+		w.mu.Lock()
+		ok := p.minBalance == nil || w.balance == nil || w.balance.Cmp(p.minBalance) >= 0
+		w.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// caller returns the address trades are simulated from before they are queued. Any executor
+// wallet is accepted by the marketplace, so the first one is used.
+func (p *walletPool) caller() common.Address {
+	return p.wallets[0].signer.Address()
+}
+
+// wallet returns the pool wallet for addr.
+func (p *walletPool) wallet(addr common.Address) (*wallet, bool) {
+	w, ok := p.byAddress[addr]
+	return w, ok
+}
+
+// statuses reports every wallet, sorted by address for stable output.
+func (p *walletPool) statuses() []WalletStatus {
+	now := p.now()
+	result := make([]WalletStatus, 0, len(p.wallets))
+	for _, w := range p.wallets {
+		healthy := p.healthy(w, now)
+		next := w.nonces.peek()
+
+		w.mu.Lock()
+		status := WalletStatus{
+			Address:     w.signer.Address().Hex(),
+			Healthy:     healthy,
+			Balance:     "unknown",
+			NextNonce:   next,
+			MinedNonce:  w.minedNonce,
+			Inflight:    w.inflight,
+			Failures:    w.failures,
+			LastError:   w.lastError,
+			RefreshedAt: w.refreshedAt,
+		}
+		if w.balance != nil {
+			status.Balance = w.balance.String()
+		}
+		if now.Before(w.cooldownUntil) {
+			until := w.cooldownUntil
+			status.CooldownUntil = &until
+		}
+		w.mu.Unlock()
+
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result
+}
+
+// ethToWei converts an ETH amount to wei; zero or negative means no minimum.
+func ethToWei(eth float64) *big.Int {
+	if eth <= 0 {
+		return nil
+	}
+	wei, _ := new(big.Float).Mul(big.NewFloat(eth), big.NewFloat(1e18)).Int(nil)
+	return wei
+}
@@ -0,0 +1,181 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWalletState serves fixed balances and mined nonces.
+type fakeWalletState struct {
+	balances map[common.Address]*big.Int
+	nonces   map[common.Address]uint64
+}
+
+func (f *fakeWalletState) BalanceAt(_ context.Context, account common.Address, _ *big.Int) (*big.Int, error) {
+	return f.balances[account], nil
+}
+
+func (f *fakeWalletState) NonceAt(_ context.Context, account common.Address, _ *big.Int) (uint64, error) {
+	return f.nonces[account], nil
+}
+
+func (f *fakeWalletState) PendingNonceAt(_ context.Context, account common.Address) (uint64, error) {
+	return f.nonces[account], nil
+}
+
+// newTestPool builds a pool over a fresh nonce store with every wallet synced to nonce 0.
+func newTestPool(t *testing.T, signers []Signer, minBalance *big.Int, maxFailures int, pendingFor PendingExecutorLookup) *walletPool {
+	t.Helper()
+	pool := newWalletPool(signers, NewNonceStore(newTestRepository(t).db), minBalance, maxFailures, time.Minute, pendingFor)
+	require.NoError(t, pool.syncNonces(context.Background(), &fakeWalletState{nonces: map[common.Address]uint64{}}))
+	return pool
+}
+
+func newTestSigners(t *testing.T, n int) []Signer {
+	t.Helper()
+	signers := make([]Signer, n)
+	for i := range signers {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		signers[i] = NewKeySigner(key)
+	}
+	return signers
+}
+
+func TestWalletPool_SchedulesByLoadAndAffinity(t *testing.T) {
+	ctx := context.Background()
+	signers := newTestSigners(t, 2)
+	pool := newTestPool(t, signers, nil, 3, nil)
+	a, b := pool.wallets[0], pool.wallets[1]
+
+	w1, release1, err := pool.acquire(ctx, "0xNFT", "1")
+	require.NoError(t, err)
+	require.Same(t, a, w1)
+
+	// Another token goes to the idle wallet.
+	w2, release2, err := pool.acquire(ctx, "0xnft", "2")
+	require.NoError(t, err)
+	require.Same(t, b, w2)
+
+	// The same token (address case-insensitive) stays on its wallet even though both are busy.
+	w3, release3, err := pool.acquire(ctx, "0xnft", "1")
+	require.NoError(t, err)
+	require.Same(t, a, w3)
+
+	release1()
+	release2()
+	release3()
+	require.Empty(t, pool.affinity)
+
+	// Unmined nonces count as load: wallet a has two transactions outstanding.
+	_, err = a.nonces.reserve(ctx)
+	require.NoError(t, err)
+	_, err = a.nonces.reserve(ctx)
+	require.NoError(t, err)
+
+	w, release, err := pool.acquire(ctx, "0xnft", "3")
+	require.NoError(t, err)
+	require.Same(t, b, w)
+	release()
+}
+
+func TestWalletPool_AffinityFollowsPendingExecution(t *testing.T) {
+	ctx := context.Background()
+	signers := newTestSigners(t, 2)
+	pending := signers[1].Address().Hex()
+	pool := newTestPool(t, signers, nil, 3, func(_ context.Context, nft, tokenID string) (string, error) {
+		if nft == "0xnft" && tokenID == "7" {
+			return pending, nil
+		}
+		return "", nil
+	})
+
+	w, release, err := pool.acquire(ctx, "0xNFT", "7")
+	require.NoError(t, err)
+	require.Same(t, pool.wallets[1], w)
+	release()
+}
+
+// TestWalletPool_PendingLookupRunsUnlocked checks the pending-execution query runs without the
+// pool lock, and that a token claimed by a concurrent acquire during the query keeps its wallet.
+func TestWalletPool_PendingLookupRunsUnlocked(t *testing.T) {
+	ctx := context.Background()
+	signers := newTestSigners(t, 2)
+	var pool *walletPool
+	var inner func()
+	lookups := 0
+	pool = newTestPool(t, signers, nil, 3, func(_ context.Context, _, _ string) (string, error) {
+		lookups++
+		if lookups > 1 {
+			return "", nil
+		}
+		require.True(t, pool.mu.TryLock(), "lookup must not hold the pool lock")
+		pool.mu.Unlock()
+		// Another request claims the token while this lookup is running.
+		w, release, err := pool.acquire(ctx, "0xnft", "7")
+		require.NoError(t, err)
+		require.Same(t, pool.wallets[0], w)
+		inner = release
+		return signers[1].Address().Hex(), nil
+	})
+
+	w, release, err := pool.acquire(ctx, "0xnft", "7")
+	require.NoError(t, err)
+	require.Same(t, pool.wallets[0], w, "the in-process claim wins over the looked-up wallet")
+	require.Equal(t, 2, lookups)
+
+	// While the token is held the lookup is skipped.
+	w, release2, err := pool.acquire(ctx, "0xnft", "7")
+	require.NoError(t, err)
+	require.Same(t, pool.wallets[0], w)
+	require.Equal(t, 2, lookups)
+
+	inner()
+	release()
+	release2()
+	require.Empty(t, pool.affinity)
+}
+
+func TestWalletPool_Health(t *testing.T) {
+	ctx := context.Background()
+	signers := newTestSigners(t, 2)
+	pool := newTestPool(t, signers, big.NewInt(1000), 2, nil)
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+	a, b := pool.wallets[0], pool.wallets[1]
+
+	// Wallet a is below the minimum balance.
+	pool.refresh(ctx, &fakeWalletState{
+		balances: map[common.Address]*big.Int{a.signer.Address(): big.NewInt(10), b.signer.Address(): big.NewInt(5000)},
+		nonces:   map[common.Address]uint64{},
+	})
+	w, release, err := pool.acquire(ctx, "0xnft", "1")
+	require.NoError(t, err)
+	require.Same(t, b, w)
+	release()
+
+	// Repeated failures put wallet b into cooldown; nothing healthy remains.
+	pool.recordResult(b, errors.New("boom"))
+	pool.recordResult(b, errors.New("boom"))
+	_, _, err = pool.acquire(ctx, "0xnft", "1")
+	require.ErrorIs(t, err, ErrNoHealthyWallet)
+
+	statuses := pool.statuses()
+	require.Len(t, statuses, 2)
+	for _, st := range statuses {
+		require.False(t, st.Healthy)
+	}
+
+	now = now.Add(2 * time.Minute)
+	w, release, err = pool.acquire(ctx, "0xnft", "1")
+	require.NoError(t, err)
+	require.Same(t, b, w)
+	release()
+}
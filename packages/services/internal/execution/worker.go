@@ -0,0 +1,170 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+)
+
+// runQueue starts the worker pool and the stale-job janitor. It returns immediately; the
+// goroutines stop when ctx is cancelled and are tracked by s.wg.
+func (s *Service) runQueue(ctx context.Context) {
+	workers := s.cfg.ExecutionQueueWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	if s.cfg.ExecutionQueueStaleTimeout > 0 {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.requeueStaleJobs(ctx)
+		}()
+	}
+
+	logger.Info("execution queue started", "workers", workers, "capacity", s.cfg.ExecutionQueueCapacity)
+}
+
+// work claims and processes jobs until ctx is cancelled. When the queue is empty it sleeps
+// for the poll interval or until an enqueue on this instance wakes it.
+// While a circuit breaker is tripped jobs stay queued instead of being attempted.
+func (s *Service) work(ctx context.Context) {
+	for {
+		var job *ExecutionJob
+		var err error
+		if s.checkBreakers(ctx) == nil {
+			job, err = s.queue.Claim(ctx)
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Error("failed to claim execution job", err)
+		}
+		if job != nil {
+			s.processJob(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.jobReady:
+		case <-time.After(s.cfg.ExecutionQueuePollInterval):
+		}
+	}
+}
+
+// processJob runs one attempt of a queued execute request. Permanent failures and the last
+// allowed attempt fail the job; other errors put it back in the queue after the retry delay.
+func (s *Service) processJob(ctx context.Context, job *ExecutionJob) {
+	var req ExecuteTradeRequest
+	if err := json.Unmarshal([]byte(job.Request), &req); err != nil {
+		s.finishJob(job, "InvalidRequest", true, err)
+		return
+	}
+
+	resp, err := s.executeTrade(ctx, &req)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Shutting down: hand the job back immediately rather than waiting for the janitor.
+			if err := s.queue.Retry(context.Background(), job.ID, "", err, 0); err != nil {
+				logger.Error("failed to requeue execution job", err, "executionId", job.ID)
+			}
+			return
+		}
+		var unprofitable *UnprofitableError
+		if errors.As(err, &unprofitable) && unprofitable.Decision == ProfitDecisionDefer {
+			s.deferJob(job, err)
+			return
+		}
+		code, permanent := classifyExecuteError(err)
+		logger.Error("execution job attempt failed", err,
+			"executionId", job.ID,
+			"attempt", job.Attempts,
+			"code", code,
+			"permanent", permanent,
+		)
+		s.finishJob(job, code, permanent, err)
+		return
+	}
+
+	if err := s.queue.Complete(context.Background(), job.ID, resp.TxHash); err != nil {
+		logger.Error("failed to complete execution job", err, "executionId", job.ID, "txHash", resp.TxHash)
+	}
+}
+
+// finishJob records a failed attempt as either a retry or a terminal failure. It uses a
+// fresh context so the outcome is saved even while the service is shutting down.
+func (s *Service) finishJob(job *ExecutionJob, code string, permanent bool, cause error) {
+	ctx := context.Background()
+	var err error
+	if permanent || job.Attempts >= s.cfg.ExecutionQueueMaxAttempts {
+		err = s.queue.Fail(ctx, job.ID, code, permanent, cause)
+	} else {
+		err = s.queue.Retry(ctx, job.ID, code, cause, s.cfg.ExecutionQueueRetryDelay)
+	}
+	if err != nil {
+		logger.Error("failed to update execution job", err, "executionId", job.ID)
+	}
+}
+
+// deferJob requeues a trade held back by the profitability policy until gas gets cheaper.
+// Deferrals do not use up attempts; once the job has waited ExecutionProfitDeferMaxWait it fails.
+func (s *Service) deferJob(job *ExecutionJob, cause error) {
+	code, _ := classifyExecuteError(cause)
+	if maxWait := s.cfg.ExecutionProfitDeferMaxWait; maxWait > 0 && time.Since(job.CreatedAt) >= maxWait {
+		logger.Warn("unprofitable execution job deferred too long, giving up",
+			"executionId", job.ID,
+			"waited", time.Since(job.CreatedAt).String(),
+		)
+		s.finishJob(job, code, true, cause)
+		return
+	}
+
+	logger.Info("execution job deferred until gas is cheaper",
+		"executionId", job.ID,
+		"delay", s.cfg.ExecutionProfitDeferDelay.String(),
+		"reason", cause.Error(),
+	)
+	if err := s.queue.Defer(context.Background(), job.ID, code, cause, s.cfg.ExecutionProfitDeferDelay); err != nil {
+		logger.Error("failed to defer execution job", err, "executionId", job.ID)
+	}
+}
+
+// requeueStaleJobs periodically returns jobs whose worker disappeared to the queue.
+func (s *Service) requeueStaleJobs(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.ExecutionQueueStaleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.queue.RequeueStale(ctx, time.Now().Add(-s.cfg.ExecutionQueueStaleTimeout))
+		if err != nil && ctx.Err() == nil {
+			logger.Error("failed to requeue stale execution jobs", err)
+		}
+		if n > 0 {
+			logger.Warn("requeued stale execution jobs", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifyWorkers wakes one idle worker after an enqueue without blocking.
+func (s *Service) notifyWorkers() {
+	select {
+	case s.jobReady <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,207 @@
+// Package hmacauth authenticates the internal channel between the matching engine and the
+// execution service with a shared secret.
+//
+// Requests carry a timestamp, a random nonce and an HMAC-SHA256 over
+// "timestamp\nnonce\nMETHOD\npath\nbody". The receiver rejects requests outside the replay
+// window, nonces it has already seen within that window, and bad signatures. Responses are
+// signed over "timestamp\nrequest nonce\nstatus\nbody", so the caller can tell a genuine
+// execution service answer from one injected by whoever else can reach the network.
+package hmacauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Header names used for request and response authentication.
+const (
+	HeaderTimestamp = "X-Oeasy-Timestamp"
+	HeaderNonce     = "X-Oeasy-Nonce"
+	HeaderSignature = "X-Oeasy-Signature"
+)
+
+// DefaultWindow is the replay window used when none is configured.
+const DefaultWindow = 30 * time.Second
+
+// MaxBodyBytes bounds the request body read before the signature is checked, so an
+// unauthenticated caller cannot make the receiver buffer arbitrarily large payloads.
+// Internal requests carry two orders and a signature, well under this limit.
+const MaxBodyBytes = 16 << 10
+
+var (
+	ErrMissingSecret    = errors.New("internal auth secret is not configured")
+	ErrMissingSignature = errors.New("missing authentication headers")
+	ErrStaleTimestamp   = errors.New("timestamp outside replay window")
+	ErrBadSignature     = errors.New("invalid signature")
+	ErrReplayed         = errors.New("nonce already used")
+	ErrBodyTooLarge     = errors.New("request body too large")
+)
+
+// Authenticator signs and verifies internal requests and responses.
+type Authenticator struct {
+	secret []byte
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// New constructs an Authenticator. The secret must be non-empty; a non-positive window
+// falls back to DefaultWindow.
+func New(secret string, window time.Duration) (*Authenticator, error) {
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Authenticator{
+		secret: []byte(secret),
+		window: window,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}, nil
+}
+
+// SignRequest sets the authentication headers on req for the given body and returns the
+// nonce, which the caller needs to verify the response.
+func (a *Authenticator) SignRequest(req *http.Request, body []byte) (string, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(a.now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, a.mac(ts, nonce, req.Method, requestPath(req), string(body)))
+	return nonce, nil
+}
+
+// VerifyRequest checks the authentication headers of r against body and records the nonce.
+func (a *Authenticator) VerifyRequest(r *http.Request, body []byte) error {
+	ts, nonce, sig := r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce), r.Header.Get(HeaderSignature)
+	if ts == "" || nonce == "" || sig == "" {
+		return ErrMissingSignature
+	}
+	if err := a.checkTimestamp(ts); err != nil {
+		return err
+	}
+	if !a.equal(sig, a.mac(ts, nonce, r.Method, requestPath(r), string(body))) {
+		return ErrBadSignature
+	}
+	return a.remember(nonce)
+}
+
+// SignResponse sets the authentication headers for a response to the request with nonce.
+func (a *Authenticator) SignResponse(h http.Header, nonce string, status int, body []byte) {
+	ts := strconv.FormatInt(a.now().Unix(), 10)
+	h.Set(HeaderTimestamp, ts)
+	h.Set(HeaderSignature, a.mac(ts, nonce, strconv.Itoa(status), string(body)))
+}
+
+// VerifyResponse checks a response to the request that was signed with nonce.
+func (a *Authenticator) VerifyResponse(h http.Header, nonce string, status int, body []byte) error {
+	ts, sig := h.Get(HeaderTimestamp), h.Get(HeaderSignature)
+	if ts == "" || sig == "" {
+		return ErrMissingSignature
+	}
+	if err := a.checkTimestamp(ts); err != nil {
+		return err
+	}
+	if !a.equal(sig, a.mac(ts, nonce, strconv.Itoa(status), string(body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ReadBody reads and restores r.Body so it can be verified and then decoded by the handler.
+// Bodies over MaxBodyBytes fail with ErrBodyTooLarge.
+func ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func (a *Authenticator) checkTimestamp(ts string) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrStaleTimestamp, ts)
+	}
+	skew := a.now().Sub(time.Unix(sec, 0))
+	if skew > a.window || skew < -a.window {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
+
+// remember records a nonce for the replay window and rejects one seen before.
+func (a *Authenticator) remember(nonce string) error {
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if expiry, ok := a.seen[nonce]; ok && now.Before(expiry) {
+		return ErrReplayed
+	}
+	for n, expiry := range a.seen {
+		if !now.Before(expiry) {
+			delete(a.seen, n)
+		}
+	}
+	// A nonce can be replayed until its timestamp leaves the window on either side.
+	a.seen[nonce] = now.Add(2 * a.window)
+	return nil
+}
+
+func (a *Authenticator) mac(parts ...string) string {
+	h := hmac.New(sha256.New, a.secret)
+	for i, p := range parts {
+		if i > 0 {
+			h.Write([]byte{'\n'})
+		}
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (a *Authenticator) equal(got, want string) bool {
+	return hmac.Equal([]byte(got), []byte(want))
+}
+
+// requestPath is the signed path; a client request to a bare host has an empty path that
+// the server sees as "/".
+func requestPath(r *http.Request) string {
+	if r.URL.Path == "" {
+		return "/"
+	}
+	return r.URL.Path
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
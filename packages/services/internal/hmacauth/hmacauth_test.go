@@ -0,0 +1,86 @@
+package hmacauth
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_AuthenticatesBothDirections(t *testing.T) {
+	auth, err := New("shared-secret", 30*time.Second)
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	auth.now = func() time.Time { return now }
+
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(append([]byte("echo:"), body...))
+	}), "/internal/health")
+
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	body := []byte(`{"makerOrder":{}}`)
+	signed := func() (*http.Request, string) {
+		req := httptest.NewRequest(http.MethodPost, "/internal/execute", bytes.NewReader(body))
+		nonce, err := auth.SignRequest(req, body)
+		require.NoError(t, err)
+		return req, nonce
+	}
+
+	// A signed request is served and the response verifies against its nonce.
+	req, nonce := signed()
+	rec := send(req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Equal(t, "echo:"+string(body), rec.Body.String())
+	require.NoError(t, auth.VerifyResponse(rec.Header(), nonce, rec.Code, rec.Body.Bytes()))
+	require.ErrorIs(t, auth.VerifyResponse(rec.Header(), "other-nonce", rec.Code, rec.Body.Bytes()), ErrBadSignature)
+	require.ErrorIs(t, auth.VerifyResponse(rec.Header(), nonce, http.StatusOK, rec.Body.Bytes()), ErrBadSignature)
+
+	// Replaying the exact request is rejected.
+	replay := httptest.NewRequest(http.MethodPost, "/internal/execute", bytes.NewReader(body))
+	replay.Header = req.Header.Clone()
+	require.Equal(t, http.StatusUnauthorized, send(replay).Code)
+
+	// Unsigned, tampered, wrongly keyed and stale requests are rejected.
+	require.Equal(t, http.StatusUnauthorized, send(httptest.NewRequest(http.MethodPost, "/internal/execute", bytes.NewReader(body))).Code)
+
+	req, _ = signed()
+	req.Body = io.NopCloser(bytes.NewReader([]byte(`{"makerOrder":{"price":"1"}}`)))
+	require.Equal(t, http.StatusUnauthorized, send(req).Code)
+
+	other, err := New("other-secret", 30*time.Second)
+	require.NoError(t, err)
+	other.now = auth.now
+	req = httptest.NewRequest(http.MethodPost, "/internal/execute", bytes.NewReader(body))
+	_, err = other.SignRequest(req, body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, send(req).Code)
+
+	req, _ = signed()
+	now = now.Add(31 * time.Second)
+	require.Equal(t, http.StatusUnauthorized, send(req).Code)
+
+	// Oversized bodies are rejected before the signature is checked.
+	large := bytes.Repeat([]byte("x"), MaxBodyBytes+1)
+	req = httptest.NewRequest(http.MethodPost, "/internal/execute", bytes.NewReader(large))
+	_, err = auth.SignRequest(req, large)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, send(req).Code)
+
+	// Exempt paths need no signature.
+	require.Equal(t, http.StatusAccepted, send(httptest.NewRequest(http.MethodGet, "/internal/health", nil)).Code)
+}
+
+func TestNew_RequiresSecret(t *testing.T) {
+	_, err := New("", time.Minute)
+	require.ErrorIs(t, err, ErrMissingSecret)
+}
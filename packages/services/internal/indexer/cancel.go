@@ -0,0 +1,146 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancelEvent 表示链上 OrderCancelled 事件（maker 直接调用合约 cancelOrder 消费 nonce）
+type CancelEvent struct {
+	ID              uint64           `gorm:"primaryKey"`
+	TransactionHash string           `gorm:"type:varchar(66);uniqueIndex:idx_cancel_tx_log"` // 交易哈希
+	LogIndex        uint             `gorm:"uniqueIndex:idx_cancel_tx_log"`                  // 日志索引（与交易哈希组成唯一键）
+	BlockNumber     uint64           `gorm:"index"`                                          // 区块号
+	BlockHash       string           `gorm:"type:varchar(66)"`                               // 区块哈希
+	Maker           string           `gorm:"type:varchar(66);index:idx_cancel_maker_nonce"`  // 订单创建者地址
+	Nonce           string           `gorm:"type:numeric;index:idx_cancel_maker_nonce"`      // 被取消的 nonce
+	Status          TradeEventStatus `gorm:"type:varchar(16);index"`                         // pending 或 confirmed
+	ConfirmedAt     *time.Time       // 达到确认深度的时间
+	CreatedAt       time.Time
+}
+
+// TableName 设置 CancelEvent 的表名
+func (CancelEvent) TableName() string {
+	return "cancel_events"
+}
+
+// cancellableStatuses 是链上取消生效的订单状态：活跃订单，以及暂时不可成交、条件恢复后可能重新激活的订单
+var cancellableStatuses = []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusInvalid, orders.OrderStatusUnfunded}
+
+// processOrderCancelled 记录 OrderCancelled 事件，将 (maker, nonce) 对应的未结束订单标记为 cancelled
+// 并从 Redis 活跃订单簿中移除。取消不等待确认：即使之后被链重组撤销，也只是订单暂时不参与撮合。
+func (s *Service) processOrderCancelled(ctx context.Context, vLog types.Log, event *contracts.OeasyMarketplaceOrderCancelled, head uint64) error {
+	cancelEvent := CancelEvent{
+		TransactionHash: vLog.TxHash.Hex(),
+		LogIndex:        vLog.Index,
+		BlockNumber:     vLog.BlockNumber,
+		BlockHash:       vLog.BlockHash.Hex(),
+		Maker:           strings.ToLower(event.Maker.Hex()),
+		Nonce:           event.Nonce.String(),
+		Status:          s.eventStatusAt(vLog.BlockNumber, head),
+		CreatedAt:       time.Now(),
+	}
+	if cancelEvent.Status == TradeEventConfirmed {
+		now := time.Now()
+		cancelEvent.ConfirmedAt = &now
+	}
+
+	var cancelled []orders.Order
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&cancelEvent).Error; err != nil {
+			return err
+		}
+		// 逐个状态修改，变更记录保留各自的原状态，链重组时恢复
+		for _, from := range cancellableStatuses {
+			changed, err := transitionOrders(tx, vLog, from, orders.OrderStatusCancelled,
+				"maker = ? AND nonce = ?", cancelEvent.Maker, cancelEvent.Nonce)
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, changed...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("OrderCancelled事件处理完成",
+		"交易哈希", vLog.TxHash.Hex(),
+		"maker", cancelEvent.Maker,
+		"nonce", cancelEvent.Nonce,
+		"取消订单数", len(cancelled),
+		"状态", cancelEvent.Status,
+	)
+
+	for _, ord := range cancelled {
+		s.uncacheCancelledOrder(ctx, ord)
+	}
+	return nil
+}
+
+// uncacheCancelledOrder 从 Redis 活跃订单簿移除订单并发布取消通知，与订单服务的链下取消一致。
+// Redis 失败只记录日志：数据库已是 cancelled，撮合引擎提交前的可成交性检查也会拒绝已消费的 nonce。
+func (s *Service) uncacheCancelledOrder(ctx context.Context, ord orders.Order) {
+	if s.redis == nil {
+		return
+	}
+	if err := s.redis.HDel(ctx, "orders:active:"+ord.Side, ord.Hash).Err(); err != nil {
+		logger.Error("从活跃订单簿移除已取消订单失败", err, "orderId", ord.ID, "hash", ord.Hash)
+		return
+	}
+
+	payload, err := json.Marshal(struct {
+		OrderID uint      `json:"orderId"`
+		Maker   string    `json:"maker"`
+		Nonce   string    `json:"nonce"`
+		Hash    string    `json:"hash"`
+		Time    time.Time `json:"time"`
+	}{OrderID: ord.ID, Maker: ord.Maker, Nonce: ord.Nonce, Hash: ord.Hash, Time: time.Now()})
+	if err == nil {
+		_ = s.redis.Publish(ctx, "orders:cancelled", payload).Err()
+	}
+}
+
+// recacheOrders 按数据库中的当前状态同步 Redis 活跃订单簿：active 订单写回（链重组回滚、买单资金恢复），
+// 其他状态的订单移除（例如回滚撤销了资金恢复，买单重新变为 unfunded）
+func (s *Service) recacheOrders(ctx context.Context, orderIDs []uint) {
+	if s.redis == nil || len(orderIDs) == 0 {
+		return
+	}
+	var changed []orders.Order
+	err := s.db.WithContext(ctx).Where("id IN ?", orderIDs).Find(&changed).Error
+	if err != nil {
+		logger.Error("查询回滚后的订单失败", err, "订单数", len(orderIDs))
+		return
+	}
+	restored := make([]orders.Order, 0, len(changed))
+	for _, ord := range changed {
+		if ord.Status != orders.OrderStatusActive {
+			_ = s.redis.HDel(ctx, "orders:active:"+ord.Side, ord.Hash).Err()
+			continue
+		}
+		restored = append(restored, ord)
+	}
+	for _, ord := range restored {
+		payload, err := json.Marshal(ord)
+		if err != nil {
+			continue
+		}
+		if err := s.redis.HSet(ctx, "orders:active:"+ord.Side, ord.Hash, payload).Err(); err != nil {
+			logger.Error("恢复活跃订单缓存失败", err, "orderId", ord.ID, "hash", ord.Hash)
+		}
+	}
+	if len(restored) > 0 {
+		logger.Info("已恢复回滚订单的活跃订单簿缓存", "订单数", len(restored))
+	}
+}
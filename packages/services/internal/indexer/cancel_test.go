@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcile_OnChainCancelRemovesOrderFromBook indexes an OrderCancelled event for the
+// ask's (maker, nonce), then forks it away and checks the ask is back on the book until the
+// cancel is reindexed.
+func TestReconcile_OnChainCancelRemovesOrderFromBook(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	svc.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	askHash, err := svc.orderHash(contractOrder(testAsk))
+	require.NoError(t, err)
+	bidHash, err := svc.orderHash(contractOrder(testBid))
+	require.NoError(t, err)
+	mr.HSet("orders:active:ask", askHash, "{}")
+	mr.HSet("orders:active:bid", bidHash, "{}")
+	sub := svc.redis.Subscribe(ctx, "orders:cancelled")
+	defer sub.Close()
+	_, err = sub.Receive(ctx)
+	require.NoError(t, err)
+
+	chain.backend.Commit() // 1
+	chain.emitOrderCancelled(testMaker, 1)
+	chain.backend.Commit() // 2: cancel
+
+	require.NoError(t, svc.reconcile(ctx))
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusCancelled, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+	var events []CancelEvent
+	require.NoError(t, svc.db.Find(&events).Error)
+	require.Len(t, events, 1)
+	require.Equal(t, "0x00000000000000000000000000000000000000a1", events[0].Maker)
+	require.Equal(t, "1", events[0].Nonce)
+	require.False(t, mr.Exists("orders:active:ask"))
+	require.Equal(t, "{}", mr.HGet("orders:active:bid", bidHash))
+	msg, err := sub.ReceiveMessage(ctx)
+	require.NoError(t, err)
+	require.Contains(t, msg.Payload, `"hash":"`+askHash+`"`)
+
+	// Forking from block 1 rolls the cancel back: the ask is active again in Postgres and Redis.
+	require.NoError(t, chain.backend.Fork(chain.blockHash(1)))
+	chain.backend.Commit() // 2': the dropped cancel is mined again
+	chain.backend.Commit() // 3'
+	require.NoError(t, svc.checkReorg(ctx))
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+	require.NoError(t, svc.db.Find(&events).Error)
+	require.Empty(t, events)
+	require.Contains(t, mr.HGet("orders:active:ask", askHash), `"status":"active"`)
+
+	// Reindexing the new fork applies the cancel again.
+	require.NoError(t, svc.reconcile(ctx))
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusCancelled, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+	require.False(t, mr.Exists("orders:active:ask"))
+}
+
+// TestReconcile_OnChainCancelAppliesToInactiveOrder cancels an order that was invalid or
+// unfunded, so it cannot become active again, and restores its status and invalid reason when
+// the cancel is forked away.
+func TestReconcile_OnChainCancelAppliesToInactiveOrder(t *testing.T) {
+	for _, status := range []orders.OrderStatus{orders.OrderStatusInvalid, orders.OrderStatusUnfunded} {
+		t.Run(string(status), func(t *testing.T) {
+			ctx := context.Background()
+			chain := newTestChain(t)
+			svc := newTestService(t, chain)
+			require.NoError(t, svc.db.Model(&orders.Order{}).Where("side = ?", "ask").
+				Updates(map[string]any{"status": status, "invalid_reason": "test"}).Error)
+
+			chain.backend.Commit() // 1
+			chain.emitOrderCancelled(testMaker, 1)
+			chain.backend.Commit() // 2: cancel
+
+			require.NoError(t, svc.reconcile(ctx))
+			require.Equal(t, []orders.OrderStatus{orders.OrderStatusCancelled, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+
+			require.NoError(t, chain.backend.Fork(chain.blockHash(1)))
+			chain.backend.Commit() // 2'
+			chain.backend.Commit() // 3'
+			require.NoError(t, svc.checkReorg(ctx))
+			require.Equal(t, []orders.OrderStatus{status, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+			var ask orders.Order
+			require.NoError(t, svc.db.Where("side = ?", "ask").First(&ask).Error)
+			require.Equal(t, "test", ask.InvalidReason)
+		})
+	}
+}
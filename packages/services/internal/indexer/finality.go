@@ -0,0 +1,143 @@
+package indexer
+
+import (
+	"context"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// TradeEventStatus 表示交易事件的最终性
+type TradeEventStatus string
+
+const (
+	// TradeEventPending 事件所在区块的确认数尚未达到 INDEXER_CONFIRMATIONS，可能被链重组撤销
+	TradeEventPending TradeEventStatus = "pending"
+	// TradeEventConfirmed 事件已达到确认深度，视为最终结果
+	TradeEventConfirmed TradeEventStatus = "confirmed"
+)
+
+// eventStatusAt 根据当前链头判断区块中的事件是否已达到确认深度
+func (s *Service) eventStatusAt(blockNumber, head uint64) TradeEventStatus {
+	if head >= blockNumber+s.cfg.IndexerConfirmations {
+		return TradeEventConfirmed
+	}
+	return TradeEventPending
+}
+
+// settledOrderStatus 返回成交事件对应的订单状态：未确认时为 settling，确认后为 filled
+func settledOrderStatus(status TradeEventStatus) orders.OrderStatus {
+	if status == TradeEventPending {
+		return orders.OrderStatusSettling
+	}
+	return orders.OrderStatusFilled
+}
+
+// confirmEvents 将达到确认深度的 pending 事件提升为 confirmed，
+// 并把这些事件置为 settling 的订单改为 filled
+func (s *Service) confirmEvents(ctx context.Context, head uint64) error {
+	if head < s.cfg.IndexerConfirmations {
+		return nil
+	}
+	depth := head - s.cfg.IndexerConfirmations
+
+	var cancels []CancelEvent
+	err := s.db.WithContext(ctx).
+		Where("status = ? AND block_number <= ?", TradeEventPending, depth).
+		Order("block_number, log_index").
+		Find(&cancels).Error
+	if err != nil {
+		return err
+	}
+	for _, event := range cancels {
+		removed, err := s.removeIfOrphaned(ctx, eventRef{event.TransactionHash, event.LogIndex, event.BlockNumber, event.BlockHash})
+		if err != nil {
+			return err
+		}
+		if removed {
+			continue
+		}
+		// 取消事件不涉及待确认的订单状态，直接标记为已确认
+		err = s.db.WithContext(ctx).Model(&CancelEvent{}).Where("id = ?", event.ID).
+			Updates(map[string]any{"status": TradeEventConfirmed, "confirmed_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	var pending []TradeEvent
+	err = s.db.WithContext(ctx).
+		Where("status = ? AND block_number <= ?", TradeEventPending, depth).
+		Order("block_number, log_index").
+		Find(&pending).Error
+	if err != nil {
+		return err
+	}
+
+	confirmed := 0
+	for _, event := range pending {
+		removed, err := s.removeIfOrphaned(ctx, eventRef{event.TransactionHash, event.LogIndex, event.BlockNumber, event.BlockHash})
+		if err != nil {
+			return err
+		}
+		if removed {
+			continue
+		}
+		if err := s.confirmEvent(ctx, event); err != nil {
+			return err
+		}
+		confirmed++
+	}
+	if confirmed > 0 {
+		logger.Info("交易事件已达到确认深度",
+			"确认数量", confirmed,
+			"确认深度", s.cfg.IndexerConfirmations,
+			"当前区块", head,
+		)
+	}
+	return nil
+}
+
+// removeIfOrphaned 校验待确认事件记录的区块哈希仍在主链上。区块已被替换时（WebSocket 在分叉上写入、
+// 之后未收到 Removed 推送）按 removeLog 撤销该事件并返回 true，不再确认。
+func (s *Service) removeIfOrphaned(ctx context.Context, ref eventRef) (bool, error) {
+	canonical, err := s.isCanonical(ctx, IndexedBlock{BlockNumber: ref.BlockNumber, BlockHash: ref.BlockHash})
+	if err != nil || canonical {
+		return false, err
+	}
+	logger.Warn("待确认事件所在区块已不在主链上，撤销该事件",
+		"交易哈希", ref.TransactionHash,
+		"日志索引", ref.LogIndex,
+		"区块", ref.BlockNumber,
+		"区块哈希", ref.BlockHash,
+	)
+	return true, s.removeLog(ctx, ref.log())
+}
+
+// confirmEvent 在一个事务中确认单个事件，订单状态修改同样记录在 order_status_changes 中
+func (s *Service) confirmEvent(ctx context.Context, event TradeEvent) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Model(&TradeEvent{}).Where("id = ?", event.ID).
+			Updates(map[string]any{"status": TradeEventConfirmed, "confirmed_at": now}).Error
+		if err != nil {
+			return err
+		}
+
+		// 只处理由该事件置为 settling 的订单
+		vLog := types.Log{
+			BlockNumber: event.BlockNumber,
+			TxHash:      common.HexToHash(event.TransactionHash),
+			Index:       event.LogIndex,
+		}
+		settled := tx.Model(&OrderStatusChange{}).Select("order_id").
+			Where("transaction_hash = ? AND log_index = ? AND new_status = ?",
+				event.TransactionHash, event.LogIndex, orders.OrderStatusSettling)
+		_, err = transitionOrders(tx, vLog, orders.OrderStatusSettling, orders.OrderStatusFilled, "id IN (?)", settled)
+		return err
+	})
+}
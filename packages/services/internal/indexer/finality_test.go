@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcile_PromotesTradeAfterConfirmations indexes a trade at the chain head as pending
+// with settling orders, then promotes it once enough blocks are built on top.
+func TestReconcile_PromotesTradeAfterConfirmations(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	svc.cfg.IndexerConfirmations = 2
+
+	chain.emitTrade(testAsk, testBid)
+	chain.backend.Commit() // 1: trade
+	chain.backend.Commit() // 2
+
+	require.NoError(t, svc.reconcile(ctx))
+	var event TradeEvent
+	require.NoError(t, svc.db.First(&event).Error)
+	require.Equal(t, TradeEventPending, event.Status)
+	require.Nil(t, event.ConfirmedAt)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusSettling, orders.OrderStatusSettling}, orderStatuses(t, svc.db))
+
+	// Replaying the same log (as the WebSocket path does) keeps it pending.
+	logs, err := chain.backend.Client().FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{testMarketplace}})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.NoError(t, svc.processLog(ctx, logs[0], 2))
+	require.NoError(t, svc.db.First(&event).Error)
+	require.Equal(t, TradeEventPending, event.Status)
+
+	chain.backend.Commit() // 3: two blocks on top of the trade
+	require.NoError(t, svc.reconcile(ctx))
+	require.NoError(t, svc.db.First(&event).Error)
+	require.Equal(t, TradeEventConfirmed, event.Status)
+	require.NotNil(t, event.ConfirmedAt)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusFilled, orders.OrderStatusFilled}, orderStatuses(t, svc.db))
+
+	// Both steps are logged, so a rollback past the trade restores the active orders.
+	var changes []OrderStatusChange
+	require.NoError(t, svc.db.Order("id").Find(&changes).Error)
+	require.Len(t, changes, 4)
+	require.NoError(t, svc.rollback(ctx, 1))
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+}
+
+// TestConfirmEvents_RemovesEventsFromReplacedBlock stores a pending trade and a pending cancel
+// whose recorded block hashes are no longer canonical, and checks that confirmation undoes them
+// instead of promoting them.
+func TestConfirmEvents_RemovesEventsFromReplacedBlock(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	svc.cfg.IndexerConfirmations = 2
+	other := testOrder(testMaker, 2, 1000, 0)
+	svc.seedOrder(t, other)
+
+	chain.emitTrade(testAsk, testBid)
+	chain.emitOrderCancelled(testMaker, 2)
+	chain.backend.Commit() // 1: trade, cancel
+	chain.backend.Commit() // 2
+	chain.backend.Commit() // 3
+
+	logs, err := chain.backend.Client().FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{testMarketplace}})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	for _, vLog := range logs {
+		vLog.BlockHash = common.HexToHash("0x01")
+		require.NoError(t, svc.processLog(ctx, vLog, 1))
+	}
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusSettling, orders.OrderStatusSettling, orders.OrderStatusCancelled},
+		orderStatuses(t, svc.db))
+
+	require.NoError(t, svc.confirmEvents(ctx, 3))
+	var count int64
+	require.NoError(t, svc.db.Model(&TradeEvent{}).Count(&count).Error)
+	require.Zero(t, count)
+	require.NoError(t, svc.db.Model(&CancelEvent{}).Count(&count).Error)
+	require.Zero(t, count)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive, orders.OrderStatusActive},
+		orderStatuses(t, svc.db))
+}
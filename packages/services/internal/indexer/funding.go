@@ -0,0 +1,244 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/matching"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentTokenBalance 记录买单 maker 在支付代币上的余额和对 marketplace 的授权额度快照。
+// 只跟踪持有 active 或 unfunded 买单的地址，数值在处理 Transfer / Approval 事件时从链上读取。
+type PaymentTokenBalance struct {
+	TokenAddress    string `gorm:"primaryKey;type:varchar(66)"` // 支付代币地址
+	Holder          string `gorm:"primaryKey;type:varchar(66)"` // 买单 maker 地址
+	Balance         string `gorm:"type:numeric"`                // 代币余额
+	Allowance       string `gorm:"type:numeric"`                // 对 marketplace 的授权额度
+	BlockNumber     uint64 `gorm:"index"`                       // 触发刷新的事件所在区块
+	BlockHash       string `gorm:"type:varchar(66)"`            // 触发刷新的事件所在区块哈希
+	TransactionHash string `gorm:"type:varchar(66)"`            // 触发刷新的事件交易哈希
+	LogIndex        uint   // 触发刷新的事件日志索引
+	UpdatedAt       time.Time
+}
+
+// TableName 设置 PaymentTokenBalance 的表名
+func (PaymentTokenBalance) TableName() string {
+	return "payment_token_balances"
+}
+
+// processPaymentTokenLog 处理支付代币的 Transfer / Approval 事件，刷新受影响地址的资金快照
+func (s *Service) processPaymentTokenLog(ctx context.Context, vLog types.Log) error {
+	var holders []common.Address
+	if transfer, err := s.paymentFilterer.ParseTransfer(vLog); err == nil {
+		holders = append(holders, transfer.From, transfer.To)
+	} else if approval, err := s.paymentFilterer.ParseApproval(vLog); err == nil {
+		if approval.Spender != s.marketplaceAddr {
+			return nil
+		}
+		holders = append(holders, approval.Owner)
+	} else {
+		return nil
+	}
+
+	for _, holder := range holders {
+		if holder == (common.Address{}) {
+			continue
+		}
+		if err := s.refreshFunding(ctx, vLog, holder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshFunding 读取 holder 当前的余额和授权额度，写入快照，并按快照更新其买单：
+// 余额或授权不足以支付的 active 买单改为 unfunded 并移出 Redis，资金恢复的 unfunded 买单改回 active。
+// 状态修改记录在 order_status_changes 中，链重组时回滚。
+func (s *Service) refreshFunding(ctx context.Context, vLog types.Log, holder common.Address) error {
+	token := vLog.Address
+	tokenAddr := strings.ToLower(token.Hex())
+	maker := strings.ToLower(holder.Hex())
+
+	// 没有挂单的地址与订单簿无关，不读取链上数据
+	var bids int64
+	err := s.db.WithContext(ctx).Model(&orders.Order{}).
+		Where("maker = ? AND payment_token = ? AND side = ? AND status IN ?",
+			maker, tokenAddr, "bid", []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusUnfunded}).
+		Count(&bids).Error
+	if err != nil || bids == 0 {
+		return err
+	}
+
+	caller, err := contracts.NewMockUSDCCaller(token, s.client)
+	if err != nil {
+		return err
+	}
+	// 读取事件所在区块的状态，而不是最新状态：和解轮询补处理旧区块时快照与事件一致
+	balance, allowance, err := s.readFunding(ctx, caller, holder, vLog.BlockNumber)
+	if err != nil {
+		return err
+	}
+
+	// 成交交易中被成交的买单交给 TradeExecuted 处理
+	tradedBid, err := s.tradedOrderHash(ctx, vLog.TxHash, func(ord contracts.IMarketplaceOrder) bool {
+		return ord.Side == 1 && ord.Maker == holder && ord.PaymentToken == token
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot := PaymentTokenBalance{
+		TokenAddress:    tokenAddr,
+		Holder:          maker,
+		Balance:         balance.String(),
+		Allowance:       allowance.String(),
+		BlockNumber:     vLog.BlockNumber,
+		BlockHash:       vLog.BlockHash.Hex(),
+		TransactionHash: vLog.TxHash.Hex(),
+		LogIndex:        vLog.Index,
+		UpdatedAt:       time.Now(),
+	}
+
+	var unfunded, refunded []orders.Order
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&snapshot).Error; err != nil {
+			return err
+		}
+
+		bidsOf := func() *gorm.DB {
+			query := tx.Model(&orders.Order{}).Select("id").
+				Where("maker = ? AND payment_token = ? AND side = ?", maker, tokenAddr, "bid")
+			if tradedBid != "" {
+				query = query.Where("hash <> ?", tradedBid)
+			}
+			return query
+		}
+
+		// 余额不足优先于授权不足，与撮合引擎可成交性检查的判断顺序一致
+		for _, short := range []struct {
+			limit  *big.Int
+			reason string
+		}{
+			{balance, matching.ReasonInsufficientBalance},
+			{allowance, matching.ReasonInsufficientAllowance},
+		} {
+			changed, err := transitionOrders(tx, vLog, orders.OrderStatusActive, orders.OrderStatusUnfunded,
+				"id IN (?)", bidsOf().Where("price > ?", short.limit.String()))
+			if err != nil {
+				return err
+			}
+			if err := setInvalidReason(tx, changed, short.reason); err != nil {
+				return err
+			}
+			unfunded = append(unfunded, changed...)
+		}
+
+		refunded, err = transitionOrders(tx, vLog, orders.OrderStatusUnfunded, orders.OrderStatusActive,
+			"id IN (?)", bidsOf().Where("price <= ? AND price <= ? AND expiry > ?", balance.String(), allowance.String(), time.Now()))
+		if err != nil {
+			return err
+		}
+		return setInvalidReason(tx, refunded, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(unfunded) > 0 || len(refunded) > 0 {
+		logger.Info("买单资金状态已更新",
+			"maker", maker,
+			"支付代币", tokenAddr,
+			"余额", snapshot.Balance,
+			"授权额度", snapshot.Allowance,
+			"资金不足", len(unfunded),
+			"恢复", len(refunded),
+		)
+	}
+	for _, ord := range unfunded {
+		s.uncacheInvalidOrder(ctx, ord, ord.InvalidReason)
+	}
+	ids := make([]uint, 0, len(refunded))
+	for _, ord := range refunded {
+		ids = append(ids, ord.ID)
+	}
+	s.recacheOrders(ctx, ids)
+	return nil
+}
+
+// readFunding 读取 holder 在指定区块的余额和对 marketplace 的授权额度
+func (s *Service) readFunding(ctx context.Context, caller *contracts.MockUSDCCaller, holder common.Address, block uint64) (*big.Int, *big.Int, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block)}
+	balance, err := caller.BalanceOf(opts, holder)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 %s 余额失败: %w", strings.ToLower(holder.Hex()), err)
+	}
+	allowance, err := caller.Allowance(opts, holder, s.marketplaceAddr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 %s 授权额度失败: %w", strings.ToLower(holder.Hex()), err)
+	}
+	return balance, allowance, nil
+}
+
+// restoreSnapshots 重新读取被回滚删除的资金快照。回滚已把订单状态恢复到 block 时的状态，
+// 这里只按 block 的链上数据重写快照，不修改订单；失败只记录日志，下一次事件时会重新读取。
+func (s *Service) restoreSnapshots(ctx context.Context, deleted []PaymentTokenBalance, block uint64) {
+	if len(deleted) == 0 {
+		return
+	}
+	header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+	if err != nil {
+		logger.Error("获取区块失败，无法恢复资金快照", err, "区块", block, "快照数", len(deleted))
+		return
+	}
+	for _, old := range deleted {
+		token, holder := common.HexToAddress(old.TokenAddress), common.HexToAddress(old.Holder)
+		caller, err := contracts.NewMockUSDCCaller(token, s.client)
+		if err != nil {
+			logger.Error("恢复资金快照失败", err, "holder", old.Holder, "支付代币", old.TokenAddress)
+			continue
+		}
+		balance, allowance, err := s.readFunding(ctx, caller, holder, block)
+		if err != nil {
+			logger.Error("恢复资金快照失败", err, "holder", old.Holder, "支付代币", old.TokenAddress)
+			continue
+		}
+		snapshot := PaymentTokenBalance{
+			TokenAddress: old.TokenAddress,
+			Holder:       old.Holder,
+			Balance:      balance.String(),
+			Allowance:    allowance.String(),
+			BlockNumber:  block,
+			BlockHash:    header.Hash().Hex(),
+			UpdatedAt:    time.Now(),
+		}
+		err = s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&snapshot).Error
+		if err != nil {
+			logger.Error("恢复资金快照失败", err, "holder", old.Holder, "支付代币", old.TokenAddress)
+		}
+	}
+	logger.Info("已按回滚后的区块恢复资金快照", "区块", block, "快照数", len(deleted))
+}
+
+// setInvalidReason 记录订单不可成交的原因（reason 为空时清除），同时更新内存中的订单
+func setInvalidReason(tx *gorm.DB, changed []orders.Order, reason string) error {
+	if len(changed) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(changed))
+	for i := range changed {
+		changed[i].InvalidReason = reason
+		ids = append(ids, changed[i].ID)
+	}
+	return tx.Model(&orders.Order{}).Where("id IN ?", ids).Update("invalid_reason", reason).Error
+}
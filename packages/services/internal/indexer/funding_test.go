@@ -0,0 +1,130 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/matching"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// fundingClient answers the payment token's balanceOf and allowance calls from maps, since the
+// log emitter standing in for the token has no state.
+type fundingClient struct {
+	simulated.Client
+	balances   map[common.Address]*big.Int
+	allowances map[common.Address]*big.Int
+	blocks     []uint64 // block number of each call
+}
+
+func (c *fundingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	parsed, err := contracts.MockUSDCMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("call without a block number")
+	}
+	c.blocks = append(c.blocks, block.Uint64())
+	method, err := parsed.MethodById(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	holder := args[0].(common.Address)
+	value := c.balances[holder]
+	if method.Name == "allowance" {
+		value = c.allowances[holder]
+	}
+	if value == nil {
+		value = new(big.Int)
+	}
+	return method.Outputs.Pack(value)
+}
+
+func (c *testChain) emitERC20(event string, from, to common.Address, value int64) {
+	c.t.Helper()
+	parsed, err := contracts.MockUSDCMetaData.GetAbi()
+	require.NoError(c.t, err)
+	data, err := parsed.Events[event].Inputs.NonIndexed().Pack(big.NewInt(value))
+	require.NoError(c.t, err)
+	c.emitLog(testPayment, nil, []common.Hash{parsed.Events[event].ID, addressTopic(from), addressTopic(to)}, data)
+}
+
+// TestReconcile_BidFollowsBuyerFunding spends the bidder's balance below the bid price and
+// checks the bid becomes unfunded and leaves the book, then restores the balance and checks
+// the bid is active again. Balances are read at the event's block, and a rollback of the
+// restoring block re-reads the snapshot at the block before it.
+func TestReconcile_BidFollowsBuyerFunding(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	svc.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	client := &fundingClient{
+		Client:     chain.backend.Client(),
+		balances:   map[common.Address]*big.Int{testTaker: big.NewInt(2000)},
+		allowances: map[common.Address]*big.Int{testTaker: big.NewInt(2000)},
+	}
+	svc.client = client
+	bidHash, err := svc.orderHash(contractOrder(testBid))
+	require.NoError(t, err)
+	mr.HSet("orders:active:bid", bidHash, "{}")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000d1")
+
+	chain.emitERC20("Approval", testTaker, testMarketplace, 2000)
+	chain.backend.Commit() // 1: approval covers the bid
+	require.NoError(t, svc.reconcile(ctx))
+	var snapshot PaymentTokenBalance
+	require.NoError(t, svc.db.First(&snapshot, "holder = ?", "0x00000000000000000000000000000000000000a2").Error)
+	require.Equal(t, "2000", snapshot.Balance)
+	require.Equal(t, "2000", snapshot.Allowance)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+
+	client.balances[testTaker] = big.NewInt(500)
+	chain.emitERC20("Transfer", testTaker, other, 1500)
+	chain.backend.Commit() // 2: the bidder spends below the bid price
+	require.NoError(t, svc.reconcile(ctx))
+	var bid orders.Order
+	require.NoError(t, svc.db.First(&bid, "hash = ?", bidHash).Error)
+	require.Equal(t, orders.OrderStatusUnfunded, bid.Status)
+	require.Equal(t, matching.ReasonInsufficientBalance, bid.InvalidReason)
+	require.False(t, mr.Exists("orders:active:bid"))
+
+	client.balances[testTaker] = big.NewInt(1500)
+	chain.emitERC20("Transfer", other, testTaker, 1000)
+	chain.backend.Commit() // 3: funds come back
+	require.NoError(t, svc.reconcile(ctx))
+	require.NoError(t, svc.db.First(&bid, "hash = ?", bidHash).Error)
+	require.Equal(t, orders.OrderStatusActive, bid.Status)
+	require.Empty(t, bid.InvalidReason)
+	require.Contains(t, mr.HGet("orders:active:bid", bidHash), `"status":"active"`)
+	require.NoError(t, svc.db.First(&snapshot, "holder = ?", "0x00000000000000000000000000000000000000a2").Error)
+	require.Equal(t, "1500", snapshot.Balance)
+	require.EqualValues(t, 3, snapshot.BlockNumber)
+	require.Equal(t, []uint64{1, 1, 2, 2, 3, 3}, client.blocks)
+
+	// Rolling back block 3 re-reads the deleted snapshot at block 2 and leaves the bid unfunded.
+	client.balances[testTaker] = big.NewInt(500)
+	require.NoError(t, svc.rollback(ctx, 3))
+	require.NoError(t, svc.db.First(&snapshot, "holder = ?", "0x00000000000000000000000000000000000000a2").Error)
+	require.Equal(t, "500", snapshot.Balance)
+	require.EqualValues(t, 2, snapshot.BlockNumber)
+	require.Equal(t, chain.blockHash(2).Hex(), snapshot.BlockHash)
+	require.Equal(t, uint64(2), client.blocks[len(client.blocks)-1])
+	require.NoError(t, svc.db.First(&bid, "hash = ?", bidHash).Error)
+	require.Equal(t, orders.OrderStatusUnfunded, bid.Status)
+	require.Equal(t, matching.ReasonInsufficientBalance, bid.InvalidReason)
+}
@@ -0,0 +1,102 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errNotExecuteTrade 表示交易输入不是直接调用 marketplace.executeTrade（例如经由其他合约转发）
+var errNotExecuteTrade = errors.New("交易输入不是 executeTrade 调用")
+
+// tradeOrderHashes 获取成交事件所在交易，解码 executeTrade(makerOrder, takerOrder, sig) 的输入，
+// 按订单服务的方式重新计算两张订单的 EIP-712 哈希。解码出的订单必须与事件一致。
+func (s *Service) tradeOrderHashes(ctx context.Context, vLog types.Log, event *contracts.OeasyMarketplaceTradeExecuted) (makerHash, takerHash string, err error) {
+	tx, _, err := s.client.TransactionByHash(ctx, vLog.TxHash)
+	if err != nil {
+		return "", "", fmt.Errorf("获取交易 %s 失败: %w", vLog.TxHash.Hex(), err)
+	}
+	maker, taker, err := decodeExecuteTrade(tx.Data())
+	if err != nil {
+		return "", "", err
+	}
+	if maker.Maker != event.Maker || taker.Maker != event.Taker || maker.Nft != event.Nft ||
+		maker.TokenId.Cmp(event.TokenId) != 0 {
+		return "", "", fmt.Errorf("%w: 解码出的订单与事件不一致", errNotExecuteTrade)
+	}
+
+	if makerHash, err = s.orderHash(maker); err != nil {
+		return "", "", err
+	}
+	if takerHash, err = s.orderHash(taker); err != nil {
+		return "", "", err
+	}
+	return makerHash, takerHash, nil
+}
+
+// decodeExecuteTrade 解码 executeTrade 调用数据中的两张订单
+func decodeExecuteTrade(input []byte) (maker, taker contracts.IMarketplaceOrder, err error) {
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	if err != nil {
+		return maker, taker, err
+	}
+	method := parsed.Methods["executeTrade"]
+	if len(input) < 4 || !bytes.Equal(input[:4], method.ID) {
+		return maker, taker, errNotExecuteTrade
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return maker, taker, fmt.Errorf("%w: %v", errNotExecuteTrade, err)
+	}
+	maker = *abi.ConvertType(args[0], new(contracts.IMarketplaceOrder)).(*contracts.IMarketplaceOrder)
+	taker = *abi.ConvertType(args[1], new(contracts.IMarketplaceOrder)).(*contracts.IMarketplaceOrder)
+	return maker, taker, nil
+}
+
+// tradedOrderHash 判断交易是否直接调用了 executeTrade，是则返回其中满足 match 的订单哈希，否则返回空字符串。
+// 成交交易中 NFT 和支付代币的转移日志先于 TradeExecuted 记录，被成交的订单应交给成交事件处理。
+func (s *Service) tradedOrderHash(ctx context.Context, txHash common.Hash, match func(contracts.IMarketplaceOrder) bool) (string, error) {
+	tx, _, err := s.client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return "", fmt.Errorf("获取交易 %s 失败: %w", txHash.Hex(), err)
+	}
+	if tx.To() == nil || *tx.To() != s.marketplaceAddr {
+		return "", nil
+	}
+	maker, taker, err := decodeExecuteTrade(tx.Data())
+	if err != nil {
+		return "", nil
+	}
+	for _, ord := range []contracts.IMarketplaceOrder{maker, taker} {
+		if match(ord) {
+			return s.orderHash(ord)
+		}
+	}
+	return "", nil
+}
+
+// orderHash 计算订单哈希，格式与 orders 表的 hash 列一致（小写 0x 十六进制）
+func (s *Service) orderHash(order contracts.IMarketplaceOrder) (string, error) {
+	digest, err := orders.HashOrder(s.typedData, orders.OrderFields{
+		Maker:        order.Maker,
+		NFT:          order.Nft,
+		TokenID:      order.TokenId,
+		PaymentToken: order.PaymentToken,
+		Price:        order.Price,
+		Expiry:       order.Expiry,
+		Nonce:        order.Nonce,
+		Side:         order.Side,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(digest.Hex()), nil
+}
@@ -0,0 +1,60 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcile_FillsOnlyTheTradedOrders decodes executeTrade from the trade transaction and
+// fills the two exact orders, leaving the taker's older bid on the same token active.
+func TestReconcile_FillsOnlyTheTradedOrders(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	olderBidHash := svc.seedOrder(t, testOrder(testTaker, 2, 900, 1))
+
+	chain.emitTrade(testAsk, testBid)
+	chain.backend.Commit()
+	require.NoError(t, svc.reconcile(ctx))
+
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusFilled, orders.OrderStatusFilled, orders.OrderStatusActive},
+		orderStatuses(t, svc.db))
+	var event TradeEvent
+	require.NoError(t, svc.db.First(&event).Error)
+	askHash, err := svc.orderHash(contractOrder(testAsk))
+	require.NoError(t, err)
+	bidHash, err := svc.orderHash(contractOrder(testBid))
+	require.NoError(t, err)
+	require.Equal(t, askHash, event.MakerOrderHash)
+	require.Equal(t, bidHash, event.TakerOrderHash)
+	require.NotEqual(t, olderBidHash, event.TakerOrderHash)
+}
+
+// TestReconcile_UndecodableTradeFillsNothing records a TradeExecuted log whose transaction is
+// not a direct executeTrade call without touching any order.
+func TestReconcile_UndecodableTradeFillsNothing(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	require.NoError(t, err)
+	event := parsed.Events["TradeExecuted"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(7), testPayment, big.NewInt(1000), uint8(0), big.NewInt(25))
+	require.NoError(t, err)
+	chain.emitLog(testMarketplace, nil, []common.Hash{event.ID, addressTopic(testMaker), addressTopic(testTaker), addressTopic(testNFT)}, data)
+	chain.backend.Commit()
+	require.NoError(t, svc.reconcile(ctx))
+
+	var stored TradeEvent
+	require.NoError(t, svc.db.First(&stored).Error)
+	require.Empty(t, stored.MakerOrderHash)
+	require.Empty(t, stored.TakerOrderHash)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+}
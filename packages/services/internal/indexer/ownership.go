@@ -0,0 +1,214 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/matching"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenOwnership 记录 INDEXER_NFT_COLLECTIONS 中每个 token 的当前持有者，由 Transfer 事件维护。
+// 销毁的 token 持有者为零地址。
+type TokenOwnership struct {
+	NFTAddress      string `gorm:"primaryKey;type:varchar(66);column:nft_address"` // NFT 合约地址
+	TokenID         string `gorm:"primaryKey;type:numeric"`                        // Token ID
+	Owner           string `gorm:"type:varchar(66);index"`                         // 当前持有者
+	BlockNumber     uint64 // 最近一次转移所在区块
+	TransactionHash string `gorm:"type:varchar(66)"` // 最近一次转移的交易哈希
+	LogIndex        uint   // 最近一次转移的日志索引
+	UpdatedAt       time.Time
+}
+
+// TableName 设置 TokenOwnership 的表名
+func (TokenOwnership) TableName() string {
+	return "token_ownership"
+}
+
+// NFTTransfer 表示已处理的 ERC-721 Transfer 事件，链重组回滚后据此重建 token_ownership
+type NFTTransfer struct {
+	ID              uint64 `gorm:"primaryKey"`
+	TransactionHash string `gorm:"type:varchar(66);uniqueIndex:idx_nft_transfer_tx_log"`    // 交易哈希
+	LogIndex        uint   `gorm:"uniqueIndex:idx_nft_transfer_tx_log"`                     // 日志索引（与交易哈希组成唯一键）
+	BlockNumber     uint64 `gorm:"index"`                                                   // 区块号
+	BlockHash       string `gorm:"type:varchar(66)"`                                        // 区块哈希
+	NFTAddress      string `gorm:"type:varchar(66);column:nft_address;index:idx_nft_token"` // NFT 合约地址
+	TokenID         string `gorm:"type:numeric;index:idx_nft_token"`                        // Token ID
+	FromAddress     string `gorm:"type:varchar(66)"`                                        // 转出地址（铸造时为零地址）
+	ToAddress       string `gorm:"type:varchar(66)"`                                        // 转入地址（销毁时为零地址）
+	CreatedAt       time.Time
+}
+
+// TableName 设置 NFTTransfer 的表名
+func (NFTTransfer) TableName() string {
+	return "nft_transfers"
+}
+
+// ownership 返回转移之后的持有者记录
+func (t NFTTransfer) ownership() TokenOwnership {
+	return TokenOwnership{
+		NFTAddress:      t.NFTAddress,
+		TokenID:         t.TokenID,
+		Owner:           t.ToAddress,
+		BlockNumber:     t.BlockNumber,
+		TransactionHash: t.TransactionHash,
+		LogIndex:        t.LogIndex,
+		UpdatedAt:       time.Now(),
+	}
+}
+
+// processNFTTransfer 记录 Transfer 事件并更新 token 持有者。token 离开原持有者时，
+// 原持有者在该 token 上的活跃卖单无法再成交，标记为 invalid 并移出 Redis 活跃订单簿。
+// 与取消一样不等待确认，链重组时通过 order_status_changes 回滚。
+func (s *Service) processNFTTransfer(ctx context.Context, vLog types.Log, event *contracts.OeasyNFTTransfer) error {
+	transfer := NFTTransfer{
+		TransactionHash: vLog.TxHash.Hex(),
+		LogIndex:        vLog.Index,
+		BlockNumber:     vLog.BlockNumber,
+		BlockHash:       vLog.BlockHash.Hex(),
+		NFTAddress:      strings.ToLower(vLog.Address.Hex()),
+		TokenID:         event.TokenId.String(),
+		FromAddress:     strings.ToLower(event.From.Hex()),
+		ToAddress:       strings.ToLower(event.To.Hex()),
+		CreatedAt:       time.Now(),
+	}
+
+	// 成交交易中被成交的卖单交给 TradeExecuted 处理
+	var tradedAsk string
+	if event.From != (common.Address{}) && event.From != event.To {
+		var err error
+		tradedAsk, err = s.tradedOrderHash(ctx, vLog.TxHash, func(ord contracts.IMarketplaceOrder) bool {
+			return ord.Side == 0 && ord.Maker == event.From && ord.Nft == vLog.Address && ord.TokenId.Cmp(event.TokenId) == 0
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var invalidated []orders.Order
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&transfer).Error; err != nil {
+			return err
+		}
+		if err := upsertOwnership(tx, transfer.ownership()); err != nil {
+			return err
+		}
+		if event.From == (common.Address{}) || event.From == event.To {
+			return nil
+		}
+
+		query := tx.Model(&orders.Order{}).Select("id").
+			Where("maker = ? AND nft_address = ? AND token_id = ? AND side = ?",
+				transfer.FromAddress, transfer.NFTAddress, transfer.TokenID, "ask")
+		if tradedAsk != "" {
+			query = query.Where("hash <> ?", tradedAsk)
+		}
+		var err error
+		invalidated, err = transitionOrders(tx, vLog, orders.OrderStatusActive, orders.OrderStatusInvalid, "id IN (?)", query)
+		if err != nil {
+			return err
+		}
+		return setInvalidReason(tx, invalidated, matching.ReasonSellerNotOwner)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("NFT Transfer事件处理完成",
+		"交易哈希", vLog.TxHash.Hex(),
+		"nft", transfer.NFTAddress,
+		"tokenId", transfer.TokenID,
+		"from", transfer.FromAddress,
+		"to", transfer.ToAddress,
+		"失效卖单数", len(invalidated),
+	)
+
+	for _, ord := range invalidated {
+		s.uncacheInvalidOrder(ctx, ord, matching.ReasonSellerNotOwner)
+	}
+	return nil
+}
+
+// upsertOwnership 写入持有者记录，只有比已记录的转移更新的事件才会覆盖（轮询与订阅的处理顺序可能不同）
+func upsertOwnership(tx *gorm.DB, ownership TokenOwnership) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "nft_address"}, {Name: "token_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"owner", "block_number", "transaction_hash", "log_index", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{
+			SQL: "token_ownership.block_number < excluded.block_number OR " +
+				"(token_ownership.block_number = excluded.block_number AND token_ownership.log_index < excluded.log_index)",
+		}}},
+	}).Create(&ownership).Error
+}
+
+// rebuildOwnership 在删除转移记录后，按剩余的最新转移重建这些 token 的持有者记录
+func rebuildOwnership(tx *gorm.DB, removed []NFTTransfer) error {
+	type tokenKey struct{ nft, tokenID string }
+	seen := make(map[tokenKey]bool, len(removed))
+	for _, t := range removed {
+		key := tokenKey{t.NFTAddress, t.TokenID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var latest NFTTransfer
+		err := tx.Where("nft_address = ? AND token_id = ?", key.nft, key.tokenID).
+			Order("block_number DESC, log_index DESC").Limit(1).Find(&latest).Error
+		if err != nil {
+			return err
+		}
+		if latest.ID == 0 {
+			err = tx.Where("nft_address = ? AND token_id = ?", key.nft, key.tokenID).Delete(&TokenOwnership{}).Error
+		} else {
+			err = tx.Save(latest.ownership()).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTransfers 删除满足条件的转移记录并重建涉及 token 的持有者，返回删除数量
+func deleteTransfers(tx *gorm.DB, query string, args ...any) (int, error) {
+	var removed []NFTTransfer
+	if err := tx.Where(query, args...).Find(&removed).Error; err != nil || len(removed) == 0 {
+		return 0, err
+	}
+	if err := tx.Where(query, args...).Delete(&NFTTransfer{}).Error; err != nil {
+		return 0, err
+	}
+	return len(removed), rebuildOwnership(tx, removed)
+}
+
+// uncacheInvalidOrder 从 Redis 活跃订单簿移除订单并发布失效通知，消息格式与撮合引擎的可成交性检查一致
+func (s *Service) uncacheInvalidOrder(ctx context.Context, ord orders.Order, reason string) {
+	if s.redis == nil {
+		return
+	}
+	if err := s.redis.HDel(ctx, "orders:active:"+ord.Side, ord.Hash).Err(); err != nil {
+		logger.Error("从活跃订单簿移除失效订单失败", err, "orderId", ord.ID, "hash", ord.Hash)
+		return
+	}
+
+	payload, err := json.Marshal(struct {
+		OrderID uint      `json:"orderId"`
+		Maker   string    `json:"maker"`
+		Nonce   string    `json:"nonce"`
+		Hash    string    `json:"hash"`
+		Reason  string    `json:"reason"`
+		Time    time.Time `json:"time"`
+	}{OrderID: ord.ID, Maker: ord.Maker, Nonce: ord.Nonce, Hash: ord.Hash, Reason: reason, Time: time.Now()})
+	if err == nil {
+		_ = s.redis.Publish(ctx, "orders:invalid", payload).Err()
+	}
+}
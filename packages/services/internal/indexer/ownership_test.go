@@ -0,0 +1,59 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oeasy-NFT/services/internal/matching"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcile_TransferInvalidatesSellersAsk moves the ask's token away from the maker and
+// checks ownership is recorded and the ask invalidated, then forks the transfer away and checks
+// both are undone.
+func TestReconcile_TransferInvalidatesSellersAsk(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	svc.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	askHash, err := svc.orderHash(contractOrder(testAsk))
+	require.NoError(t, err)
+	mr.HSet("orders:active:ask", askHash, "{}")
+	recipient := common.HexToAddress("0x00000000000000000000000000000000000000d1")
+
+	chain.emitNFTTransfer(common.Address{}, testMaker, 7)
+	chain.backend.Commit() // 1: mint to the seller
+	chain.emitNFTTransfer(testMaker, recipient, 7)
+	chain.backend.Commit() // 2: seller moves the token away
+
+	require.NoError(t, svc.reconcile(ctx))
+	var ownership TokenOwnership
+	require.NoError(t, svc.db.First(&ownership, "nft_address = ? AND token_id = ?", "0x00000000000000000000000000000000000000b1", "7").Error)
+	require.Equal(t, "0x00000000000000000000000000000000000000d1", ownership.Owner)
+	require.EqualValues(t, 2, ownership.BlockNumber)
+
+	var ask orders.Order
+	require.NoError(t, svc.db.First(&ask, "hash = ?", askHash).Error)
+	require.Equal(t, orders.OrderStatusInvalid, ask.Status)
+	require.Equal(t, matching.ReasonSellerNotOwner, ask.InvalidReason)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusInvalid, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+	require.False(t, mr.Exists("orders:active:ask"))
+
+	// Forking from block 1 drops the transfer: the seller owns the token and the ask is live again.
+	require.NoError(t, chain.backend.Fork(chain.blockHash(1)))
+	chain.backend.Rollback() // discard the re-queued transfer
+	chain.backend.Commit()   // 2'
+	chain.backend.Commit()   // 3'
+	require.NoError(t, svc.reconcile(ctx))
+	require.NoError(t, svc.db.First(&ownership, "nft_address = ? AND token_id = ?", "0x00000000000000000000000000000000000000b1", "7").Error)
+	require.Equal(t, "0x00000000000000000000000000000000000000a1", ownership.Owner)
+	require.NoError(t, svc.db.First(&ask, "hash = ?", askHash).Error)
+	require.Equal(t, orders.OrderStatusActive, ask.Status)
+	require.Empty(t, ask.InvalidReason)
+	require.Contains(t, mr.HGet("orders:active:ask", askHash), `"status":"active"`)
+}
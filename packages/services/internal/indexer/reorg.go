@@ -0,0 +1,392 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errReorgDuringPoll 表示轮询过程中链发生了变化，本轮放弃，下一轮从头检测重组
+var errReorgDuringPoll = errors.New("轮询期间检测到链重组，等待下一轮回滚")
+
+// IndexedBlock 记录最近处理过的区块哈希（最多 INDEXER_REORG_WINDOW 个），用于检测链重组
+type IndexedBlock struct {
+	BlockNumber uint64 `gorm:"primaryKey;autoIncrement:false"` // 区块号
+	BlockHash   string `gorm:"type:varchar(66)"`               // 区块哈希
+	ParentHash  string `gorm:"type:varchar(66)"`               // 父区块哈希
+	CreatedAt   time.Time
+}
+
+// TableName 设置 IndexedBlock 的表名
+func (IndexedBlock) TableName() string {
+	return "indexed_blocks"
+}
+
+// OrderStatusChange 记录索引器根据链上事件对订单状态的每次修改。
+// 链重组时按相反顺序恢复到修改前的状态。
+type OrderStatusChange struct {
+	ID              uint64             `gorm:"primaryKey"`
+	BlockNumber     uint64             `gorm:"index"`                                    // 触发修改的事件所在区块
+	TransactionHash string             `gorm:"type:varchar(66);index:idx_change_tx_log"` // 事件交易哈希
+	LogIndex        uint               `gorm:"index:idx_change_tx_log"`                  // 事件日志索引
+	OrderID         uint               `gorm:"index"`                                    // 订单 ID
+	PreviousStatus  orders.OrderStatus `gorm:"type:varchar(16)"`                         // 修改前状态
+	NewStatus       orders.OrderStatus `gorm:"type:varchar(16)"`                         // 修改后状态
+	PreviousReason  string             `gorm:"type:varchar(255)"`                        // 修改前的 invalid_reason
+	CreatedAt       time.Time
+}
+
+// TableName 设置 OrderStatusChange 的表名
+func (OrderStatusChange) TableName() string {
+	return "order_status_changes"
+}
+
+// transitionOrders 将满足条件且处于 from 状态的订单改为 to 状态，并记录每个订单的修改，
+// 以便链重组时回滚。返回被修改的订单。
+func transitionOrders(tx *gorm.DB, vLog types.Log, from, to orders.OrderStatus, query string, args ...any) ([]orders.Order, error) {
+	var changed []orders.Order
+	err := tx.Model(&changed).
+		Clauses(clause.Returning{}).
+		Where(query, args...).
+		Where("status = ?", from).
+		Updates(map[string]any{"status": to, "updated_at": time.Now()}).Error
+	if err != nil || len(changed) == 0 {
+		return nil, err
+	}
+
+	changes := make([]OrderStatusChange, 0, len(changed))
+	for _, ord := range changed {
+		changes = append(changes, OrderStatusChange{
+			BlockNumber:     vLog.BlockNumber,
+			TransactionHash: vLog.TxHash.Hex(),
+			LogIndex:        vLog.Index,
+			OrderID:         ord.ID,
+			PreviousStatus:  from,
+			NewStatus:       to,
+			PreviousReason:  ord.InvalidReason, // 只更新了状态，返回的仍是修改前的原因
+		})
+	}
+	if err := tx.Create(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// revertOrderChanges 按相反顺序撤销满足条件的订单状态修改（连同 invalid_reason）并删除修改记录，返回涉及的订单 ID。
+// 只有仍处于修改后状态的订单会被恢复，之后又被其他途径修改的订单保持不变。
+func revertOrderChanges(tx *gorm.DB, query string, args ...any) ([]uint, error) {
+	var changes []OrderStatusChange
+	if err := tx.Where(query, args...).Order("id DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	orderIDs := make([]uint, 0, len(changes))
+	for _, c := range changes {
+		err := tx.Model(&orders.Order{}).
+			Where("id = ? AND status = ?", c.OrderID, c.NewStatus).
+			Updates(map[string]any{"status": c.PreviousStatus, "invalid_reason": c.PreviousReason, "updated_at": time.Now()}).Error
+		if err != nil {
+			return nil, err
+		}
+		orderIDs = append(orderIDs, c.OrderID)
+	}
+	if err := tx.Where(query, args...).Delete(&OrderStatusChange{}).Error; err != nil {
+		return nil, err
+	}
+	return orderIDs, nil
+}
+
+// checkReorg 比较最近记录的区块哈希与当前链上的哈希。
+// 不一致时向前查找分叉点，回滚分叉点之后的事件、订单状态和检查点，随后的轮询从分叉点重新索引。
+func (s *Service) checkReorg(ctx context.Context) error {
+	if s.cfg.IndexerReorgWindow == 0 {
+		return nil
+	}
+
+	var recorded []IndexedBlock
+	if err := s.db.WithContext(ctx).Order("block_number DESC").Find(&recorded).Error; err != nil {
+		return err
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+
+	canonical, err := s.isCanonical(ctx, recorded[0])
+	if err != nil || canonical {
+		return err
+	}
+
+	// 从最新往前找到第一个仍在主链上的区块，分叉点是它的下一个区块
+	forkBlock := recorded[len(recorded)-1].BlockNumber
+	found := false
+	for _, b := range recorded[1:] {
+		canonical, err := s.isCanonical(ctx, b)
+		if err != nil {
+			return err
+		}
+		if canonical {
+			forkBlock = b.BlockNumber + 1
+			found = true
+			break
+		}
+	}
+	if !found {
+		logger.Error("链重组深度超过记录窗口，回滚到最早记录的区块", nil,
+			"最早记录区块", forkBlock,
+			"窗口", s.cfg.IndexerReorgWindow,
+		)
+	}
+
+	logger.Warn("检测到链重组，开始回滚",
+		"分叉区块", forkBlock,
+		"原最新区块", recorded[0].BlockNumber,
+		"原区块哈希", recorded[0].BlockHash,
+	)
+	return s.rollback(ctx, forkBlock)
+}
+
+// isCanonical 判断记录的区块是否仍在主链上
+func (s *Service) isCanonical(ctx context.Context, b IndexedBlock) (bool, error) {
+	header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(b.BlockNumber))
+	if errors.Is(err, ethereum.NotFound) {
+		// 新链比记录的更短
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("获取区块 %d 失败: %w", b.BlockNumber, err)
+	}
+	return header.Hash().Hex() == b.BlockHash, nil
+}
+
+// rollback 在一个事务中撤销 forkBlock 及之后区块产生的订单状态修改，删除这些区块的事件和区块记录
+// （NFT 转移删除后重建 token 持有者，删除之后刷新的资金快照并按 forkBlock-1 的状态重新读取），
+// 并把检查点退回到 forkBlock-1。恢复为 active 的订单重新写入 Redis 活跃订单簿。
+func (s *Service) rollback(ctx context.Context, forkBlock uint64) error {
+	checkpoint := forkBlock - 1
+	var reverted []uint
+	var deleted int64
+	var snapshots []PaymentTokenBalance
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reverted, err = revertOrderChanges(tx, "block_number >= ?", forkBlock); err != nil {
+			return err
+		}
+		result := tx.Where("block_number >= ?", forkBlock).Delete(&TradeEvent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if err := tx.Where("block_number >= ?", forkBlock).Delete(&CancelEvent{}).Error; err != nil {
+			return err
+		}
+		if _, err := deleteTransfers(tx, "block_number >= ?", forkBlock); err != nil {
+			return err
+		}
+		if err := tx.Where("block_number >= ?", forkBlock).Find(&snapshots).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("block_number >= ?", forkBlock).Delete(&PaymentTokenBalance{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("block_number >= ?", forkBlock).Delete(&IndexedBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&IndexerStatus{}).Where("id = ?", 1).
+			Update("last_processed_block", checkpoint).Error
+	})
+	if err != nil {
+		return fmt.Errorf("回滚到区块 %d 失败: %w", forkBlock, err)
+	}
+	s.lastProcessedBlock = checkpoint
+	s.recacheOrders(ctx, reverted)
+	s.restoreSnapshots(ctx, snapshots, checkpoint)
+
+	logger.Warn("链重组回滚完成",
+		"分叉区块", forkBlock,
+		"删除交易事件", deleted,
+		"恢复订单状态", len(reverted),
+		"新检查点", checkpoint,
+	)
+	return nil
+}
+
+// removeLog 撤销单个被链重组移除的事件（WebSocket 订阅推送 Removed=true 的日志），
+// 该事件刷新的资金快照按前一区块的状态重新读取
+func (s *Service) removeLog(ctx context.Context, vLog types.Log) error {
+	var reverted []uint
+	var snapshots []PaymentTokenBalance
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		reverted, err = revertOrderChanges(tx, "transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index)
+		if err != nil {
+			return err
+		}
+		result := tx.Where("transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index).Delete(&TradeEvent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Where("transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index).Delete(&CancelEvent{}).Error; err != nil {
+			return err
+		}
+		if _, err := deleteTransfers(tx, "transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index); err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index).Find(&snapshots).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index).Delete(&PaymentTokenBalance{}).Error; err != nil {
+			return err
+		}
+		logger.Warn("事件已被链重组移除，已撤销",
+			"交易哈希", vLog.TxHash.Hex(),
+			"日志索引", vLog.Index,
+			"区块", vLog.BlockNumber,
+			"删除交易事件", result.RowsAffected,
+			"恢复订单状态", len(reverted),
+		)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.recacheOrders(ctx, reverted)
+	if vLog.BlockNumber > 0 {
+		s.restoreSnapshots(ctx, snapshots, vLog.BlockNumber-1)
+	}
+	return nil
+}
+
+// canonicalBlocks 获取 [from, to] 中落在重组窗口内的区块头，并校验它们首尾相连、
+// 且第一个区块的父哈希与已记录的前一区块一致
+func (s *Service) canonicalBlocks(ctx context.Context, from, to, head uint64) ([]IndexedBlock, error) {
+	window := s.cfg.IndexerReorgWindow
+	if window == 0 {
+		return nil, nil
+	}
+	start := from
+	if head >= window && head-window+1 > start {
+		start = head - window + 1
+	}
+	if start > to {
+		return nil, nil
+	}
+
+	blocks := make([]IndexedBlock, 0, to-start+1)
+	for n := start; n <= to; n++ {
+		header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, fmt.Errorf("获取区块 %d 失败: %w", n, err)
+		}
+		block := IndexedBlock{
+			BlockNumber: n,
+			BlockHash:   header.Hash().Hex(),
+			ParentHash:  header.ParentHash.Hex(),
+		}
+		if len(blocks) > 0 && blocks[len(blocks)-1].BlockHash != block.ParentHash {
+			return nil, errReorgDuringPoll
+		}
+		blocks = append(blocks, block)
+	}
+
+	var prev IndexedBlock
+	err := s.db.WithContext(ctx).Where("block_number = ?", start-1).Limit(1).Find(&prev).Error
+	if err != nil {
+		return nil, err
+	}
+	if prev.BlockHash != "" && prev.BlockHash != blocks[0].ParentHash {
+		return nil, errReorgDuringPoll
+	}
+	return blocks, nil
+}
+
+// checkLogsCanonical 校验日志来自刚获取的区块，而不是轮询期间被替换的分叉
+func checkLogsCanonical(logs []types.Log, blocks []IndexedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	first := blocks[0].BlockNumber
+	for _, vLog := range logs {
+		if vLog.BlockNumber < first {
+			continue
+		}
+		i := vLog.BlockNumber - first
+		if i >= uint64(len(blocks)) || blocks[i].BlockHash != vLog.BlockHash.Hex() {
+			return errReorgDuringPoll
+		}
+	}
+	return nil
+}
+
+// eventRef 标识已存储事件对应的日志及其所在区块
+type eventRef struct {
+	TransactionHash string
+	LogIndex        uint
+	BlockNumber     uint64
+	BlockHash       string
+}
+
+// log 返回 removeLog 撤销该事件所需的日志字段
+func (r eventRef) log() types.Log {
+	return types.Log{
+		BlockNumber: r.BlockNumber,
+		TxHash:      common.HexToHash(r.TransactionHash),
+		Index:       r.LogIndex,
+	}
+}
+
+// removeOrphanedEvents 撤销刚获取的区块范围内、区块哈希与主链不一致的已存储事件
+// （交易事件、取消事件、NFT 转移和资金快照）。这些事件由 WebSocket 订阅在分叉上写入，
+// 重组时没有收到对应的 Removed 推送，随后处理的主链日志会重新写入仍然有效的事件。
+func (s *Service) removeOrphanedEvents(ctx context.Context, blocks []IndexedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	first, last := blocks[0].BlockNumber, blocks[len(blocks)-1].BlockNumber
+	removed := make(map[string]bool)
+	for _, model := range []any{&TradeEvent{}, &CancelEvent{}, &NFTTransfer{}, &PaymentTokenBalance{}} {
+		var refs []eventRef
+		err := s.db.WithContext(ctx).Model(model).
+			Select("transaction_hash, log_index, block_number, block_hash").
+			Where("block_number BETWEEN ? AND ? AND block_hash <> ?", first, last, "").
+			Find(&refs).Error
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			key := fmt.Sprintf("%s:%d", ref.TransactionHash, ref.LogIndex)
+			if ref.BlockHash == blocks[ref.BlockNumber-first].BlockHash || removed[key] {
+				continue
+			}
+			removed[key] = true
+			if err := s.removeLog(ctx, ref.log()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordBlocks 保存区块哈希并清理重组窗口之外的记录
+func (s *Service) recordBlocks(ctx context.Context, blocks []IndexedBlock, head uint64) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	db := s.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&blocks).Error
+	if err != nil {
+		return err
+	}
+	if head >= s.cfg.IndexerReorgWindow {
+		return db.Where("block_number <= ?", head-s.cfg.IndexerReorgWindow).Delete(&IndexedBlock{}).Error
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package indexer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// logEmitterCode is runtime bytecode standing in for the marketplace, the NFT and the payment token. The calldata may start
+// with a real executeTrade call; the log to emit is a trailer of topic count (3 or 4), topics
+// and data, and the last word is the trailer length.
+//
+//	PUSH1 0x20 CALLDATASIZE SUB CALLDATALOAD DUP1 CALLDATASIZE SUB PUSH1 0 CALLDATACOPY
+//	PUSH1 0 MLOAD PUSH1 3 EQ PUSH1 0x2c JUMPI
+//	PUSH1 0x80 MLOAD PUSH1 0x60 MLOAD PUSH1 0x40 MLOAD PUSH1 0x20 MLOAD
+//	PUSH1 0xc0 <trailer length> SUB PUSH1 0xa0 LOG4 STOP
+//	JUMPDEST PUSH1 0x60 MLOAD PUSH1 0x40 MLOAD PUSH1 0x20 MLOAD
+//	PUSH1 0xa0 <trailer length> SUB PUSH1 0x80 LOG3 STOP
+var logEmitterCode = common.FromHex("0x6020360335803603600037600051600314602c5760805160605160405160205160c060203603350360a0a4005b60605160405160205160a06020360335036080a300")
+
+var (
+	testMarketplace = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testMaker       = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	testTaker       = common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	testNFT         = common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	testPayment     = common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	testExpiry      = time.Now().Add(time.Hour).Truncate(time.Second)
+)
+
+// testOrder returns the signed fields of a token-7 order; side 0 is an ask, 1 a bid.
+func testOrder(maker common.Address, nonce, price int64, side uint8) orders.OrderFields {
+	return orders.OrderFields{
+		Maker:        maker,
+		NFT:          testNFT,
+		TokenID:      big.NewInt(7),
+		PaymentToken: testPayment,
+		Price:        big.NewInt(price),
+		Expiry:       big.NewInt(testExpiry.Unix()),
+		Nonce:        big.NewInt(nonce),
+		Side:         side,
+	}
+}
+
+var (
+	testAsk = testOrder(testMaker, 1, 1000, 0)
+	testBid = testOrder(testTaker, 1, 1000, 1)
+)
+
+// testChain is a simulated chain whose marketplace address emits events from calldata.
+type testChain struct {
+	t       *testing.T
+	backend *simulated.Backend
+	key     *ecdsa.PrivateKey
+	sender  common.Address
+}
+
+func newTestChain(t *testing.T) *testChain {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		sender:          {Balance: big.NewInt(1e18)},
+		testMarketplace: {Code: logEmitterCode, Balance: big.NewInt(0)},
+		testNFT:         {Code: logEmitterCode, Balance: big.NewInt(0)},
+		testPayment:     {Code: logEmitterCode, Balance: big.NewInt(0)},
+	})
+	t.Cleanup(func() { backend.Close() })
+	return &testChain{t: t, backend: backend, key: key, sender: sender}
+}
+
+// emitTrade sends executeTrade(ask, bid) to the marketplace address, which logs TradeExecuted
+// for it; the transaction is mined by the next commit.
+func (c *testChain) emitTrade(ask, bid orders.OrderFields) {
+	c.t.Helper()
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	require.NoError(c.t, err)
+	call, err := parsed.Pack("executeTrade", contractOrder(ask), contractOrder(bid), make([]byte, 65))
+	require.NoError(c.t, err)
+
+	event := parsed.Events["TradeExecuted"]
+	data, err := event.Inputs.NonIndexed().Pack(ask.TokenID, ask.PaymentToken, ask.Price, ask.Side, big.NewInt(25))
+	require.NoError(c.t, err)
+	c.emitLog(testMarketplace, call, []common.Hash{event.ID, addressTopic(ask.Maker), addressTopic(bid.Maker), addressTopic(ask.NFT)}, data)
+}
+
+// emitOrderCancelled logs OrderCancelled(maker, nonce) from the marketplace address.
+func (c *testChain) emitOrderCancelled(maker common.Address, nonce int64) {
+	c.t.Helper()
+	parsed, err := contracts.OeasyMarketplaceMetaData.GetAbi()
+	require.NoError(c.t, err)
+	call, err := parsed.Pack("cancelOrder", big.NewInt(nonce))
+	require.NoError(c.t, err)
+	topics := []common.Hash{parsed.Events["OrderCancelled"].ID, addressTopic(maker), common.BigToHash(big.NewInt(nonce))}
+	c.emitLog(testMarketplace, call, topics, nil)
+}
+
+// emitNFTTransfer logs an ERC-721 Transfer(from, to, tokenID) from the NFT address.
+func (c *testChain) emitNFTTransfer(from, to common.Address, tokenID int64) {
+	c.t.Helper()
+	parsed, err := contracts.OeasyNFTMetaData.GetAbi()
+	require.NoError(c.t, err)
+	call, err := parsed.Pack("transferFrom", from, to, big.NewInt(tokenID))
+	require.NoError(c.t, err)
+	topics := []common.Hash{parsed.Events["Transfer"].ID, addressTopic(from), addressTopic(to), common.BigToHash(big.NewInt(tokenID))}
+	c.emitLog(testNFT, call, topics, nil)
+}
+
+func contractOrder(f orders.OrderFields) contracts.IMarketplaceOrder {
+	return contracts.IMarketplaceOrder{
+		Maker: f.Maker, Nft: f.NFT, TokenId: f.TokenID, PaymentToken: f.PaymentToken,
+		Price: f.Price, Expiry: f.Expiry, Nonce: f.Nonce, Side: f.Side,
+	}
+}
+
+func addressTopic(addr common.Address) common.Hash {
+	return common.BytesToHash(addr.Bytes())
+}
+
+// emitLog sends call to the emitter at to, followed by the log trailer read by logEmitterCode.
+func (c *testChain) emitLog(to common.Address, call []byte, topics []common.Hash, data []byte) {
+	c.t.Helper()
+	trailer := common.BigToHash(big.NewInt(int64(len(topics)))).Bytes()
+	for _, topic := range topics {
+		trailer = append(trailer, topic.Bytes()...)
+	}
+	trailer = append(trailer, data...)
+	trailer = append(trailer, common.BigToHash(big.NewInt(int64(len(trailer)+32))).Bytes()...)
+	calldata := append(append([]byte{}, call...), trailer...)
+
+	ctx := context.Background()
+	client := c.backend.Client()
+	nonce, err := client.PendingNonceAt(ctx, c.sender)
+	require.NoError(c.t, err)
+	head, err := client.HeaderByNumber(ctx, nil)
+	require.NoError(c.t, err)
+	tip, err := client.SuggestGasTipCap(ctx)
+	require.NoError(c.t, err)
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+		Gas:       200000,
+		To:        &to,
+		Data:      calldata,
+	}), types.LatestSignerForChainID(big.NewInt(1337)), c.key)
+	require.NoError(c.t, err)
+	require.NoError(c.t, client.SendTransaction(ctx, tx))
+}
+
+func (c *testChain) blockHash(number uint64) common.Hash {
+	c.t.Helper()
+	header, err := c.backend.Client().HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	require.NoError(c.t, err)
+	return header.Hash()
+}
+
+// newTestService builds an indexer over the chain with a SQLite database holding testAsk and
+// testBid as active orders.
+func newTestService(t *testing.T, chain *testChain) *Service {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&orders.Order{}, &TradeEvent{}, &IndexerStatus{}, &IndexedBlock{}, &OrderStatusChange{}, &CancelEvent{},
+		&NFTTransfer{}, &TokenOwnership{}, &PaymentTokenBalance{}))
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.Create(&IndexerStatus{ID: 1}).Error)
+
+	client := chain.backend.Client()
+	filterer, err := contracts.NewOeasyMarketplaceFilterer(testMarketplace, client)
+	require.NoError(t, err)
+	nftFilterer, err := contracts.NewOeasyNFTFilterer(testNFT, client)
+	require.NoError(t, err)
+	paymentFilterer, err := contracts.NewMockUSDCFilterer(testPayment, client)
+	require.NoError(t, err)
+	svc := &Service{
+		cfg:                 &config.Config{ChainID: 1337, IndexerReorgWindow: 16},
+		client:              client,
+		db:                  db,
+		marketplaceAddr:     testMarketplace,
+		marketplaceFilterer: filterer,
+		nftFilterer:         nftFilterer,
+		collections:         map[common.Address]bool{testNFT: true},
+		paymentFilterer:     paymentFilterer,
+		paymentTokens:       map[common.Address]bool{testPayment: true},
+		typedData:           orders.NewTypedData(1337, testMarketplace),
+	}
+	svc.seedOrder(t, testAsk)
+	svc.seedOrder(t, testBid)
+	return svc
+}
+
+// seedOrder stores an active order with its real hash and returns the hash.
+func (s *Service) seedOrder(t *testing.T, f orders.OrderFields) string {
+	t.Helper()
+	hash, err := s.orderHash(contractOrder(f))
+	require.NoError(t, err)
+	side := "ask"
+	if f.Side == 1 {
+		side = "bid"
+	}
+	require.NoError(t, s.db.Create(&orders.Order{
+		Maker: strings.ToLower(f.Maker.Hex()), NFTAddress: strings.ToLower(f.NFT.Hex()), TokenID: f.TokenID.String(),
+		PaymentToken: strings.ToLower(f.PaymentToken.Hex()), Price: f.Price.String(), Expiry: testExpiry,
+		Nonce: f.Nonce.String(), Side: side, Status: orders.OrderStatusActive, Hash: hash,
+	}).Error)
+	return hash
+}
+
+func orderStatuses(t *testing.T, db *gorm.DB) []orders.OrderStatus {
+	t.Helper()
+	var rows []orders.Order
+	require.NoError(t, db.Order("id").Find(&rows).Error)
+	statuses := make([]orders.OrderStatus, 0, len(rows))
+	for _, row := range rows {
+		statuses = append(statuses, row.Status)
+	}
+	return statuses
+}
+
+// TestReconcile_RollsBackReorgedTrade indexes a trade, forks the chain from before its block
+// and checks that the trade and the fills are rolled back, then reindexed from the new fork.
+func TestReconcile_RollsBackReorgedTrade(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+	active := []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive}
+	filled := []orders.OrderStatus{orders.OrderStatusFilled, orders.OrderStatusFilled}
+
+	chain.backend.Commit() // 1
+	chain.emitTrade(testAsk, testBid)
+	chain.backend.Commit() // 2: trade
+	chain.backend.Commit() // 3
+
+	require.NoError(t, svc.reconcile(ctx))
+	require.Equal(t, uint64(3), svc.lastProcessedBlock)
+	var events []TradeEvent
+	require.NoError(t, svc.db.Find(&events).Error)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(2), events[0].BlockNumber)
+	require.Equal(t, chain.blockHash(2).Hex(), events[0].BlockHash)
+	require.Equal(t, filled, orderStatuses(t, svc.db))
+
+	// An idle poll on the same chain changes nothing.
+	require.NoError(t, svc.reconcile(ctx))
+	require.Equal(t, filled, orderStatuses(t, svc.db))
+
+	// Replace blocks 2-3 with a longer fork. The dropped trade goes back to the pool and is
+	// mined again in 2', in a block with a different hash.
+	oldHash := chain.blockHash(2)
+	require.NoError(t, chain.backend.Fork(chain.blockHash(1)))
+	chain.backend.Commit() // 2': trade
+	chain.backend.Commit() // 3'
+	chain.backend.Commit() // 4'
+	require.NotEqual(t, oldHash, chain.blockHash(2))
+
+	require.NoError(t, svc.checkReorg(ctx))
+	require.Equal(t, uint64(1), svc.lastProcessedBlock)
+	require.Equal(t, active, orderStatuses(t, svc.db))
+	var count int64
+	require.NoError(t, svc.db.Model(&TradeEvent{}).Count(&count).Error)
+	require.Zero(t, count)
+	require.NoError(t, svc.db.Model(&OrderStatusChange{}).Count(&count).Error)
+	require.Zero(t, count)
+
+	require.NoError(t, svc.reconcile(ctx))
+	require.Equal(t, uint64(4), svc.lastProcessedBlock)
+	events = nil
+	require.NoError(t, svc.db.Find(&events).Error)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(2), events[0].BlockNumber)
+	require.Equal(t, chain.blockHash(2).Hex(), events[0].BlockHash)
+	require.Equal(t, filled, orderStatuses(t, svc.db))
+
+	var blocks []IndexedBlock
+	require.NoError(t, svc.db.Order("block_number").Find(&blocks).Error)
+	require.Len(t, blocks, 4)
+	for i, b := range blocks {
+		require.Equal(t, chain.blockHash(uint64(i+1)).Hex(), b.BlockHash)
+	}
+}
+
+// TestProcessLog_RemovedLogIsUndone applies a trade log and then the same log flagged as
+// removed, as a WebSocket subscription delivers it after a reorg.
+func TestProcessLog_RemovedLogIsUndone(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+
+	chain.emitTrade(testAsk, testBid)
+	chain.backend.Commit()
+	logs, err := chain.backend.Client().FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{testMarketplace}})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	require.NoError(t, svc.processLog(ctx, logs[0], 1))
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusFilled, orders.OrderStatusFilled}, orderStatuses(t, svc.db))
+
+	removed := logs[0]
+	removed.Removed = true
+	require.NoError(t, svc.processLog(ctx, removed, 1))
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusActive, orders.OrderStatusActive}, orderStatuses(t, svc.db))
+	var count int64
+	require.NoError(t, svc.db.Model(&TradeEvent{}).Count(&count).Error)
+	require.Zero(t, count)
+}
+
+// TestReconcile_RemovesEventFromReplacedBlock stores a trade as the WebSocket path would from a
+// fork block that was later replaced without a removal notice, and checks that the next poll
+// undoes it and indexes the canonical log instead.
+func TestReconcile_RemovesEventFromReplacedBlock(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t)
+	svc := newTestService(t, chain)
+
+	chain.emitTrade(testAsk, testBid)
+	chain.backend.Commit() // 1: trade
+	logs, err := chain.backend.Client().FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{testMarketplace}})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	forked := logs[0]
+	forked.BlockHash = common.HexToHash("0x01")
+	require.NoError(t, svc.processLog(ctx, forked, 1))
+
+	require.NoError(t, svc.reconcile(ctx))
+	var events []TradeEvent
+	require.NoError(t, svc.db.Find(&events).Error)
+	require.Len(t, events, 1)
+	require.Equal(t, chain.blockHash(1).Hex(), events[0].BlockHash)
+	require.Equal(t, []orders.OrderStatus{orders.OrderStatusFilled, orders.OrderStatusFilled}, orderStatuses(t, svc.db))
+	var changes int64
+	require.NoError(t, svc.db.Model(&OrderStatusChange{}).Count(&changes).Error)
+	require.Equal(t, int64(2), changes)
+}
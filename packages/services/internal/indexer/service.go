@@ -7,14 +7,19 @@
 // - 安全网：周期性轮询捕获连接中断时遗漏的事件
 // - 去重机制：数据库唯一约束防止重复处理事件
 // - 自动恢复：WebSocket 失败时指数退避重连
+// - 重组检测：记录最近区块哈希，分叉时回滚事件、订单状态和检查点后重新索引
+// - 确认深度：未达到 INDEXER_CONFIRMATIONS 的事件记为 pending，订单暂为 settling，确认后改为 filled
 //
 // 监听的事件：
 // - TradeExecuted：交易链上结算时更新订单状态为 "filled"
-// - OrderCancelled：标记订单为已取消（如果添加链上取消功能）
+// - OrderCancelled：maker 在链上取消时更新订单状态为 "cancelled" 并移出 Redis 活跃订单簿
+// - NFT Transfer（INDEXER_NFT_COLLECTIONS）：维护 token_ownership，原持有者的活跃卖单标记为 "invalid"
+// - 支付代币 Transfer / Approval（INDEXER_PAYMENT_TOKENS）：刷新资金快照，无法支付的买单标记为 "unfunded"
 package indexer
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"strings"
 	"sync"
@@ -25,22 +30,42 @@ import (
 	"github.com/Oeasy-NFT/services/internal/logger"
 	"github.com/Oeasy-NFT/services/internal/orders"
 	"github.com/Oeasy-NFT/services/internal/postgres"
+	redisutil "github.com/Oeasy-NFT/services/internal/redis"
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ChainClient 是索引服务使用的以太坊客户端接口，*ethclient.Client 和模拟后端的客户端都满足
+type ChainClient interface {
+	bind.ContractFilterer
+	bind.ContractCaller
+	ethereum.BlockNumberReader
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
 // Service 监听区块链事件并更新数据库
 type Service struct {
 	cfg                 *config.Config
 	cancel              context.CancelFunc
 	wg                  sync.WaitGroup
-	client              *ethclient.Client
+	client              ChainClient
 	db                  *gorm.DB
+	redis               *redis.Client
 	marketplaceAddr     common.Address
 	marketplaceFilterer *contracts.OeasyMarketplaceFilterer
+	nftFilterer         *contracts.OeasyNFTFilterer // 只用于解析日志，与合约地址无关
+	collections         map[common.Address]bool     // 跟踪 Transfer 事件的 NFT 合约
+	paymentFilterer     *contracts.MockUSDCFilterer // 只用于解析日志，与合约地址无关
+	paymentTokens       map[common.Address]bool     // 跟踪 Transfer / Approval 事件的支付代币
+	typedData           apitypes.TypedData          // 用于重新计算成交订单的 EIP-712 哈希
 	lastProcessedBlock  uint64
 }
 
@@ -50,15 +75,21 @@ type TradeEvent struct {
 	TransactionHash string `gorm:"type:varchar(66);uniqueIndex:idx_tx_log"` // 交易哈希
 	LogIndex        uint   `gorm:"uniqueIndex:idx_tx_log"`                  // 日志索引（与交易哈希组成唯一键）
 	BlockNumber     uint64 `gorm:"index"`                                   // 区块号
+	BlockHash       string `gorm:"type:varchar(66)"`                        // 区块哈希
 	Maker           string `gorm:"type:varchar(66);index"`                  // 卖方地址
 	Taker           string `gorm:"type:varchar(66);index"`                  // 买方地址
+	MakerOrderHash  string `gorm:"type:varchar(66);index"`                  // maker 订单哈希（关联 orders.hash）
+	TakerOrderHash  string `gorm:"type:varchar(66);index"`                  // taker 订单哈希（关联 orders.hash）
 	NFTAddress      string `gorm:"type:varchar(66);index"`                  // NFT 合约地址
 	TokenID         string `gorm:"type:numeric"`                            // NFT Token ID
 	PaymentToken    string `gorm:"type:varchar(66)"`                        // 支付代币地址
 	Price           string `gorm:"type:numeric"`                            // 成交价格
 	Side            uint8  // 订单方向（0=Ask, 1=Bid）
 	Fee             string `gorm:"type:numeric"` // 平台手续费
-	CreatedAt       time.Time
+	// Status 为 pending（确认数不足）或 confirmed（已达到确认深度）
+	Status      TradeEventStatus `gorm:"type:varchar(16);index"`
+	ConfirmedAt *time.Time       // 达到确认深度的时间
+	CreatedAt   time.Time
 }
 
 // TableName 设置 TradeEvent 的表名
@@ -92,6 +123,12 @@ func NewService(cfg *config.Config) (*Service, error) {
 		return nil, err
 	}
 
+	// 连接 Redis（链上取消的订单需要移出活跃订单簿）
+	redisClient := redisutil.New(cfg.RedisAddr, cfg.RedisPassword)
+	if err := redisutil.Ping(context.Background(), redisClient); err != nil {
+		return nil, err
+	}
+
 	// 注意：不使用 AutoMigrate，表结构由 database/init.sql 管理
 	// 企业级最佳实践：数据库结构与代码分离，使用 SQL 脚本版本化管理
 
@@ -110,17 +147,44 @@ func NewService(cfg *config.Config) (*Service, error) {
 		return nil, err
 	}
 
+	nftFilterer, err := contracts.NewOeasyNFTFilterer(common.Address{}, client)
+	if err != nil {
+		return nil, err
+	}
+	paymentFilterer, err := contracts.NewMockUSDCFilterer(common.Address{}, client)
+	if err != nil {
+		return nil, err
+	}
+	collections := addressSet(cfg.IndexerNFTCollections)
+	paymentTokens := addressSet(cfg.IndexerPaymentTokens)
+
 	logger.Info("索引服务已初始化",
 		"marketplace地址", marketplaceAddr.Hex(),
+		"NFT合约数", len(collections),
+		"支付代币数", len(paymentTokens),
 		"最后处理区块", status.LastProcessedBlock,
+		"确认深度", cfg.IndexerConfirmations,
 	)
+	if cfg.IndexerConfirmations > cfg.IndexerReorgWindow {
+		// 确认前的重组必须能被检测到，否则 pending 事件可能永远不会被回滚
+		logger.Warn("确认深度大于重组检测窗口，超出窗口的重组无法回滚",
+			"确认深度", cfg.IndexerConfirmations,
+			"重组窗口", cfg.IndexerReorgWindow,
+		)
+	}
 
 	return &Service{
 		cfg:                 cfg,
 		client:              client,
 		db:                  db,
+		redis:               redisClient,
 		marketplaceAddr:     marketplaceAddr,
 		marketplaceFilterer: filterer,
+		nftFilterer:         nftFilterer,
+		collections:         collections,
+		paymentFilterer:     paymentFilterer,
+		paymentTokens:       paymentTokens,
+		typedData:           orders.NewTypedData(cfg.ChainID, marketplaceAddr),
 		lastProcessedBlock:  status.LastProcessedBlock,
 	}, nil
 }
@@ -167,7 +231,7 @@ func (s *Service) runWebSocketSubscriber(ctx context.Context) {
 
 		// Create event filter
 		query := ethereum.FilterQuery{
-			Addresses: []common.Address{s.marketplaceAddr},
+			Addresses: s.watchedAddresses(),
 		}
 
 		logs := make(chan types.Log)
@@ -199,7 +263,15 @@ func (s *Service) runWebSocketSubscriber(ctx context.Context) {
 				sub.Unsubscribe()
 				break eventLoop // Break inner loop, retry connection
 			case vLog := <-logs:
-				if err := s.processLog(ctx, vLog); err != nil {
+				head, err := s.client.BlockNumber(ctx)
+				if err != nil {
+					logger.Error("获取当前区块号失败，事件留给轮询处理", err,
+						"txHash", vLog.TxHash.Hex(),
+						"logIndex", vLog.Index,
+					)
+					continue
+				}
+				if err := s.processLog(ctx, vLog, head); err != nil {
 					logger.Error("failed to process log", err,
 						"txHash", vLog.TxHash.Hex(),
 						"logIndex", vLog.Index,
@@ -323,12 +395,24 @@ func (s *Service) runReconciliationPoller(ctx context.Context) {
 func (s *Service) reconcile(ctx context.Context) error {
 	startTime := time.Now()
 
+	// 先确认已索引的区块仍在主链上，发生分叉时回滚到分叉点
+	if err := s.checkReorg(ctx); err != nil {
+		logger.Error("链重组检测失败", err)
+		return err
+	}
+
 	currentBlock, err := s.client.BlockNumber(ctx)
 	if err != nil {
 		logger.Error("获取当前区块号失败", err)
 		return err
 	}
 
+	// 之前记录为 pending 的事件达到确认深度后提升为 confirmed
+	if err := s.confirmEvents(ctx, currentBlock); err != nil {
+		logger.Error("确认交易事件失败", err, "currentBlock", currentBlock)
+		return err
+	}
+
 	fromBlock := s.lastProcessedBlock + 1
 	toBlock := currentBlock
 
@@ -378,7 +462,7 @@ func (s *Service) reconcile(ctx context.Context) error {
 		query := ethereum.FilterQuery{
 			FromBlock: big.NewInt(int64(from)),
 			ToBlock:   big.NewInt(int64(to)),
-			Addresses: []common.Address{s.marketplaceAddr},
+			Addresses: s.watchedAddresses(),
 		}
 
 		logs, err := s.client.FilterLogs(ctx, query)
@@ -393,6 +477,21 @@ func (s *Service) reconcile(ctx context.Context) error {
 			return err
 		}
 
+		// 重组窗口内的区块记录哈希，并确认日志来自当前主链
+		blocks, err := s.canonicalBlocks(ctx, from, to, currentBlock)
+		if err == nil {
+			err = checkLogsCanonical(logs, blocks)
+		}
+		if err != nil {
+			logger.Warn("区块哈希校验未通过，本轮停止", "fromBlock", from, "toBlock", to, "error", err.Error())
+			return err
+		}
+		// 撤销 WebSocket 在分叉上写入、哈希与刚获取的区块不一致的事件
+		if err := s.removeOrphanedEvents(ctx, blocks); err != nil {
+			logger.Error("撤销分叉上的事件失败", err, "fromBlock", from, "toBlock", to)
+			return err
+		}
+
 		queryDuration := time.Since(batchStartTime)
 		logger.Info("FilterLogs 查询完成",
 			"事件数量", len(logs),
@@ -405,7 +504,7 @@ func (s *Service) reconcile(ctx context.Context) error {
 		errorCount := 0
 
 		for i, vLog := range logs {
-			if err := s.processLog(ctx, vLog); err != nil {
+			if err := s.processLog(ctx, vLog, currentBlock); err != nil {
 				errorCount++
 				logger.Error("事件处理失败", err,
 					"批次索引", i,
@@ -429,6 +528,11 @@ func (s *Service) reconcile(ctx context.Context) error {
 			)
 		}
 
+		if err := s.recordBlocks(ctx, blocks, currentBlock); err != nil {
+			logger.Error("记录区块哈希失败", err, "blockNumber", to)
+			return err
+		}
+
 		// 【企业级改进】：渐进式更新检查点，确保即使后续批次失败也不会丢失进度
 		if err := s.updateLastProcessedBlock(ctx, to); err != nil {
 			logger.Error("更新检查点失败", err, "blockNumber", to)
@@ -453,14 +557,38 @@ func (s *Service) reconcile(ctx context.Context) error {
 	return nil
 }
 
-// processLog 解析并存储单个事件日志。
+// processLog 解析并存储单个事件日志，head 为当前链头，用于判断事件是否已达到确认深度。
 // 通过数据库在 (tx_hash, log_index) 上的唯一约束处理去重。
-func (s *Service) processLog(ctx context.Context, vLog types.Log) error {
-	// 使用生成的合约绑定解析 TradeExecuted 事件
+// 被链重组移除的日志（Removed=true）会撤销之前的处理结果。
+func (s *Service) processLog(ctx context.Context, vLog types.Log, head uint64) error {
+	if vLog.Removed {
+		return s.removeLog(ctx, vLog)
+	}
+	if vLog.BlockHash == (common.Hash{}) {
+		// 尚未打包进区块的日志（pending 区块）不处理，打包后由订阅或轮询重新送达
+		return nil
+	}
+
+	if s.collections[vLog.Address] {
+		transfer, err := s.nftFilterer.ParseTransfer(vLog)
+		if err != nil {
+			// Approval 等其他 NFT 事件不影响持有者
+			return nil
+		}
+		return s.processNFTTransfer(ctx, vLog, transfer)
+	}
+	if s.paymentTokens[vLog.Address] {
+		return s.processPaymentTokenLog(ctx, vLog)
+	}
+
+	// 使用生成的合约绑定解析事件（事件签名不匹配时解析返回错误）
+	if cancelled, err := s.marketplaceFilterer.ParseOrderCancelled(vLog); err == nil {
+		return s.processOrderCancelled(ctx, vLog, cancelled, head)
+	}
 	event, err := s.marketplaceFilterer.ParseTradeExecuted(vLog)
 	if err != nil {
-		// 如果不是 TradeExecuted 事件则跳过（可能是 OrderCancelled 或其他事件）
-		logger.Info("跳过非TradeExecuted事件",
+		// 其他事件（如 FeeUpdated、Paused）与订单簿无关，跳过
+		logger.Info("跳过与订单无关的事件",
 			"交易哈希", vLog.TxHash.Hex(),
 			"日志索引", vLog.Index,
 		)
@@ -478,55 +606,78 @@ func (s *Service) processLog(ctx context.Context, vLog types.Log) error {
 		"price", event.Price.String(),
 	)
 
-	// 将事件存储到数据库
-	// 【修复】：统一使用小写地址格式，与订单表保持一致
-	tradeEvent := TradeEvent{
-		TransactionHash: vLog.TxHash.Hex(),
-		LogIndex:        uint(vLog.Index),
-		BlockNumber:     vLog.BlockNumber,
-		Maker:           strings.ToLower(event.Maker.Hex()),
-		Taker:           strings.ToLower(event.Taker.Hex()),
-		NFTAddress:      strings.ToLower(event.Nft.Hex()),
-		TokenID:         event.TokenId.String(),
-		PaymentToken:    strings.ToLower(event.PaymentToken.Hex()),
-		Price:           event.Price.String(),
-		Side:            event.Side,
-		Fee:             event.Fee.String(),
-		CreatedAt:       time.Now(),
-	}
-
-	// 插入或忽略（如果由于唯一约束已存在）
-	result := s.db.WithContext(ctx).Create(&tradeEvent)
-	eventAlreadyExists := false
+	// 已记录的事件以数据库为准（状态由 confirmEvents 推进，订单哈希无需重新解码）
+	var tradeEvent TradeEvent
+	err = s.db.WithContext(ctx).
+		Where("transaction_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index).
+		Limit(1).Find(&tradeEvent).Error
+	if err != nil {
+		return err
+	}
+	eventAlreadyExists := tradeEvent.ID != 0
 
-	if result.Error != nil {
-		// 检查是否是重复键错误（这是预期的，可以安全忽略）
-		errMsg := result.Error.Error()
-		if strings.Contains(errMsg, "UNIQUE constraint failed") ||
-			strings.Contains(errMsg, "duplicate key") ||
-			strings.Contains(errMsg, "uk_trade_events_tx_log") {
-			logger.Info("事件已存在，继续更新订单状态",
+	if eventAlreadyExists {
+		logger.Info("事件已存在，继续更新订单状态",
+			"交易哈希", vLog.TxHash.Hex(),
+			"日志索引", vLog.Index,
+			"状态", tradeEvent.Status,
+		)
+	} else {
+		// 解码交易输入得到两张订单的哈希，只有这两张订单会被标记为成交
+		makerHash, takerHash, err := s.tradeOrderHashes(ctx, vLog, event)
+		if err != nil && !errors.Is(err, errNotExecuteTrade) {
+			return err
+		}
+		if err != nil {
+			// 无法关联订单时只记录事件；未关联的订单会在撮合前的可成交性检查中因 nonce 已消费被标记为 invalid
+			logger.Warn("无法从交易输入关联订单，仅记录成交事件",
 				"交易哈希", vLog.TxHash.Hex(),
-				"日志索引", vLog.Index,
+				"error", err.Error(),
 			)
-			eventAlreadyExists = true
-			// 不要 return！继续执行订单更新
-		} else {
-			// 其他错误才返回
-			return result.Error
 		}
-	}
 
-	if !eventAlreadyExists {
+		// 将事件存储到数据库
+		// 【修复】：统一使用小写地址格式，与订单表保持一致
+		tradeEvent = TradeEvent{
+			TransactionHash: vLog.TxHash.Hex(),
+			LogIndex:        uint(vLog.Index),
+			BlockNumber:     vLog.BlockNumber,
+			BlockHash:       vLog.BlockHash.Hex(),
+			Maker:           strings.ToLower(event.Maker.Hex()),
+			Taker:           strings.ToLower(event.Taker.Hex()),
+			MakerOrderHash:  makerHash,
+			TakerOrderHash:  takerHash,
+			NFTAddress:      strings.ToLower(event.Nft.Hex()),
+			TokenID:         event.TokenId.String(),
+			PaymentToken:    strings.ToLower(event.PaymentToken.Hex()),
+			Price:           event.Price.String(),
+			Side:            event.Side,
+			Fee:             event.Fee.String(),
+			Status:          s.eventStatusAt(vLog.BlockNumber, head),
+			CreatedAt:       time.Now(),
+		}
+		if tradeEvent.Status == TradeEventConfirmed {
+			now := time.Now()
+			tradeEvent.ConfirmedAt = &now
+		}
+
+		// 插入或忽略（WebSocket 与轮询并发处理同一事件时由唯一约束去重）
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&tradeEvent)
+		if result.Error != nil {
+			return result.Error
+		}
 		logger.Info("TradeExecuted事件存储成功",
 			"交易哈希", vLog.TxHash.Hex(),
 			"事件ID", tradeEvent.ID,
+			"状态", tradeEvent.Status,
+			"makerOrderHash", makerHash,
+			"takerOrderHash", takerHash,
 		)
 	}
 
-	// 将买卖双方的订单状态更新为 "filled"
+	// 将买卖双方的订单状态更新为 "settling"（未确认）或 "filled"（已确认）
 	// 重要：即使事件已存在，也要尝试更新订单状态（可能之前失败了）
-	if err := s.updateOrdersToFilled(ctx, event); err != nil {
+	if err := s.updateOrdersSettled(ctx, vLog, tradeEvent, settledOrderStatus(tradeEvent.Status)); err != nil {
 		logger.Error("更新订单状态失败", err,
 			"交易哈希", vLog.TxHash.Hex(),
 		)
@@ -540,51 +691,57 @@ func (s *Service) processLog(ctx context.Context, vLog types.Log) error {
 	return nil
 }
 
-// updateOrdersToFilled 在交易执行后将订单标记为已成交
-func (s *Service) updateOrdersToFilled(ctx context.Context, event *contracts.OeasyMarketplaceTradeExecuted) error {
-	// 【关键修复】：将地址转换为小写以匹配数据库中的存储格式
-	// PostgreSQL 字符串比较区分大小写，event.Maker.Hex() 返回的是带大小写的地址
-	// 但数据库中存储的是全小写地址，导致 WHERE 条件匹配失败
-
-	// 更新 maker 的订单
-	makerResult := s.db.WithContext(ctx).Model(&orders.Order{}).
-		Where("maker = ? AND nft_address = ? AND token_id = ? AND status = ?",
-			strings.ToLower(event.Maker.Hex()), // 转换为小写
-			strings.ToLower(event.Nft.Hex()),   // 转换为小写
-			event.TokenId.String(),
-			orders.OrderStatusActive,
-		).
-		Update("status", orders.OrderStatusFilled)
-
-	if makerResult.Error != nil {
-		return makerResult.Error
-	}
-
-	logger.Info("已更新maker订单为已成交",
-		"maker地址", event.Maker.Hex(),
-		"更新数量", makerResult.RowsAffected,
-	)
+// updateOrdersSettled 在交易执行后将事件关联的两张活跃订单标记为 to（settling 或 filled）。
+// 只按订单哈希精确匹配，同一 NFT 上的其他订单（如价格不同的旧买单）不受影响。
+// 每个被修改的订单都记录在 order_status_changes 中，链重组时据此回滚。
+func (s *Service) updateOrdersSettled(ctx context.Context, vLog types.Log, event TradeEvent, to orders.OrderStatus) error {
+	hashes := make([]string, 0, 2)
+	for _, h := range []string{event.MakerOrderHash, event.TakerOrderHash} {
+		if h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
 
-	// 更新 taker 的订单
-	takerResult := s.db.WithContext(ctx).Model(&orders.Order{}).
-		Where("maker = ? AND nft_address = ? AND token_id = ? AND status = ?",
-			strings.ToLower(event.Taker.Hex()), // 转换为小写
-			strings.ToLower(event.Nft.Hex()),   // 转换为小写
-			event.TokenId.String(),
-			orders.OrderStatusActive,
-		).
-		Update("status", orders.OrderStatusFilled)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updated, err := transitionOrders(tx, vLog, orders.OrderStatusActive, to, "hash IN ?", hashes)
+		if err != nil {
+			return err
+		}
+		logger.Info("已更新成交订单状态",
+			"makerOrderHash", event.MakerOrderHash,
+			"takerOrderHash", event.TakerOrderHash,
+			"新状态", to,
+			"更新数量", len(updated),
+		)
+		return nil
+	})
+}
 
-	if takerResult.Error != nil {
-		return takerResult.Error
+// watchedAddresses 返回需要获取日志的合约地址：marketplace、配置的 NFT 合约和支付代币
+func (s *Service) watchedAddresses() []common.Address {
+	addrs := make([]common.Address, 0, 1+len(s.collections)+len(s.paymentTokens))
+	addrs = append(addrs, s.marketplaceAddr)
+	for addr := range s.collections {
+		addrs = append(addrs, addr)
 	}
+	for addr := range s.paymentTokens {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
 
-	logger.Info("已更新taker订单为已成交",
-		"taker地址", event.Taker.Hex(),
-		"更新数量", takerResult.RowsAffected,
-	)
-
-	return nil
+// addressSet 解析配置中的合约地址列表
+func addressSet(addrs []string) map[common.Address]bool {
+	set := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			set[common.HexToAddress(addr)] = true
+		}
+	}
+	return set
 }
 
 // updateLastProcessedBlock 持久化和解的检查点
@@ -601,8 +758,11 @@ func (s *Service) Shutdown() {
 		s.cancel()
 	}
 	s.wg.Wait()
-	if s.client != nil {
-		s.client.Close()
+	if closer, ok := s.client.(interface{ Close() }); ok {
+		closer.Close()
+	}
+	if s.redis != nil {
+		_ = s.redis.Close()
 	}
 	logger.Info("索引服务已关闭")
 }
@@ -0,0 +1,259 @@
+package matching
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// engineStats 记录撮合循环的运行指标，供管理接口查询
+type engineStats struct {
+	mu                sync.Mutex
+	cycles            uint64
+	lastCycleAt       time.Time
+	lastCycleDuration time.Duration
+	lastCycleError    string
+	lastMatches       int
+	totalMatches      uint64
+	submitted         uint64
+	failures          uint64
+	invalidated       uint64
+}
+
+// StatusResponse 是 /status 接口的响应结构
+type StatusResponse struct {
+	Paused            bool      `json:"paused"`
+	Cycles            uint64    `json:"cycles"`
+	LastCycleAt       time.Time `json:"lastCycleAt"`
+	LastCycleDuration string    `json:"lastCycleDuration"`
+	LastCycleError    string    `json:"lastCycleError,omitempty"`
+	LastMatches       int       `json:"lastMatches"`
+	TotalMatches      uint64    `json:"totalMatches"`
+	Submitted         uint64    `json:"submitted"`
+	Failures          uint64    `json:"failures"`
+	Invalidated       uint64    `json:"invalidated"`
+	BackingOff        int       `json:"backingOff"`
+	DeadLetters       int       `json:"deadLetters"`
+}
+
+// BookResponse 是 /book/:nft/:tokenId 接口的响应结构（引擎视角的内存订单簿）
+type BookResponse struct {
+	NFTAddress string  `json:"nftAddress"`
+	TokenID    string  `json:"tokenId"`
+	Asks       []Order `json:"asks"`
+	Bids       []Order `json:"bids"`
+}
+
+// recordCycle 记录一次撮合循环的结果
+func (s *engineStats) recordCycle(start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cycles++
+	s.lastCycleAt = start
+	s.lastCycleDuration = time.Since(start)
+	s.lastCycleError = ""
+	if err != nil {
+		s.lastCycleError = err.Error()
+	}
+}
+
+// recordMatches 记录本轮发现的匹配数量
+func (s *engineStats) recordMatches(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastMatches = n
+	s.totalMatches += uint64(n)
+}
+
+// recordSubmission 记录一次提交结果
+func (s *engineStats) recordSubmission(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures++
+		return
+	}
+	s.submitted++
+}
+
+// recordInvalidated 记录一次链上不可成交导致的订单失效
+func (s *engineStats) recordInvalidated() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invalidated++
+}
+
+// registerRoutes 注册撮合引擎管理接口
+func (e *Engine) registerRoutes() {
+	e.engine.GET("/health", e.handleHealth)
+	e.engine.GET("/status", e.handleStatus)
+	e.engine.GET("/book/:nft/:tokenId", e.handleBook)
+	e.engine.GET("/deadletters", e.handleListDeadLetters)
+
+	// 会改变引擎状态的接口需要管理令牌
+	admin := e.engine.Group("/", e.requireAdminToken)
+	admin.POST("/pause", e.handlePause)
+	admin.POST("/resume", e.handleResume)
+	admin.POST("/deadletters/:key/retry", e.handleRetryDeadLetter)
+	admin.DELETE("/deadletters/:key", e.handleDropDeadLetter)
+}
+
+// requireAdminToken 校验 Authorization: Bearer <MATCHING_ADMIN_TOKEN>，未配置令牌时拒绝所有请求
+func (e *Engine) requireAdminToken(c *gin.Context) {
+	token := e.cfg.MatchingAdminToken
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API disabled"})
+		return
+	}
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		logger.Warn("管理接口认证失败", "path", c.FullPath(), "client_ip", c.ClientIP())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	c.Next()
+}
+
+func (e *Engine) handleHealth(c *gin.Context) {
+	if err := e.redisClient.Ping(c.Request.Context()).Err(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "matching-engine"})
+}
+
+func (e *Engine) handleStatus(c *gin.Context) {
+	deadLetters, err := e.redisClient.HLen(c.Request.Context(), deadLetterKey).Result()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read dead letters"})
+		return
+	}
+
+	e.failures.mu.Lock()
+	backingOff := len(e.failures.pairs)
+	e.failures.mu.Unlock()
+
+	e.stats.mu.Lock()
+	resp := StatusResponse{
+		Paused:            e.paused.Load(),
+		Cycles:            e.stats.cycles,
+		LastCycleAt:       e.stats.lastCycleAt,
+		LastCycleDuration: e.stats.lastCycleDuration.String(),
+		LastCycleError:    e.stats.lastCycleError,
+		LastMatches:       e.stats.lastMatches,
+		TotalMatches:      e.stats.totalMatches,
+		Submitted:         e.stats.submitted,
+		Failures:          e.stats.failures,
+		Invalidated:       e.stats.invalidated,
+		BackingOff:        backingOff,
+		DeadLetters:       int(deadLetters),
+	}
+	e.stats.mu.Unlock()
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (e *Engine) handleBook(c *gin.Context) {
+	nft := c.Param("nft")
+	tokenID := c.Param("tokenId")
+	ctx := c.Request.Context()
+
+	asks, err := e.fetchOrders(ctx, "ask")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read order book"})
+		return
+	}
+	bids, err := e.fetchOrders(ctx, "bid")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read order book"})
+		return
+	}
+
+	resp := BookResponse{
+		NFTAddress: strings.ToLower(nft),
+		TokenID:    tokenID,
+		Asks:       filterBook(asks, nft, tokenID),
+		Bids:       filterBook(bids, nft, tokenID),
+	}
+
+	// 卖单按价格升序，买单按价格降序，与撮合优先级一致
+	sort.SliceStable(resp.Asks, func(i, j int) bool { return comparePrice(resp.Asks[i], resp.Asks[j]) < 0 })
+	sort.SliceStable(resp.Bids, func(i, j int) bool { return comparePrice(resp.Bids[i], resp.Bids[j]) > 0 })
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (e *Engine) handlePause(c *gin.Context) {
+	if !e.paused.Swap(true) {
+		logger.Warn("撮合引擎已暂停", "client_ip", c.ClientIP())
+	}
+	c.JSON(http.StatusOK, gin.H{"paused": true})
+}
+
+func (e *Engine) handleResume(c *gin.Context) {
+	if e.paused.Swap(false) {
+		logger.Info("撮合引擎已恢复", "client_ip", c.ClientIP())
+	}
+	c.JSON(http.StatusOK, gin.H{"paused": false})
+}
+
+func (e *Engine) handleListDeadLetters(c *gin.Context) {
+	dls, err := e.DeadLetters(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list dead letters"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deadLetters": dls})
+}
+
+func (e *Engine) handleRetryDeadLetter(c *gin.Context) {
+	e.respondDeadLetterAction(c, e.RetryDeadLetter(c.Request.Context(), c.Param("key")), "retry scheduled")
+}
+
+func (e *Engine) handleDropDeadLetter(c *gin.Context) {
+	e.respondDeadLetterAction(c, e.DropDeadLetter(c.Request.Context(), c.Param("key")), "dropped")
+}
+
+func (e *Engine) respondDeadLetterAction(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		logger.Info("死信订单对已处理", "pairKey", c.Param("key"), "action", message)
+		c.JSON(http.StatusOK, gin.H{"message": message})
+	}
+}
+
+// filterBook 筛选指定 NFT 和 tokenId 的订单
+func filterBook(orders []Order, nft, tokenID string) []Order {
+	result := make([]Order, 0)
+	for _, ord := range orders {
+		if strings.EqualFold(ord.NFTAddress, nft) && ord.TokenID.String() == tokenID {
+			result = append(result, ord)
+		}
+	}
+	return result
+}
+
+// comparePrice 比较两个订单的价格，无法解析的价格视为 0
+func comparePrice(a, b Order) int {
+	pa, ok := new(big.Int).SetString(a.Price.String(), 10)
+	if !ok {
+		pa = new(big.Int)
+	}
+	pb, ok := new(big.Int).SetString(b.Price.String(), 10)
+	if !ok {
+		pb = new(big.Int)
+	}
+	return pa.Cmp(pb)
+}
@@ -0,0 +1,251 @@
+package matching
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// deadLetterKey 是 Redis 中死信订单对的哈希表键，field 为订单对 key
+const deadLetterKey = "matching:deadletter"
+
+// 失败处理的默认参数（配置为零值时使用）
+const (
+	defaultMaxFailures = 5
+	defaultBackoffBase = 5 * time.Second
+	defaultBackoffMax  = 5 * time.Minute
+)
+
+// ErrDeadLetterNotFound 表示指定的死信记录不存在
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter 记录连续提交失败次数超限、已停止重试的订单对
+type DeadLetter struct {
+	PairKey       string    `json:"pairKey"`
+	Ask           Order     `json:"ask"`
+	Bid           Order     `json:"bid"`
+	Failures      int       `json:"failures"`
+	LastError     string    `json:"lastError"`
+	FirstFailedAt time.Time `json:"firstFailedAt"`
+	LastFailedAt  time.Time `json:"lastFailedAt"`
+}
+
+// pairFailure 记录单个订单对的失败状态
+type pairFailure struct {
+	failures      int
+	simulations   int // 仅模拟执行的次数，不计入失败
+	lastError     string
+	firstFailedAt time.Time
+	nextAttempt   time.Time
+}
+
+// failureTracker 按订单对跟踪提交失败次数，并计算指数退避的下次重试时间
+type failureTracker struct {
+	mu          sync.Mutex
+	pairs       map[string]*pairFailure
+	maxFailures int
+	base        time.Duration
+	max         time.Duration
+	now         func() time.Time
+}
+
+// newFailureTracker 创建失败跟踪器，非法参数回退为默认值
+func newFailureTracker(maxFailures int, base, max time.Duration) *failureTracker {
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFailures
+	}
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	if max < base {
+		max = defaultBackoffMax
+		if max < base {
+			max = base
+		}
+	}
+	return &failureTracker{
+		pairs:       make(map[string]*pairFailure),
+		maxFailures: maxFailures,
+		base:        base,
+		max:         max,
+		now:         time.Now,
+	}
+}
+
+// pairKey 由 ask 和 bid 的订单哈希组成订单对的唯一标识
+func pairKey(match MatchPair) string {
+	return match.Ask.Hash + ":" + match.Bid.Hash
+}
+
+// ready 判断订单对是否已过退避期，可以再次提交
+func (t *failureTracker) ready(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pf, ok := t.pairs[key]
+	if !ok {
+		return true
+	}
+	return !t.now().Before(pf.nextAttempt)
+}
+
+// recordFailure 记录一次失败并安排下次重试，返回当前失败状态的副本。
+// 退避时间为 base * 2^(failures-1)，上限为 max。
+func (t *failureTracker) recordFailure(key string, err error) pairFailure {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	pf := t.entry(key)
+	if pf.failures == 0 {
+		pf.firstFailedAt = now
+	}
+	pf.failures++
+	pf.lastError = err.Error()
+	pf.nextAttempt = now.Add(t.delay(pf.failures))
+
+	return *pf
+}
+
+// recordSimulation 记录执行服务仅模拟了订单对（未广播），按与失败相同的指数退避推迟下次提交，
+// 避免每轮撮合重复模拟同一订单对。模拟次数不计入失败，订单对不会因此移入死信列表。
+func (t *failureTracker) recordSimulation(key string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pf := t.entry(key)
+	pf.simulations++
+	pf.nextAttempt = t.now().Add(t.delay(pf.simulations))
+	return pf.nextAttempt
+}
+
+// entry 返回订单对的失败状态，不存在时创建（调用方持有锁）
+func (t *failureTracker) entry(key string) *pairFailure {
+	pf, ok := t.pairs[key]
+	if !ok {
+		pf = &pairFailure{}
+		t.pairs[key] = pf
+	}
+	return pf
+}
+
+// delay 返回第 n 次退避的等待时间：base * 2^(n-1)，上限为 max
+func (t *failureTracker) delay(n int) time.Duration {
+	if shift := n - 1; shift < 32 {
+		if d := t.base << uint(shift); d > 0 && d < t.max {
+			return d
+		}
+	}
+	return t.max
+}
+
+// exhausted 判断失败次数是否已达到死信阈值
+func (t *failureTracker) exhausted(pf pairFailure) bool {
+	return pf.failures >= t.maxFailures
+}
+
+// reset 清除订单对的失败记录（提交成功或人工重试时调用）
+func (t *failureTracker) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pairs, key)
+}
+
+// deadLetter 将订单对写入 Redis 死信列表，并清除内存中的失败记录
+func (e *Engine) deadLetter(ctx context.Context, key string, match MatchPair, pf pairFailure) error {
+	entry := DeadLetter{
+		PairKey:       key,
+		Ask:           match.Ask,
+		Bid:           match.Bid,
+		Failures:      pf.failures,
+		LastError:     pf.lastError,
+		FirstFailedAt: pf.firstFailedAt,
+		LastFailedAt:  e.failures.now(),
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := e.redisClient.HSet(ctx, deadLetterKey, key, payload).Err(); err != nil {
+		return err
+	}
+	e.failures.reset(key)
+	return nil
+}
+
+// deadLetterKeys 返回当前所有死信订单对的 key 集合，用于撮合时跳过
+func (e *Engine) deadLetterKeys(ctx context.Context) (map[string]struct{}, error) {
+	keys, err := e.redisClient.HKeys(ctx, deadLetterKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return set, nil
+}
+
+// DeadLetters 列出所有死信订单对，按最后失败时间倒序排列
+func (e *Engine) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	entries, err := e.redisClient.HGetAll(ctx, deadLetterKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DeadLetter, 0, len(entries))
+	for _, payload := range entries {
+		var dl DeadLetter
+		if err := json.Unmarshal([]byte(payload), &dl); err != nil {
+			continue
+		}
+		result = append(result, dl)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastFailedAt.After(result[j].LastFailedAt)
+	})
+	return result, nil
+}
+
+// RetryDeadLetter 将订单对移出死信列表并清零失败计数，下一轮撮合会重新提交
+func (e *Engine) RetryDeadLetter(ctx context.Context, key string) error {
+	removed, err := e.redisClient.HDel(ctx, deadLetterKey, key).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrDeadLetterNotFound
+	}
+	e.failures.reset(key)
+	return nil
+}
+
+// DropDeadLetter 放弃订单对：删除死信记录，并将双方订单从活跃订单簿缓存中移除
+func (e *Engine) DropDeadLetter(ctx context.Context, key string) error {
+	payload, err := e.redisClient.HGet(ctx, deadLetterKey, key).Result()
+	if err == redis.Nil {
+		return ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var dl DeadLetter
+	if err := json.Unmarshal([]byte(payload), &dl); err != nil {
+		return err
+	}
+
+	pipe := e.redisClient.TxPipeline()
+	pipe.HDel(ctx, "orders:active:ask", dl.Ask.Hash)
+	pipe.HDel(ctx, "orders:active:bid", dl.Bid.Hash)
+	pipe.HDel(ctx, deadLetterKey, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	e.failures.reset(key)
+	return nil
+}
@@ -6,22 +6,30 @@
 // - 从 Redis 读取活跃订单（由订单服务发布）
 // - 实现价格-时间优先撮合算法
 // - 发现匹配时通知执行服务
-// - 执行成功后更新订单状态
+// - 跟进执行任务：广播后将订单移出缓存，最终失败的计入退避或移入死信列表
+// - 提交失败的订单对按指数退避重试，失败次数超限后移入死信列表
+// - 提交前复核链上状态（所有权、授权、余额、nonce），不可成交的订单标记为无效
+// - 在 MatchingServicePort 上提供管理接口（状态、订单簿快照、暂停/恢复）
 package matching
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/big"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/hmacauth"
 	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/Oeasy-NFT/services/internal/postgres"
 	redisutil "github.com/Oeasy-NFT/services/internal/redis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -99,8 +107,8 @@ func (ct *CustomTime) UnmarshalJSON(b []byte) error {
 
 // MatchPair 表示成功匹配的 ask 和 bid 订单对
 type MatchPair struct {
-	Ask Order
-	Bid Order
+	Ask Order `json:"ask"`
+	Bid Order `json:"bid"`
 }
 
 // ExecuteTradeRequest 表示提交给执行服务的交易请求
@@ -124,8 +132,12 @@ type OrderData struct {
 
 // ExecuteTradeResponse 表示执行服务的响应
 type ExecuteTradeResponse struct {
-	TxHash string `json:"txHash"`
-	Status string `json:"status"`
+	ExecutionID uint64 `json:"executionId"`
+	TxHash      string `json:"txHash"`
+	Status      string `json:"status"`
+	Duplicate   bool   `json:"duplicate"`
+	// Simulated 为 true 表示执行服务运行在仅模拟模式，交易未广播
+	Simulated bool `json:"simulated"`
 }
 
 // Engine 表示撮合引擎的运行时实例
@@ -134,19 +146,101 @@ type Engine struct {
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	redisClient *redis.Client
+	failures    *failureTracker
+	executor    Executor
+	policy      MatchPolicy
+	fillability *FillabilityChecker
+	orderRepo   *orders.Repository
+	ethClient   *ethclient.Client
+	engine      *gin.Engine
+	stats       engineStats
+	paused      atomic.Bool
+	// executionBackoffUntil 为执行服务发出背压信号后暂停提交的截止时间（UnixNano）
+	executionBackoffUntil atomic.Int64
 }
 
-// NewEngine 创建新的撮合引擎实例
+// NewEngine 创建新的撮合引擎实例，通过 HTTP 调用配置的执行服务
+// 内部接口认证密钥未配置时拒绝启动
 func NewEngine(cfg *config.Config) (*Engine, error) {
+	auth, err := hmacauth.New(cfg.InternalAuthSecret, cfg.InternalAuthWindow)
+	if err != nil {
+		return nil, fmt.Errorf("INTERNAL_AUTH_SECRET: %w", err)
+	}
+	executor := NewHTTPExecutor(
+		executionURLs(cfg.ExecutionURLs, cfg.ExecutionServicePort),
+		cfg.ExecutionTimeout,
+		cfg.ExecutionMaxRetries,
+		auth,
+	)
+	return NewEngineWithExecutor(cfg, executor)
+}
+
+// NewEngineInProcess 创建与执行服务运行在同一进程中的撮合引擎：撮合结果经进程内执行器
+// 直接交给执行服务的 handler（见 execution.Service.Handler），请求仍按 INTERNAL_AUTH_SECRET 签名
+func NewEngineInProcess(cfg *config.Config, handler http.Handler) (*Engine, error) {
+	auth, err := hmacauth.New(cfg.InternalAuthSecret, cfg.InternalAuthWindow)
+	if err != nil {
+		return nil, fmt.Errorf("INTERNAL_AUTH_SECRET: %w", err)
+	}
+	return NewEngineWithExecutor(cfg, NewInProcessExecutor(handler, auth))
+}
+
+// NewEngineWithExecutor 使用指定的执行器创建撮合引擎实例（单进程部署或测试时使用进程内执行器）
+func NewEngineWithExecutor(cfg *config.Config, executor Executor) (*Engine, error) {
+	policy, err := ParseMatchPolicy(cfg.MatchPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	redisClient := redisutil.New(cfg.RedisAddr, cfg.RedisPassword)
 	if err := redisutil.Ping(context.Background(), redisClient); err != nil {
 		return nil, err
 	}
 
-	return &Engine{
+	gin.SetMode(gin.ReleaseMode)
+	ginEngine := gin.New()
+	ginEngine.Use(gin.Recovery())
+	ginEngine.Use(logger.HTTPLogger())
+
+	var (
+		fillability *FillabilityChecker
+		orderRepo   *orders.Repository
+		ethClient   *ethclient.Client
+	)
+	if cfg.MatchFillabilityCheck {
+		// 提交前的链上可成交性检查：需要 RPC 读取链上状态，并将无效订单写回数据库
+		ethClient, err = ethclient.Dial(cfg.RPCURL)
+		if err != nil {
+			return nil, err
+		}
+		marketplaceAddr := common.HexToAddress(cfg.MarketplaceAddr)
+		reader, err := NewBindingReader(ethClient, marketplaceAddr)
+		if err != nil {
+			return nil, err
+		}
+		fillability = NewFillabilityChecker(reader, marketplaceAddr, cfg.MatchFillabilityCacheTTL)
+
+		db, err := postgres.New(cfg.PostgresDSN)
+		if err != nil {
+			return nil, err
+		}
+		orderRepo = orders.NewRepository(db)
+	}
+
+	e := &Engine{
 		cfg:         cfg,
 		redisClient: redisClient,
-	}, nil
+		failures:    newFailureTracker(cfg.MatchMaxFailures, cfg.MatchBackoffBase, cfg.MatchBackoffMax),
+		executor:    executor,
+		policy:      policy,
+		fillability: fillability,
+		orderRepo:   orderRepo,
+		ethClient:   ethClient,
+		engine:      ginEngine,
+	}
+	e.registerRoutes()
+
+	return e, nil
 }
 
 // Run 启动撮合引擎循环，持续扫描兼容的订单
@@ -154,7 +248,21 @@ func (e *Engine) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	e.cancel = cancel
 
-	logger.Info("matching engine started", "interval", "5s")
+	srv := &http.Server{
+		Addr:              ":" + e.cfg.MatchingServicePort,
+		Handler:           e.engine,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("matching engine admin http server error", err)
+		}
+	}()
+
+	logger.Info("matching engine started", "interval", "5s", "policy", e.policy, "adminPort", e.cfg.MatchingServicePort)
 
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -162,9 +270,19 @@ func (e *Engine) Run() error {
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelShutdown()
+			return srv.Shutdown(shutdownCtx)
 		case <-ticker.C:
-			if err := e.matchOrders(ctx); err != nil {
+			// 暂停期间跳过撮合，进程和管理接口保持运行
+			if e.paused.Load() {
+				continue
+			}
+
+			start := time.Now()
+			err := e.matchOrders(ctx)
+			e.stats.recordCycle(start, err)
+			if err != nil {
 				logger.Error("matching cycle failed", err)
 			}
 		}
@@ -173,6 +291,12 @@ func (e *Engine) Run() error {
 
 // matchOrders 从 Redis 获取活跃的 ask 和 bid 订单并尝试撮合
 func (e *Engine) matchOrders(ctx context.Context) error {
+	// 跟进已提交的执行任务，仍在执行中的订单本轮不参与撮合
+	inflight, err := e.followExecutions(ctx)
+	if err != nil {
+		return err
+	}
+
 	// 从 Redis 获取所有活跃的卖单（ask）
 	asks, err := e.fetchOrders(ctx, "ask")
 	if err != nil {
@@ -184,14 +308,42 @@ func (e *Engine) matchOrders(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	asks = excludeOrders(asks, inflight)
+	bids = excludeOrders(bids, inflight)
+
+	// 死信列表中的订单对不再自动提交，等待人工重试或丢弃；撮合时跳过它们，订单仍可与其他对手方成交
+	deadLetters, err := e.deadLetterKeys(ctx)
+	if err != nil {
+		return err
+	}
 
 	// 寻找兼容的订单匹配
-	matches := e.findMatches(asks, bids)
+	matches := e.findMatches(asks, bids, deadLetters)
+	e.stats.recordMatches(len(matches))
+
+	// 提交前复核链上状态，不可成交的订单标记为无效而不是反复重试
+	if e.fillability != nil && len(matches) > 0 {
+		matches = e.filterFillable(ctx, matches)
+	}
 
 	// 将匹配的订单对发送到执行服务
 	if len(matches) > 0 {
 		logger.Info("发现订单匹配", "数量", len(matches))
+
+		// 执行服务处于背压（队列已满或熔断器触发）期间不提交，订单保留在订单簿中
+		if until := e.executionBackoffUntil.Load(); time.Now().UnixNano() < until {
+			logger.Info("执行服务背压中，跳过本轮提交", "恢复时间", time.Unix(0, until))
+			return nil
+		}
+
 		for _, match := range matches {
+			key := pairKey(match)
+
+			// 仍处于退避期的订单对本轮跳过
+			if !e.failures.ready(key) {
+				continue
+			}
+
 			logger.Info("匹配订单对",
 				"NFT地址", match.Ask.NFTAddress,
 				"TokenID", match.Ask.TokenID,
@@ -201,20 +353,55 @@ func (e *Engine) matchOrders(ctx context.Context) error {
 			)
 
 			// 提交到执行服务进行链上结算
-			if err := e.submitToExecution(ctx, match); err != nil {
-				logger.Error("提交执行失败", err,
-					"NFT", match.Ask.NFTAddress,
-					"TokenID", match.Ask.TokenID,
+			execResp, err := e.submitToExecution(ctx, match)
+			e.stats.recordSubmission(err)
+
+			// 执行队列已满或熔断器触发：不计入订单对失败，按 Retry-After 暂停提交，剩余订单对留待之后
+			if execErr, ok := backpressure(err); ok {
+				wait := execErr.RetryAfter
+				if wait <= 0 {
+					wait = defaultExecutionBackoff
+				}
+				e.executionBackoffUntil.Store(time.Now().Add(wait).UnixNano())
+				logger.Warn("执行服务背压，暂停提交",
+					"原因", execErr.Code,
+					"等待", wait,
+					"pairKey", key,
 				)
+				break
+			}
+			if err != nil {
+				e.handleSubmitFailure(ctx, key, match, err)
 				// 继续处理其他匹配，不因单个失败而中断
 				continue
 			}
 
+			// 执行服务运行在仅模拟模式：交易未广播，订单保留在订单簿中，该订单对按退避推迟下次提交
+			if execResp.Simulated {
+				next := e.failures.recordSimulation(key)
+				logger.Info("执行服务仅模拟，订单保留在订单簿中",
+					"pairKey", key,
+					"卖方", match.Ask.Maker,
+					"买方", match.Bid.Maker,
+					"下次提交", next,
+				)
+				continue
+			}
+
 			logger.Info("订单对已提交执行",
 				"卖方", match.Ask.Maker,
 				"买方", match.Bid.Maker,
 			)
 
+			// 已入队的订单对在任务广播或失败前不再参与撮合，由 followExecutions 跟进结果
+			if execResp.ExecutionID != 0 {
+				if err := e.trackExecution(ctx, key, match, execResp.ExecutionID); err != nil {
+					logger.Error("记录提交中订单对失败", err, "pairKey", key, "执行ID", execResp.ExecutionID)
+				}
+				continue
+			}
+			e.failures.reset(key)
+
 			// 从 Redis 删除已提交的订单，避免重复撮合
 			// 注意：订单状态最终由索引服务更新，这里只是从缓存中移除
 			askKey := "orders:active:ask"
@@ -232,6 +419,31 @@ func (e *Engine) matchOrders(ctx context.Context) error {
 	return nil
 }
 
+// handleSubmitFailure 记录订单对的一次失败并安排退避重试；
+// 失败次数达到阈值，或执行服务判定为永久失败（如合约回滚 NonceConsumed）时移入死信列表
+func (e *Engine) handleSubmitFailure(ctx context.Context, key string, match MatchPair, err error) {
+	pf := e.failures.recordFailure(key, err)
+	logger.Error("提交执行失败", err,
+		"NFT", match.Ask.NFTAddress,
+		"TokenID", match.Ask.TokenID,
+		"失败次数", pf.failures,
+		"下次重试", pf.nextAttempt,
+	)
+
+	if !e.failures.exhausted(pf) && !isPermanentExecutionError(err) {
+		return
+	}
+	if dlErr := e.deadLetter(ctx, key, match, pf); dlErr != nil {
+		logger.Error("写入死信列表失败", dlErr, "pairKey", key)
+		return
+	}
+	logger.Warn("订单对已移入死信列表",
+		"pairKey", key,
+		"失败次数", pf.failures,
+		"最后错误", pf.lastError,
+	)
+}
+
 // fetchOrders 从 Redis 检索指定方向的所有活跃订单
 func (e *Engine) fetchOrders(ctx context.Context, side string) ([]Order, error) {
 	key := "orders:active:" + side
@@ -259,64 +471,22 @@ func (e *Engine) fetchOrders(ctx context.Context, side string) ([]Order, error)
 	return orders, nil
 }
 
-// findMatches 实现简单的撮合算法，将 ask 和 bid 配对
+// findMatches 按引擎配置的撮合策略将 ask 和 bid 配对
 // 撮合条件：
 // - 相同的 NFT 合集和 token ID
 // - 相同的支付代币
 // - Ask 价格 <= Bid 价格（买方愿意支付至少卖方要价）
 // - 两个订单都未过期
 //
-// TODO: [性能优化] - 实现价格-时间优先队列，达到 O(log n) 撮合复杂度
+// - 订单对不在 excluded 中（死信列表中的订单对不再配对，ask 可以与其他 bid 成交）
+//
 // TODO: [可扩展性] - 支持部分成交和多数量撮合
-func (e *Engine) findMatches(asks []Order, bids []Order) []MatchPair {
-	matches := make([]MatchPair, 0)
-
-	// MVP 采用简单的 O(n*m) 撮合算法
-	// 生产环境应使用排序价格队列实现 O(log n) 复杂度
-	for _, ask := range asks {
-		for _, bid := range bids {
-			if e.isMatch(ask, bid) {
-				matches = append(matches, MatchPair{Ask: ask, Bid: bid})
-				break // 在这个简单实现中，每个 ask 最多匹配一个 bid
-			}
-		}
-	}
-
-	return matches
-}
-
-// isMatch 判断 ask 和 bid 订单是否兼容可执行
-func (e *Engine) isMatch(ask Order, bid Order) bool {
-	// 必须是相同的 NFT
-	if ask.NFTAddress != bid.NFTAddress || ask.TokenID.String() != bid.TokenID.String() {
-		return false
-	}
-
-	// 必须使用相同的支付代币
-	if ask.PaymentToken != bid.PaymentToken {
-		return false
-	}
-
-	// Bid 价格必须达到或超过 ask 价格（买方愿意支付 >= 卖方要价）
-	// 这是标准订单簿撮合规则: bid >= ask
-	// MVP 中我们按 ask 价格执行（maker 获得其要求的价格）
-	// TODO: [撮合优化] - 实现价格改善逻辑或中间价执行
-	askPrice, askOk := new(big.Int).SetString(ask.Price.String(), 10)
-	bidPrice, bidOk := new(big.Int).SetString(bid.Price.String(), 10)
-	if !askOk || !bidOk {
-		return false
-	}
-
-	// Bid 必须 >= ask 才能形成有效匹配
-	if bidPrice.Cmp(askPrice) < 0 {
-		return false
-	}
-
-	return true
+func (e *Engine) findMatches(asks []Order, bids []Order, excluded map[string]struct{}) []MatchPair {
+	return matchWith(e.policy, asks, bids, excluded)
 }
 
 // submitToExecution 将匹配的订单对提交给执行服务进行链上结算
-func (e *Engine) submitToExecution(ctx context.Context, match MatchPair) error {
+func (e *Engine) submitToExecution(ctx context.Context, match MatchPair) (*ExecuteTradeResponse, error) {
 	// 构建执行请求
 	// 在标准订单簿中：ask 是 maker（挂单方），bid 是 taker（吃单方）
 	req := ExecuteTradeRequest{
@@ -343,47 +513,20 @@ func (e *Engine) submitToExecution(ctx context.Context, match MatchPair) error {
 		MakerSignature: match.Ask.Signature,
 	}
 
-	// 序列化请求
-	reqBody, err := json.Marshal(req)
+	execResp, err := e.executor.Execute(ctx, &req)
 	if err != nil {
-		return fmt.Errorf("序列化执行请求失败: %w", err)
-	}
-
-	// 构建执行服务 URL
-	executionURL := fmt.Sprintf("http://localhost:%s/internal/execute", e.cfg.ExecutionServicePort)
-
-	// 创建 HTTP 请求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", executionURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("创建HTTP请求失败: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	// 发送请求
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("调用执行服务失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("执行服务返回错误: HTTP %d", resp.StatusCode)
-	}
-
-	// 解析响应
-	var execResp ExecuteTradeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&execResp); err != nil {
-		return fmt.Errorf("解析执行响应失败: %w", err)
+		return nil, err
 	}
 
-	logger.Info("交易已提交到链上",
+	logger.Info("交易已提交执行队列",
+		"执行ID", execResp.ExecutionID,
 		"交易哈希", execResp.TxHash,
 		"状态", execResp.Status,
+		"重复提交", execResp.Duplicate,
+		"仅模拟", execResp.Simulated,
 	)
 
-	return nil
+	return execResp, nil
 }
 
 // Shutdown 优雅关闭撮合引擎
@@ -392,5 +535,8 @@ func (e *Engine) Shutdown() {
 		e.cancel()
 	}
 	e.wg.Wait()
+	if e.ethClient != nil {
+		e.ethClient.Close()
+	}
 	logger.Info("撮合引擎已关闭")
 }
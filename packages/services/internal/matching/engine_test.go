@@ -10,10 +10,16 @@ package matching
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Oeasy-NFT/services/internal/config"
+	"github.com/Oeasy-NFT/services/internal/hmacauth"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
@@ -27,10 +33,12 @@ func setupTestMatchingEngine(t *testing.T) (*Engine, *redis.Client, func()) {
 	require.NoError(t, err)
 
 	cfg := &config.Config{
-		RedisAddr:       srv.Addr(),
-		MarketplaceAddr: "0x0000000000000000000000000000000000000001",
-		RPCURL:          "http://localhost",
-		ChainID:         1,
+		RedisAddr:          srv.Addr(),
+		MarketplaceAddr:    "0x0000000000000000000000000000000000000001",
+		RPCURL:             "http://localhost",
+		ChainID:            1,
+		InternalAuthSecret: "test-internal-secret",
+		MatchingAdminToken: "test-admin-token",
 	}
 
 	engine, err := NewEngine(cfg)
@@ -72,7 +80,7 @@ func TestFindMatches_ExactPriceMatch(t *testing.T) {
 		},
 	}
 
-	matches := engine.findMatches(asks, bids)
+	matches := engine.findMatches(asks, bids, nil)
 	require.Len(t, matches, 1)
 	require.Equal(t, asks[0].ID, matches[0].Ask.ID)
 	require.Equal(t, bids[0].ID, matches[0].Bid.ID)
@@ -101,7 +109,7 @@ func TestFindMatches_DifferentNFT(t *testing.T) {
 		},
 	}
 
-	matches := engine.findMatches(asks, bids)
+	matches := engine.findMatches(asks, bids, nil)
 	require.Len(t, matches, 0)
 }
 
@@ -128,7 +136,7 @@ func TestFindMatches_BidPriceTooLow(t *testing.T) {
 		},
 	}
 
-	matches := engine.findMatches(asks, bids)
+	matches := engine.findMatches(asks, bids, nil)
 	require.Len(t, matches, 0, "bid price below ask should not match")
 }
 
@@ -157,12 +165,41 @@ func TestFindMatches_BidExceedsAsk(t *testing.T) {
 		},
 	}
 
-	matches := engine.findMatches(asks, bids)
+	matches := engine.findMatches(asks, bids, nil)
 	require.Len(t, matches, 1, "bid >= ask should match")
 	require.Equal(t, asks[0].ID, matches[0].Ask.ID)
 	require.Equal(t, bids[0].ID, matches[0].Bid.ID)
 }
 
+// TestFindMatches_SkipsExcludedPair validates a dead-lettered pair does not keep its ask from
+// matching another compatible bid under either policy.
+func TestFindMatches_SkipsExcludedPair(t *testing.T) {
+	order := func(id uint, side, price, hash string) Order {
+		return Order{
+			ID:           id,
+			NFTAddress:   "0x0000000000000000000000000000000000000002",
+			TokenID:      "1",
+			PaymentToken: "0x0000000000000000000000000000000000000003",
+			Price:        FlexString(price),
+			Side:         side,
+			Hash:         hash,
+		}
+	}
+	asks := []Order{order(1, "ask", "100", "0xask")}
+	bids := []Order{order(2, "bid", "150", "0xbid1"), order(3, "bid", "120", "0xbid2")}
+	excluded := map[string]struct{}{"0xask:0xbid1": {}}
+
+	for _, policy := range []MatchPolicy{PolicyFirstFit, PolicyPriceTime} {
+		matches := matchWith(policy, asks, bids, nil)
+		require.Len(t, matches, 1, policy)
+		require.Equal(t, "0xbid1", matches[0].Bid.Hash, policy)
+
+		matches = matchWith(policy, asks, bids, excluded)
+		require.Len(t, matches, 1, policy)
+		require.Equal(t, "0xbid2", matches[0].Bid.Hash, policy)
+	}
+}
+
 // TestFetchOrders_FiltersExpired validates that expired orders are excluded from matching.
 func TestFetchOrders_FiltersExpired(t *testing.T) {
 	engine, redisClient, cleanup := setupTestMatchingEngine(t)
@@ -220,3 +257,529 @@ func TestFetchOrders_ReturnsActive(t *testing.T) {
 	require.Len(t, orders, 1)
 	require.Equal(t, activeOrder.Maker, orders[0].Maker)
 }
+
+// testAuth signs requests from test executors and authenticates stub execution services.
+var testAuth = func() *hmacauth.Authenticator {
+	auth, err := hmacauth.New("test-internal-secret", time.Minute)
+	if err != nil {
+		panic(err)
+	}
+	return auth
+}()
+
+// newFailingExecutionServer starts an execution service stub that always returns 500
+// and points the engine at it.
+func newFailingExecutionServer(t *testing.T, engine *Engine) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+	engine.executor = NewHTTPExecutor([]string{srv.URL}, time.Second, 0, testAuth)
+	return srv
+}
+
+// seedMatchingPair stores a compatible ask/bid pair in the active order book.
+func seedMatchingPair(t *testing.T, redisClient *redis.Client) (Order, Order) {
+	t.Helper()
+
+	ctx := context.Background()
+	ask := Order{
+		ID:           1,
+		Maker:        "0xaaa",
+		NFTAddress:   "0x0000000000000000000000000000000000000002",
+		TokenID:      "1",
+		PaymentToken: "0x0000000000000000000000000000000000000003",
+		Price:        "1000000000000000000",
+		Expiry:       CustomTime{time.Now().Add(time.Hour)},
+		Nonce:        "1",
+		Side:         "ask",
+		Hash:         "0xaskhash0000000000",
+	}
+	bid := ask
+	bid.ID = 2
+	bid.Maker = "0xbbb"
+	bid.Side = "bid"
+	bid.Hash = "0xbidhash0000000000"
+
+	askPayload, _ := json.Marshal(ask)
+	bidPayload, _ := json.Marshal(bid)
+	require.NoError(t, redisClient.HSet(ctx, "orders:active:ask", ask.Hash, askPayload).Err())
+	require.NoError(t, redisClient.HSet(ctx, "orders:active:bid", bid.Hash, bidPayload).Err())
+	return ask, bid
+}
+
+// TestFailureTracker_ExponentialBackoff validates backoff doubling and the max cap.
+func TestFailureTracker_ExponentialBackoff(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tracker := newFailureTracker(3, time.Second, 3*time.Second)
+	tracker.now = func() time.Time { return now }
+
+	require.True(t, tracker.ready("pair"))
+
+	pf := tracker.recordFailure("pair", errors.New("boom"))
+	require.Equal(t, now.Add(time.Second), pf.nextAttempt)
+	require.False(t, tracker.ready("pair"))
+
+	pf = tracker.recordFailure("pair", errors.New("boom"))
+	require.Equal(t, now.Add(2*time.Second), pf.nextAttempt)
+
+	pf = tracker.recordFailure("pair", errors.New("boom"))
+	require.Equal(t, now.Add(3*time.Second), pf.nextAttempt, "backoff should be capped at max")
+	require.True(t, tracker.exhausted(pf))
+
+	now = now.Add(3 * time.Second)
+	require.True(t, tracker.ready("pair"))
+
+	tracker.reset("pair")
+	require.True(t, tracker.ready("pair"))
+}
+
+// TestMatchOrders_FailingPairIsDeadLettered ensures a pair that keeps failing is moved
+// to the dead-letter list and is no longer submitted.
+func TestMatchOrders_FailingPairIsDeadLettered(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	exec := newFailingExecutionServer(t, engine)
+	defer exec.Close()
+
+	now := time.Now()
+	engine.failures = newFailureTracker(2, time.Minute, time.Hour)
+	engine.failures.now = func() time.Time { return now }
+
+	ask, bid := seedMatchingPair(t, redisClient)
+	ctx := context.Background()
+
+	require.NoError(t, engine.matchOrders(ctx))
+	dls, err := engine.DeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, dls, 0, "first failure should only back off")
+
+	// Still inside the backoff window: no new attempt is made.
+	require.NoError(t, engine.matchOrders(ctx))
+	require.Equal(t, 1, engine.failures.pairs[ask.Hash+":"+bid.Hash].failures)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, engine.matchOrders(ctx))
+
+	dls, err = engine.DeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, dls, 1)
+	require.Equal(t, ask.Hash+":"+bid.Hash, dls[0].PairKey)
+	require.Equal(t, 2, dls[0].Failures)
+	require.Contains(t, dls[0].LastError, "HTTP 500")
+
+	// Dead-lettered pairs are skipped on later cycles.
+	now = now.Add(time.Hour)
+	require.NoError(t, engine.matchOrders(ctx))
+	require.NotContains(t, engine.failures.pairs, dls[0].PairKey)
+}
+
+// TestMatchOrders_PermanentFailureIsDeadLetteredImmediately skips backoff for decoded reverts.
+func TestMatchOrders_PermanentFailureIsDeadLetteredImmediately(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error":"execution reverted: NonceConsumed","code":"NonceConsumed","permanent":true}`))
+	})))
+	defer srv.Close()
+	engine.executor = NewHTTPExecutor([]string{srv.URL}, time.Second, 0, testAuth)
+	engine.failures = newFailureTracker(5, time.Minute, time.Hour)
+
+	ask, bid := seedMatchingPair(t, redisClient)
+	ctx := context.Background()
+
+	require.NoError(t, engine.matchOrders(ctx))
+
+	dls, err := engine.DeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, dls, 1)
+	require.Equal(t, ask.Hash+":"+bid.Hash, dls[0].PairKey)
+	require.Equal(t, 1, dls[0].Failures)
+	require.Contains(t, dls[0].LastError, "NonceConsumed")
+}
+
+// TestMatchOrders_BackpressureIsNotAFailure keeps pairs in the book without counting a failure
+// when the execution service pushes back (full queue or tripped breaker), and pauses
+// submissions for the advertised Retry-After.
+func TestMatchOrders_BackpressureIsNotAFailure(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		code   string
+	}{
+		{http.StatusTooManyRequests, "QueueFull"},
+		{http.StatusServiceUnavailable, "MarketplacePaused"},
+	} {
+		t.Run(tc.code, func(t *testing.T) {
+			engine, redisClient, cleanup := setupTestMatchingEngine(t)
+			defer cleanup()
+
+			var calls atomic.Int32
+			srv := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(tc.status)
+				_, _ = fmt.Fprintf(w, `{"error":"busy","code":%q,"permanent":false}`, tc.code)
+			})))
+			defer srv.Close()
+			engine.executor = NewHTTPExecutor([]string{srv.URL}, time.Second, 0, testAuth)
+
+			ask, bid := seedMatchingPair(t, redisClient)
+			ctx := context.Background()
+
+			require.NoError(t, engine.matchOrders(ctx))
+			require.NotContains(t, engine.failures.pairs, ask.Hash+":"+bid.Hash)
+			dls, err := engine.DeadLetters(ctx)
+			require.NoError(t, err)
+			require.Empty(t, dls)
+			require.True(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val())
+
+			// The next cycle inside the Retry-After window does not call the service.
+			require.NoError(t, engine.matchOrders(ctx))
+			require.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+// newQueueingExecutionServer starts an execution service stub that queues every trade as job 7
+// and reports the job with whatever status is stored in job.
+func newQueueingExecutionServer(t *testing.T, engine *Engine, job *atomic.Value, executes *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/internal/execute":
+			executes.Add(1)
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(ExecuteTradeResponse{ExecutionID: 7, Status: JobStatusQueued})
+		case "/internal/jobs/7":
+			_ = json.NewEncoder(w).Encode(job.Load())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})))
+	engine.executor = NewHTTPExecutor(executionURLs([]string{srv.URL}, ""), time.Second, 0, testAuth)
+	return srv
+}
+
+// TestMatchOrders_FollowsQueuedExecution keeps a queued pair out of matching until its job
+// settles: a broadcast job removes the orders from the book, a failed one is handled like a
+// failed submission and leaves them in place.
+func TestMatchOrders_FollowsQueuedExecution(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("submitted", func(t *testing.T) {
+		engine, redisClient, cleanup := setupTestMatchingEngine(t)
+		defer cleanup()
+
+		var job atomic.Value
+		var executes atomic.Int32
+		job.Store(JobStatus{ID: 7, Status: JobStatusRunning})
+		srv := newQueueingExecutionServer(t, engine, &job, &executes)
+		defer srv.Close()
+
+		ask, bid := seedMatchingPair(t, redisClient)
+		require.NoError(t, engine.matchOrders(ctx))
+		require.True(t, redisClient.HExists(ctx, inflightKey, "7").Val())
+
+		// The job is still running: the pair is not submitted again.
+		require.NoError(t, engine.matchOrders(ctx))
+		require.Equal(t, int32(1), executes.Load())
+		require.True(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val())
+
+		job.Store(JobStatus{ID: 7, Status: JobStatusSubmitted, TxHash: "0xabc"})
+		require.NoError(t, engine.matchOrders(ctx))
+		require.False(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val())
+		require.False(t, redisClient.HExists(ctx, "orders:active:bid", bid.Hash).Val())
+		require.False(t, redisClient.HExists(ctx, inflightKey, "7").Val())
+		require.Equal(t, int32(1), executes.Load())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		engine, redisClient, cleanup := setupTestMatchingEngine(t)
+		defer cleanup()
+
+		var job atomic.Value
+		var executes atomic.Int32
+		job.Store(JobStatus{ID: 7, Status: JobStatusFailed, ErrorCode: "NonceConsumed", Error: "execution reverted: NonceConsumed", Permanent: true})
+		srv := newQueueingExecutionServer(t, engine, &job, &executes)
+		defer srv.Close()
+
+		ask, bid := seedMatchingPair(t, redisClient)
+		require.NoError(t, engine.matchOrders(ctx))
+		require.NoError(t, engine.matchOrders(ctx))
+
+		dls, err := engine.DeadLetters(ctx)
+		require.NoError(t, err)
+		require.Len(t, dls, 1)
+		require.Equal(t, ask.Hash+":"+bid.Hash, dls[0].PairKey)
+		require.Contains(t, dls[0].LastError, "NonceConsumed")
+		require.True(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val())
+		require.True(t, redisClient.HExists(ctx, "orders:active:bid", bid.Hash).Val())
+		require.False(t, redisClient.HExists(ctx, inflightKey, "7").Val())
+		require.Equal(t, int32(1), executes.Load())
+	})
+}
+
+// TestMatchOrders_SimulatedTradeKeepsOrders leaves both orders on the book when the execution
+// service only simulated the trade, and backs the pair off instead of simulating it every cycle.
+func TestMatchOrders_SimulatedTradeKeepsOrders(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(ExecuteTradeResponse{Status: "simulated", Simulated: true})
+	})))
+	defer srv.Close()
+	engine.executor = NewHTTPExecutor([]string{srv.URL}, time.Second, 0, testAuth)
+	now := time.Now()
+	engine.failures = newFailureTracker(2, time.Minute, time.Hour)
+	engine.failures.now = func() time.Time { return now }
+
+	ask, bid := seedMatchingPair(t, redisClient)
+	ctx := context.Background()
+	require.NoError(t, engine.matchOrders(ctx))
+	require.True(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val())
+	require.True(t, redisClient.HExists(ctx, "orders:active:bid", bid.Hash).Val())
+	require.Zero(t, redisClient.HLen(ctx, inflightKey).Val())
+
+	// The next cycle inside the backoff window does not simulate the pair again.
+	require.NoError(t, engine.matchOrders(ctx))
+	require.Equal(t, int32(1), calls.Load())
+
+	// Repeated simulations back off further but never dead-letter the pair.
+	for i, wait := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		now = now.Add(wait)
+		require.NoError(t, engine.matchOrders(ctx))
+		require.Equal(t, int32(i+2), calls.Load())
+	}
+	dls, err := engine.DeadLetters(ctx)
+	require.NoError(t, err)
+	require.Empty(t, dls)
+	require.Zero(t, engine.failures.pairs[ask.Hash+":"+bid.Hash].failures)
+}
+
+// TestDeadLetter_RetryAndDrop validates the operator actions on dead-lettered pairs.
+func TestDeadLetter_RetryAndDrop(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ask, bid := seedMatchingPair(t, redisClient)
+	match := MatchPair{Ask: ask, Bid: bid}
+	key := pairKey(match)
+
+	pf := engine.failures.recordFailure(key, errors.New("boom"))
+	require.NoError(t, engine.deadLetter(ctx, key, match, pf))
+
+	require.NoError(t, engine.RetryDeadLetter(ctx, key))
+	require.ErrorIs(t, engine.RetryDeadLetter(ctx, key), ErrDeadLetterNotFound)
+	require.True(t, engine.failures.ready(key))
+
+	require.NoError(t, engine.deadLetter(ctx, key, match, pf))
+	require.NoError(t, engine.DropDeadLetter(ctx, key))
+
+	dls, err := engine.DeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, dls, 0)
+	require.False(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val())
+	require.False(t, redisClient.HExists(ctx, "orders:active:bid", bid.Hash).Val())
+	require.ErrorIs(t, engine.DropDeadLetter(ctx, key), ErrDeadLetterNotFound)
+}
+
+// TestAdminAPI_PauseResumeAndStatus validates pause/resume toggles and the status payload.
+func TestAdminAPI_PauseResumeAndStatus(t *testing.T) {
+	engine, _, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	adminRequest := func(method, target, token string) *http.Request {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req
+	}
+
+	// Mutating routes reject requests without the admin token.
+	w := httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, adminRequest(http.MethodPost, "/pause", ""))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, adminRequest(http.MethodPost, "/pause", "wrong-token"))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, adminRequest(http.MethodDelete, "/deadletters/some-key", ""))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.False(t, engine.paused.Load())
+
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, adminRequest(http.MethodPost, "/pause", "test-admin-token"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, engine.paused.Load())
+
+	engine.stats.recordMatches(2)
+	engine.stats.recordSubmission(nil)
+	engine.stats.recordSubmission(errors.New("boom"))
+	engine.stats.recordCycle(time.Now(), nil)
+
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status StatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	require.True(t, status.Paused)
+	require.Equal(t, uint64(1), status.Cycles)
+	require.Equal(t, 2, status.LastMatches)
+	require.Equal(t, uint64(1), status.Submitted)
+	require.Equal(t, uint64(1), status.Failures)
+
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, adminRequest(http.MethodPost, "/resume", "test-admin-token"))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.False(t, engine.paused.Load())
+
+	// Without a configured token the mutating routes are disabled.
+	engine.cfg.MatchingAdminToken = ""
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, adminRequest(http.MethodPost, "/pause", "test-admin-token"))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.False(t, engine.paused.Load())
+}
+
+// TestAdminAPI_Book validates the per-token order book snapshot.
+func TestAdminAPI_Book(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	ask, bid := seedMatchingPair(t, redisClient)
+
+	w := httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/book/"+ask.NFTAddress+"/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var book BookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	require.Len(t, book.Asks, 1)
+	require.Len(t, book.Bids, 1)
+	require.Equal(t, ask.Hash, book.Asks[0].Hash)
+	require.Equal(t, bid.Hash, book.Bids[0].Hash)
+
+	w = httptest.NewRecorder()
+	engine.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/book/"+ask.NFTAddress+"/2", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+	require.Len(t, book.Asks, 0)
+	require.Len(t, book.Bids, 0)
+}
+
+// TestHTTPExecutor_RetriesNextURL validates gateway errors fail over to the next execution URL.
+func TestHTTPExecutor_RetriesNextURL(t *testing.T) {
+	var unavailableCalls atomic.Int32
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unavailableCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	healthy := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/internal/execute", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(ExecuteTradeResponse{TxHash: "0xabc", Status: "submitted"})
+	})))
+	defer healthy.Close()
+
+	executor := NewHTTPExecutor(executionURLs([]string{unavailable.URL, healthy.URL}, ""), time.Second, 2, testAuth)
+	resp, err := executor.Execute(context.Background(), &ExecuteTradeRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "0xabc", resp.TxHash)
+	require.Equal(t, int32(1), unavailableCalls.Load())
+}
+
+// TestHTTPExecutor_DoesNotRetryExecutionFailure ensures a 500 from the execution service is not retried.
+func TestHTTPExecutor_DoesNotRetryExecutionFailure(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "reverted"})
+	})))
+	defer srv.Close()
+
+	executor := NewHTTPExecutor([]string{srv.URL + "/internal/execute"}, time.Second, 3, testAuth)
+	_, err := executor.Execute(context.Background(), &ExecuteTradeRequest{})
+	require.ErrorContains(t, err, "HTTP 500: reverted")
+	require.Equal(t, int32(1), calls.Load())
+}
+
+// TestHTTPExecutor_RejectsUnsignedResponse ensures an execution service that cannot sign its
+// responses (e.g. an impostor on the network) is not trusted, and that it rejects our
+// requests when its secret differs.
+func TestHTTPExecutor_RejectsUnsignedResponse(t *testing.T) {
+	impostor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ExecuteTradeResponse{TxHash: "0xfake", Status: "submitted"})
+	}))
+	defer impostor.Close()
+
+	_, err := NewHTTPExecutor([]string{impostor.URL}, time.Second, 0, testAuth).Execute(context.Background(), &ExecuteTradeRequest{})
+	require.ErrorIs(t, err, hmacauth.ErrMissingSignature)
+
+	otherAuth, err := hmacauth.New("another-secret", time.Minute)
+	require.NoError(t, err)
+	var served atomic.Int32
+	srv := httptest.NewServer(otherAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+	})))
+	defer srv.Close()
+
+	_, err = NewHTTPExecutor([]string{srv.URL}, time.Second, 0, testAuth).Execute(context.Background(), &ExecuteTradeRequest{})
+	require.Error(t, err)
+	require.Zero(t, served.Load())
+}
+
+// TestInProcessExecutor validates trades are handed to the execution handler without networking.
+func TestInProcessExecutor(t *testing.T) {
+	var received ExecuteTradeRequest
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_ = json.NewEncoder(w).Encode(ExecuteTradeResponse{TxHash: "0xdef", Status: "submitted"})
+	})
+
+	executor := NewInProcessExecutor(testAuth.Middleware(handler), testAuth)
+	resp, err := executor.Execute(context.Background(), &ExecuteTradeRequest{MakerSignature: "0x01"})
+	require.NoError(t, err)
+	require.Equal(t, "0xdef", resp.TxHash)
+	require.Equal(t, "0x01", received.MakerSignature)
+}
+
+// TestNewEngineInProcess submits a matched pair through the execution handler the engine was
+// built with, signed with the configured secret.
+func TestNewEngineInProcess(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var calls atomic.Int32
+	handler := testAuth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(ExecuteTradeResponse{TxHash: "0xdef", Status: "submitted"})
+	}))
+
+	engine, err := NewEngineInProcess(&config.Config{
+		RedisAddr:          mr.Addr(),
+		MarketplaceAddr:    "0x0000000000000000000000000000000000000001",
+		InternalAuthSecret: "test-internal-secret",
+	}, handler)
+	require.NoError(t, err)
+
+	ask, bid := seedMatchingPair(t, engine.redisClient)
+	require.NoError(t, engine.matchOrders(context.Background()))
+	require.Equal(t, int32(1), calls.Load())
+	require.False(t, mr.Exists("orders:active:ask"), ask.Hash)
+	require.False(t, mr.Exists("orders:active:bid"), bid.Hash)
+}
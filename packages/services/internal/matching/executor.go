@@ -0,0 +1,399 @@
+package matching
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/hmacauth"
+)
+
+// 执行传输层的默认参数（配置为零值时使用）
+const (
+	defaultExecutionTimeout    = 30 * time.Second
+	defaultExecutionMaxRetries = 3
+	executionRetryBase         = 200 * time.Millisecond
+	executionRetryMax          = 5 * time.Second
+	executePath                = "/internal/execute"
+	jobsPath                   = "/internal/jobs/"
+	// 执行服务发出背压信号但未给出 Retry-After 时暂停提交的时长
+	defaultExecutionBackoff = 5 * time.Second
+)
+
+// Executor 将撮合成功的订单对提交给执行服务进行链上结算
+type Executor interface {
+	Execute(ctx context.Context, req *ExecuteTradeRequest) (*ExecuteTradeResponse, error)
+	// Job 查询已入队执行请求的状态，撮合引擎据此跟进 202 之后的结果
+	Job(ctx context.Context, id uint64) (*JobStatus, error)
+}
+
+// 执行任务状态，与执行服务的 execution_jobs.status 一致
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSubmitted = "submitted"
+	JobStatusFailed    = "failed"
+)
+
+// JobStatus 是执行服务 GET /internal/jobs/:id 返回的执行任务状态
+type JobStatus struct {
+	ID        uint64 `json:"id"`
+	Status    string `json:"status"`
+	TxHash    string `json:"txHash"`
+	ErrorCode string `json:"errorCode"`
+	Error     string `json:"error"`
+	// Permanent 表示任务最终失败且同一订单对重新提交也不会成功
+	Permanent bool `json:"permanent"`
+}
+
+// HTTPExecutor 通过 HTTP 调用一个或多个执行服务实例。
+// 复用同一个 http.Client 的连接池，按轮询顺序选择地址，
+// 遇到网络错误或网关类错误时带抖动地指数退避重试下一个地址。
+// 每个请求都用共享密钥做 HMAC 签名，并校验执行服务响应的签名。
+type HTTPExecutor struct {
+	urls       []string
+	client     *http.Client
+	maxRetries int
+	auth       *hmacauth.Authenticator
+	next       atomic.Uint64
+}
+
+// NewHTTPExecutor 创建 HTTP 执行器，urls 为执行服务的完整 execute 地址
+func NewHTTPExecutor(urls []string, timeout time.Duration, maxRetries int, auth *hmacauth.Authenticator) *HTTPExecutor {
+	if timeout <= 0 {
+		timeout = defaultExecutionTimeout
+	}
+	if maxRetries < 0 {
+		maxRetries = defaultExecutionMaxRetries
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          20,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	return &HTTPExecutor{
+		urls:       urls,
+		client:     &http.Client{Timeout: timeout, Transport: transport},
+		maxRetries: maxRetries,
+		auth:       auth,
+	}
+}
+
+// executionURLs 根据配置生成执行服务地址列表；未配置时回退到本机执行服务端口
+func executionURLs(configured []string, port string) []string {
+	urls := make([]string, 0, len(configured))
+	for _, u := range configured {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		// 只配置了主机地址时补全 execute 路径
+		if !strings.HasSuffix(u, executePath) {
+			u = strings.TrimRight(u, "/") + executePath
+		}
+		urls = append(urls, u)
+	}
+	if len(urls) == 0 {
+		urls = append(urls, fmt.Sprintf("http://localhost:%s%s", port, executePath))
+	}
+	return urls
+}
+
+// Execute 提交交易请求，必要时在多个地址之间重试
+func (h *HTTPExecutor) Execute(ctx context.Context, req *ExecuteTradeRequest) (*ExecuteTradeResponse, error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("序列化执行请求失败: %w", err)
+	}
+
+	var resp *ExecuteTradeResponse
+	err = h.roundRobin(ctx, func(url string) (bool, error) {
+		status, header, body, retryable, err := h.send(ctx, http.MethodPost, url, reqBody)
+		if err != nil {
+			return retryable, err
+		}
+		resp, retryable, err = decodeExecuteResponse(status, header, bytes.NewReader(body))
+		return retryable, err
+	})
+	return resp, err
+}
+
+// Job 查询执行任务状态。任务保存在共享数据库中，任一执行服务实例都可以回答
+func (h *HTTPExecutor) Job(ctx context.Context, id uint64) (*JobStatus, error) {
+	var job *JobStatus
+	err := h.roundRobin(ctx, func(url string) (bool, error) {
+		url = strings.TrimSuffix(url, executePath) + jobsPath + strconv.FormatUint(id, 10)
+		status, header, body, retryable, err := h.send(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return retryable, err
+		}
+		job, retryable, err = decodeJobResponse(status, header, bytes.NewReader(body))
+		return retryable, err
+	})
+	return job, err
+}
+
+// roundRobin 从轮询顺序的下一个地址开始调用 call，可重试的错误带退避地换下一个地址，最多重试 maxRetries 次
+func (h *HTTPExecutor) roundRobin(ctx context.Context, call func(url string) (retryable bool, err error)) error {
+	if len(h.urls) == 0 {
+		return fmt.Errorf("未配置执行服务地址")
+	}
+
+	start := h.next.Add(1) - 1
+	var lastErr error
+	for attempt := 0; attempt <= h.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay(attempt)):
+			}
+		}
+
+		url := h.urls[(start+uint64(attempt))%uint64(len(h.urls))]
+		retryable, err := call(url)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+// send 发送单次签名请求并校验响应签名，返回响应内容以及错误是否可以安全重试
+func (h *HTTPExecutor) send(ctx context.Context, method, url string, body []byte) (int, http.Header, []byte, bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, false, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	nonce, err := h.auth.SignRequest(httpReq, body)
+	if err != nil {
+		return 0, nil, nil, false, fmt.Errorf("签名执行请求失败: %w", err)
+	}
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		// 上下文取消不重试；其他网络错误可换地址重试
+		return 0, nil, nil, ctx.Err() == nil, fmt.Errorf("调用执行服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, ctx.Err() == nil, fmt.Errorf("读取执行响应失败: %w", err)
+	}
+	// 未通过签名校验的响应不可信（可能来自冒充的执行服务），换地址重试
+	if err := h.auth.VerifyResponse(resp.Header, nonce, resp.StatusCode, respBody); err != nil {
+		return 0, nil, nil, true, fmt.Errorf("执行服务响应签名校验失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	return resp.StatusCode, resp.Header, respBody, false, nil
+}
+
+// retryDelay 计算第 attempt 次重试前的等待时间（指数退避 + 随机抖动）
+func retryDelay(attempt int) time.Duration {
+	delay := executionRetryBase << uint(attempt-1)
+	if delay <= 0 || delay > executionRetryMax {
+		delay = executionRetryMax
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// isRetryableStatus 判断执行服务返回的状态码是否可重试。
+// 500 表示执行本身失败（可能已广播交易），不在传输层重试。
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ExecutionError 是执行服务返回的非 200 响应。
+// Code 为合约自定义错误名（如 NonceConsumed），Permanent 表示同一订单对重试不会成功。
+type ExecutionError struct {
+	Status    int
+	Code      string
+	Message   string
+	Permanent bool
+	// RetryAfter 为执行服务通过 Retry-After 建议的等待时间（未提供时为 0）
+	RetryAfter time.Duration
+}
+
+func (e *ExecutionError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("执行服务返回错误: HTTP %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("执行服务返回错误: HTTP %d", e.Status)
+}
+
+// JobError 表示已入队的执行任务在执行服务中最终失败（合约回滚、重试次数用尽等）
+type JobError struct {
+	ExecutionID uint64
+	Code        string
+	Message     string
+	Permanent   bool
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("执行任务 %d 失败: %s", e.ExecutionID, e.Message)
+}
+
+// isPermanentExecutionError 判断错误是否为执行服务明确标记的永久失败
+func isPermanentExecutionError(err error) bool {
+	var execErr *ExecutionError
+	var jobErr *JobError
+	return (errors.As(err, &execErr) && execErr.Permanent) || (errors.As(err, &jobErr) && jobErr.Permanent)
+}
+
+// backpressureCodes 是执行服务的背压信号：队列已满或熔断器触发（合约暂停、执行钱包余额不足、gas 过高）。
+// 这些错误与订单对本身无关，不计入失败次数。
+var backpressureCodes = map[string]bool{
+	"QueueFull":          true,
+	"MarketplacePaused":  true,
+	"ExecutorBalanceLow": true,
+	"GasPriceTooHigh":    true,
+}
+
+// backpressure 判断错误是否为执行服务的背压信号，并返回建议的等待时间
+func backpressure(err error) (*ExecutionError, bool) {
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) && backpressureCodes[execErr.Code] {
+		return execErr, true
+	}
+	return nil, false
+}
+
+// decodeExecuteResponse 解析执行服务的响应；202 表示已进入执行队列
+func decodeExecuteResponse(status int, header http.Header, body io.Reader) (*ExecuteTradeResponse, bool, error) {
+	if status != http.StatusOK && status != http.StatusAccepted {
+		return nil, isRetryableStatus(status), decodeExecutionError(status, header, body)
+	}
+
+	var execResp ExecuteTradeResponse
+	if err := json.NewDecoder(body).Decode(&execResp); err != nil {
+		return nil, false, fmt.Errorf("解析执行响应失败: %w", err)
+	}
+	return &execResp, false, nil
+}
+
+// decodeJobResponse 解析执行任务状态查询的响应
+func decodeJobResponse(status int, header http.Header, body io.Reader) (*JobStatus, bool, error) {
+	if status != http.StatusOK {
+		return nil, isRetryableStatus(status), decodeExecutionError(status, header, body)
+	}
+
+	var job JobStatus
+	if err := json.NewDecoder(body).Decode(&job); err != nil {
+		return nil, false, fmt.Errorf("解析执行任务状态失败: %w", err)
+	}
+	return &job, false, nil
+}
+
+// decodeExecutionError 将执行服务的错误响应转换为 ExecutionError
+func decodeExecutionError(status int, header http.Header, body io.Reader) *ExecutionError {
+	var errBody struct {
+		Error     string `json:"error"`
+		Code      string `json:"code"`
+		Permanent bool   `json:"permanent"`
+	}
+	_ = json.NewDecoder(body).Decode(&errBody)
+	return &ExecutionError{
+		Status:     status,
+		Code:       errBody.Code,
+		Message:    errBody.Error,
+		Permanent:  errBody.Permanent,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter 解析以秒为单位的 Retry-After 头，无法解析时返回 0
+func parseRetryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// InProcessExecutor 在进程内直接调用执行服务的 HTTP 处理器，不经过网络。
+// 用于单进程部署（撮合与执行运行在同一二进制中）以及测试。
+// 请求同样经过签名，与执行服务的认证中间件配合使用。
+type InProcessExecutor struct {
+	handler http.Handler
+	auth    *hmacauth.Authenticator
+}
+
+// NewInProcessExecutor 基于执行服务的 http.Handler 创建进程内执行器
+func NewInProcessExecutor(handler http.Handler, auth *hmacauth.Authenticator) *InProcessExecutor {
+	return &InProcessExecutor{handler: handler, auth: auth}
+}
+
+// Execute 将请求直接交给执行服务处理器，并解析其响应
+func (p *InProcessExecutor) Execute(ctx context.Context, req *ExecuteTradeRequest) (*ExecuteTradeResponse, error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("序列化执行请求失败: %w", err)
+	}
+
+	rec, err := p.serve(ctx, http.MethodPost, executePath, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, _, err := decodeExecuteResponse(rec.Status, rec.Header(), &rec.Body)
+	return resp, err
+}
+
+// Job 通过执行服务处理器查询执行任务状态
+func (p *InProcessExecutor) Job(ctx context.Context, id uint64) (*JobStatus, error) {
+	rec, err := p.serve(ctx, http.MethodGet, jobsPath+strconv.FormatUint(id, 10), nil)
+	if err != nil {
+		return nil, err
+	}
+	job, _, err := decodeJobResponse(rec.Status, rec.Header(), &rec.Body)
+	return job, err
+}
+
+// serve 构造签名请求并交给执行服务处理器
+func (p *InProcessExecutor) serve(ctx context.Context, method, path string, body []byte) (*hmacauth.ResponseBuffer, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if _, err := p.auth.SignRequest(httpReq, body); err != nil {
+		return nil, fmt.Errorf("签名执行请求失败: %w", err)
+	}
+
+	rec := hmacauth.NewResponseBuffer()
+	p.handler.ServeHTTP(rec, httpReq)
+	return rec, nil
+}
@@ -0,0 +1,558 @@
+package matching
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/contracts"
+	"github.com/Oeasy-NFT/services/internal/logger"
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 链上可成交性检查的默认参数
+const (
+	defaultFillabilityCacheTTL = 10 * time.Second
+	fillabilityConcurrency     = 8
+)
+
+// 订单不可成交的原因
+const (
+	ReasonNonceConsumed         = "nonce consumed on-chain"
+	ReasonSellerNotOwner        = "seller no longer owns token"
+	ReasonNFTNotApproved        = "marketplace not approved for token"
+	ReasonInsufficientBalance   = "insufficient payment token balance"
+	ReasonInsufficientAllowance = "insufficient payment token allowance"
+)
+
+// ChainReader 提供可成交性检查所需的链上只读查询
+type ChainReader interface {
+	ConsumedNonce(opts *bind.CallOpts, maker common.Address, nonce *big.Int) (bool, error)
+	OwnerOf(opts *bind.CallOpts, nft common.Address, tokenID *big.Int) (common.Address, error)
+	GetApproved(opts *bind.CallOpts, nft common.Address, tokenID *big.Int) (common.Address, error)
+	IsApprovedForAll(opts *bind.CallOpts, nft common.Address, owner, operator common.Address) (bool, error)
+	BalanceOf(opts *bind.CallOpts, token common.Address, owner common.Address) (*big.Int, error)
+	Allowance(opts *bind.CallOpts, token common.Address, owner, spender common.Address) (*big.Int, error)
+}
+
+// bindingReader 基于生成的合约绑定实现 ChainReader，按地址缓存合约实例
+type bindingReader struct {
+	caller      bind.ContractCaller
+	marketplace *contracts.OeasyMarketplaceCaller
+	mu          sync.Mutex
+	nfts        map[common.Address]*contracts.OeasyNFTCaller
+	tokens      map[common.Address]*contracts.MockUSDCCaller
+}
+
+// NewBindingReader 创建基于合约绑定的链上读取器
+func NewBindingReader(caller bind.ContractCaller, marketplace common.Address) (ChainReader, error) {
+	mp, err := contracts.NewOeasyMarketplaceCaller(marketplace, caller)
+	if err != nil {
+		return nil, err
+	}
+	return &bindingReader{
+		caller:      caller,
+		marketplace: mp,
+		nfts:        make(map[common.Address]*contracts.OeasyNFTCaller),
+		tokens:      make(map[common.Address]*contracts.MockUSDCCaller),
+	}, nil
+}
+
+func (r *bindingReader) nft(addr common.Address) (*contracts.OeasyNFTCaller, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.nfts[addr]; ok {
+		return c, nil
+	}
+	c, err := contracts.NewOeasyNFTCaller(addr, r.caller)
+	if err != nil {
+		return nil, err
+	}
+	r.nfts[addr] = c
+	return c, nil
+}
+
+func (r *bindingReader) token(addr common.Address) (*contracts.MockUSDCCaller, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.tokens[addr]; ok {
+		return c, nil
+	}
+	c, err := contracts.NewMockUSDCCaller(addr, r.caller)
+	if err != nil {
+		return nil, err
+	}
+	r.tokens[addr] = c
+	return c, nil
+}
+
+func (r *bindingReader) ConsumedNonce(opts *bind.CallOpts, maker common.Address, nonce *big.Int) (bool, error) {
+	return r.marketplace.ConsumedNonces(opts, maker, nonce)
+}
+
+func (r *bindingReader) OwnerOf(opts *bind.CallOpts, nft common.Address, tokenID *big.Int) (common.Address, error) {
+	c, err := r.nft(nft)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return c.OwnerOf(opts, tokenID)
+}
+
+func (r *bindingReader) GetApproved(opts *bind.CallOpts, nft common.Address, tokenID *big.Int) (common.Address, error) {
+	c, err := r.nft(nft)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return c.GetApproved(opts, tokenID)
+}
+
+func (r *bindingReader) IsApprovedForAll(opts *bind.CallOpts, nft common.Address, owner, operator common.Address) (bool, error) {
+	c, err := r.nft(nft)
+	if err != nil {
+		return false, err
+	}
+	return c.IsApprovedForAll(opts, owner, operator)
+}
+
+func (r *bindingReader) BalanceOf(opts *bind.CallOpts, token common.Address, owner common.Address) (*big.Int, error) {
+	c, err := r.token(token)
+	if err != nil {
+		return nil, err
+	}
+	return c.BalanceOf(opts, owner)
+}
+
+func (r *bindingReader) Allowance(opts *bind.CallOpts, token common.Address, owner, spender common.Address) (*big.Int, error) {
+	c, err := r.token(token)
+	if err != nil {
+		return nil, err
+	}
+	return c.Allowance(opts, owner, spender)
+}
+
+// FillabilityResult 是一轮检查的结果
+type FillabilityResult struct {
+	// Invalid 记录确定不可成交的订单（key 为订单哈希，value 为原因）
+	Invalid map[string]string
+	// Unknown 记录因链上读取失败而无法判断的订单哈希，本轮应跳过但不标记无效
+	Unknown map[string]error
+}
+
+// FillabilityChecker 在提交执行前检查订单对是否仍可在链上成交。
+// 每轮撮合收集所有需要的读取并去重后并发执行，结果在短 TTL 内缓存。
+type FillabilityChecker struct {
+	reader      ChainReader
+	marketplace common.Address
+	ttl         time.Duration
+	now         func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedRead
+}
+
+type cachedRead struct {
+	value   any
+	expires time.Time
+}
+
+// NewFillabilityChecker 创建可成交性检查器
+func NewFillabilityChecker(reader ChainReader, marketplace common.Address, ttl time.Duration) *FillabilityChecker {
+	if ttl <= 0 {
+		ttl = defaultFillabilityCacheTTL
+	}
+	return &FillabilityChecker{
+		reader:      reader,
+		marketplace: marketplace,
+		ttl:         ttl,
+		now:         time.Now,
+		cache:       make(map[string]cachedRead),
+	}
+}
+
+// chainRead 是一次可缓存的链上读取
+type chainRead struct {
+	key string
+	fn  func(opts *bind.CallOpts) (any, error)
+}
+
+// Check 检查一批订单对涉及的所有订单
+func (c *FillabilityChecker) Check(ctx context.Context, matches []MatchPair) *FillabilityResult {
+	result := &FillabilityResult{
+		Invalid: make(map[string]string),
+		Unknown: make(map[string]error),
+	}
+
+	// 第一步：收集本轮需要的读取（去重）
+	reads := make(map[string]chainRead)
+	for _, m := range matches {
+		for _, r := range c.readsFor(m) {
+			reads[r.key] = r
+		}
+	}
+
+	// 第二步：批量执行未命中缓存的读取
+	values := c.execute(ctx, reads)
+
+	// 第三步：根据读取结果判定每个订单
+	for _, m := range matches {
+		if _, done := result.Invalid[m.Ask.Hash]; !done {
+			c.judgeAsk(m.Ask, values, result)
+		}
+		if _, done := result.Invalid[m.Bid.Hash]; !done {
+			c.judgeBid(m.Bid, m.Ask, values, result)
+		}
+	}
+	return result
+}
+
+// readsFor 列出判定一个订单对所需的链上读取
+func (c *FillabilityChecker) readsFor(m MatchPair) []chainRead {
+	seller := common.HexToAddress(m.Ask.Maker)
+	buyer := common.HexToAddress(m.Bid.Maker)
+	nft := common.HexToAddress(m.Ask.NFTAddress)
+	token := common.HexToAddress(m.Bid.PaymentToken)
+	tokenID, _ := new(big.Int).SetString(m.Ask.TokenID.String(), 10)
+	askNonce, _ := new(big.Int).SetString(m.Ask.Nonce.String(), 10)
+	bidNonce, _ := new(big.Int).SetString(m.Bid.Nonce.String(), 10)
+
+	reads := []chainRead{}
+	if askNonce != nil {
+		reads = append(reads, c.nonceRead(seller, askNonce))
+	}
+	if bidNonce != nil {
+		reads = append(reads, c.nonceRead(buyer, bidNonce))
+	}
+	if tokenID != nil {
+		reads = append(reads,
+			chainRead{key: ownerKey(nft, tokenID), fn: func(opts *bind.CallOpts) (any, error) {
+				return c.reader.OwnerOf(opts, nft, tokenID)
+			}},
+			chainRead{key: approvedKey(nft, tokenID), fn: func(opts *bind.CallOpts) (any, error) {
+				return c.reader.GetApproved(opts, nft, tokenID)
+			}},
+		)
+	}
+	reads = append(reads,
+		chainRead{key: approvedForAllKey(nft, seller), fn: func(opts *bind.CallOpts) (any, error) {
+			return c.reader.IsApprovedForAll(opts, nft, seller, c.marketplace)
+		}},
+		chainRead{key: balanceKey(token, buyer), fn: func(opts *bind.CallOpts) (any, error) {
+			return c.reader.BalanceOf(opts, token, buyer)
+		}},
+		chainRead{key: allowanceKey(token, buyer), fn: func(opts *bind.CallOpts) (any, error) {
+			return c.reader.Allowance(opts, token, buyer, c.marketplace)
+		}},
+	)
+	return reads
+}
+
+func (c *FillabilityChecker) nonceRead(maker common.Address, nonce *big.Int) chainRead {
+	return chainRead{key: nonceKey(maker, nonce), fn: func(opts *bind.CallOpts) (any, error) {
+		return c.reader.ConsumedNonce(opts, maker, nonce)
+	}}
+}
+
+// execute 并发执行未缓存的读取，返回 key -> 值或错误
+func (c *FillabilityChecker) execute(ctx context.Context, reads map[string]chainRead) map[string]any {
+	values := make(map[string]any, len(reads))
+	pending := make([]chainRead, 0, len(reads))
+
+	now := c.now()
+	c.mu.Lock()
+	for key, r := range reads {
+		if entry, ok := c.cache[key]; ok && now.Before(entry.expires) {
+			values[key] = entry.value
+			continue
+		}
+		pending = append(pending, r)
+	}
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return values
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, fillabilityConcurrency)
+	)
+	for _, r := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r chainRead) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			v, err := r.fn(opts)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				values[r.key] = err
+				return
+			}
+			values[r.key] = v
+		}(r)
+	}
+	wg.Wait()
+
+	expires := c.now().Add(c.ttl)
+	c.mu.Lock()
+	for _, r := range pending {
+		if _, failed := values[r.key].(error); failed {
+			continue
+		}
+		c.cache[r.key] = cachedRead{value: values[r.key], expires: expires}
+	}
+	// 顺便清理过期缓存，避免无限增长
+	for key, entry := range c.cache {
+		if !now.Before(entry.expires) {
+			delete(c.cache, key)
+		}
+	}
+	c.mu.Unlock()
+
+	return values
+}
+
+// judgeAsk 判定卖单：nonce 未消费、卖方仍持有 NFT、市场合约已获授权
+func (c *FillabilityChecker) judgeAsk(ask Order, values map[string]any, result *FillabilityResult) {
+	seller := common.HexToAddress(ask.Maker)
+	nft := common.HexToAddress(ask.NFTAddress)
+	tokenID, ok := new(big.Int).SetString(ask.TokenID.String(), 10)
+	nonce, ok2 := new(big.Int).SetString(ask.Nonce.String(), 10)
+	if !ok || !ok2 {
+		result.Invalid[ask.Hash] = "malformed order fields"
+		return
+	}
+
+	consumed, err := readValue[bool](values, nonceKey(seller, nonce))
+	if err != nil {
+		result.Unknown[ask.Hash] = err
+		return
+	}
+	if consumed {
+		result.Invalid[ask.Hash] = ReasonNonceConsumed
+		return
+	}
+
+	owner, err := readValue[common.Address](values, ownerKey(nft, tokenID))
+	if err != nil {
+		// ownerOf 对不存在（已销毁）的 token 会 revert，此时卖单同样不可成交
+		if isRevert(err) {
+			result.Invalid[ask.Hash] = ReasonSellerNotOwner
+			return
+		}
+		result.Unknown[ask.Hash] = err
+		return
+	}
+	if owner != seller {
+		result.Invalid[ask.Hash] = ReasonSellerNotOwner
+		return
+	}
+
+	approvedAll, err := readValue[bool](values, approvedForAllKey(nft, seller))
+	if err != nil {
+		result.Unknown[ask.Hash] = err
+		return
+	}
+	if approvedAll {
+		return
+	}
+	approved, err := readValue[common.Address](values, approvedKey(nft, tokenID))
+	if err != nil {
+		result.Unknown[ask.Hash] = err
+		return
+	}
+	if approved != c.marketplace {
+		result.Invalid[ask.Hash] = ReasonNFTNotApproved
+	}
+}
+
+// judgeBid 判定买单：nonce 未消费、余额和授权额度足以支付成交价（即 ask 价格）
+func (c *FillabilityChecker) judgeBid(bid Order, ask Order, values map[string]any, result *FillabilityResult) {
+	buyer := common.HexToAddress(bid.Maker)
+	token := common.HexToAddress(bid.PaymentToken)
+	nonce, ok := new(big.Int).SetString(bid.Nonce.String(), 10)
+	price, ok2 := new(big.Int).SetString(ask.Price.String(), 10)
+	if !ok || !ok2 {
+		result.Invalid[bid.Hash] = "malformed order fields"
+		return
+	}
+
+	consumed, err := readValue[bool](values, nonceKey(buyer, nonce))
+	if err != nil {
+		result.Unknown[bid.Hash] = err
+		return
+	}
+	if consumed {
+		result.Invalid[bid.Hash] = ReasonNonceConsumed
+		return
+	}
+
+	balance, err := readValue[*big.Int](values, balanceKey(token, buyer))
+	if err != nil {
+		result.Unknown[bid.Hash] = err
+		return
+	}
+	if balance.Cmp(price) < 0 {
+		result.Invalid[bid.Hash] = ReasonInsufficientBalance
+		return
+	}
+
+	allowance, err := readValue[*big.Int](values, allowanceKey(token, buyer))
+	if err != nil {
+		result.Unknown[bid.Hash] = err
+		return
+	}
+	if allowance.Cmp(price) < 0 {
+		result.Invalid[bid.Hash] = ReasonInsufficientAllowance
+	}
+}
+
+// readValue 从读取结果中取出指定类型的值
+func readValue[T any](values map[string]any, key string) (T, error) {
+	var zero T
+	v, ok := values[key]
+	if !ok {
+		return zero, fmt.Errorf("missing chain read %s", key)
+	}
+	if err, isErr := v.(error); isErr {
+		return zero, err
+	}
+	typed, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("unexpected type for chain read %s", key)
+	}
+	return typed, nil
+}
+
+// isRevert 判断读取错误是否为合约 revert（而非网络等临时错误）
+func isRevert(err error) bool {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+func nonceKey(maker common.Address, nonce *big.Int) string {
+	return "nonce:" + strings.ToLower(maker.Hex()) + ":" + nonce.String()
+}
+
+func ownerKey(nft common.Address, tokenID *big.Int) string {
+	return "owner:" + strings.ToLower(nft.Hex()) + ":" + tokenID.String()
+}
+
+func approvedKey(nft common.Address, tokenID *big.Int) string {
+	return "approved:" + strings.ToLower(nft.Hex()) + ":" + tokenID.String()
+}
+
+func approvedForAllKey(nft common.Address, owner common.Address) string {
+	return "approvedForAll:" + strings.ToLower(nft.Hex()) + ":" + strings.ToLower(owner.Hex())
+}
+
+func balanceKey(token common.Address, owner common.Address) string {
+	return "balance:" + strings.ToLower(token.Hex()) + ":" + strings.ToLower(owner.Hex())
+}
+
+func allowanceKey(token common.Address, owner common.Address) string {
+	return "allowance:" + strings.ToLower(token.Hex()) + ":" + strings.ToLower(owner.Hex())
+}
+
+// filterFillable 过滤掉链上已不可成交的订单对：确定无效的订单被标记为 invalid 并移出订单簿，
+// 读取失败的订单对本轮跳过，下一轮重新检查
+func (e *Engine) filterFillable(ctx context.Context, matches []MatchPair) []MatchPair {
+	result := e.fillability.Check(ctx, matches)
+
+	invalidated := make(map[string]struct{})
+	fillable := make([]MatchPair, 0, len(matches))
+	for _, m := range matches {
+		rejected := false
+		for _, ord := range []Order{m.Ask, m.Bid} {
+			reason, invalid := result.Invalid[ord.Hash]
+			if !invalid {
+				continue
+			}
+			rejected = true
+			if _, done := invalidated[ord.Hash]; done {
+				continue
+			}
+			invalidated[ord.Hash] = struct{}{}
+			if err := e.invalidateOrder(ctx, ord, reason); err != nil {
+				logger.Error("标记订单无效失败", err, "hash", ord.Hash, "reason", reason)
+			}
+		}
+		if rejected {
+			continue
+		}
+
+		if err, unknown := result.Unknown[m.Ask.Hash]; unknown {
+			logger.Warn("链上可成交性检查失败，本轮跳过", "hash", m.Ask.Hash, "error", err)
+			continue
+		}
+		if err, unknown := result.Unknown[m.Bid.Hash]; unknown {
+			logger.Warn("链上可成交性检查失败，本轮跳过", "hash", m.Bid.Hash, "error", err)
+			continue
+		}
+		fillable = append(fillable, m)
+	}
+	return fillable
+}
+
+// isFundingReason 判断不可成交原因是否为买方资金不足（余额或授权额度），这类买单在资金恢复后可重新激活
+func isFundingReason(reason string) bool {
+	return reason == ReasonInsufficientBalance || reason == ReasonInsufficientAllowance
+}
+
+// invalidateOrder 将订单标记为不可成交（记录原因）并从活跃订单簿缓存中移除：
+// 资金不足的买单标记为 unfunded，由索引服务在资金恢复后改回 active；其他原因标记为 invalid
+func (e *Engine) invalidateOrder(ctx context.Context, ord Order, reason string) error {
+	status := orders.OrderStatusInvalid
+	if isFundingReason(reason) {
+		status = orders.OrderStatusUnfunded
+	}
+	if e.orderRepo != nil {
+		mark := e.orderRepo.MarkInvalid
+		if status == orders.OrderStatusUnfunded {
+			mark = e.orderRepo.MarkUnfunded
+		}
+		if _, err := mark(ctx, ord.Hash, reason); err != nil {
+			return err
+		}
+	}
+	if err := e.redisClient.HDel(ctx, "orders:active:"+ord.Side, ord.Hash).Err(); err != nil {
+		return err
+	}
+	e.stats.recordInvalidated()
+
+	payload, err := json.Marshal(struct {
+		OrderID uint      `json:"orderId"`
+		Maker   string    `json:"maker"`
+		Nonce   string    `json:"nonce"`
+		Hash    string    `json:"hash"`
+		Reason  string    `json:"reason"`
+		Time    time.Time `json:"time"`
+	}{OrderID: ord.ID, Maker: ord.Maker, Nonce: ord.Nonce.String(), Hash: ord.Hash, Reason: reason, Time: time.Now()})
+	if err == nil {
+		_ = e.redisClient.Publish(ctx, "orders:invalid", payload).Err()
+	}
+
+	logger.Warn("订单已标记为不可成交",
+		"hash", ord.Hash,
+		"maker", ord.Maker,
+		"side", ord.Side,
+		"status", status,
+		"reason", reason,
+	)
+	return nil
+}
@@ -0,0 +1,219 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var (
+	testMarketplace = common.HexToAddress("0x0000000000000000000000000000000000000001")
+	testSeller      = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+	testBuyer       = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+)
+
+// fakeChainReader serves fixed on-chain state and counts reads.
+type fakeChainReader struct {
+	owner     common.Address
+	approved  bool
+	balance   *big.Int
+	allowance *big.Int
+	consumed  map[common.Address]bool
+	ownerErr  error
+	reads     atomic.Int32
+}
+
+func (f *fakeChainReader) ConsumedNonce(_ *bind.CallOpts, maker common.Address, _ *big.Int) (bool, error) {
+	f.reads.Add(1)
+	return f.consumed[maker], nil
+}
+
+func (f *fakeChainReader) OwnerOf(_ *bind.CallOpts, _ common.Address, _ *big.Int) (common.Address, error) {
+	f.reads.Add(1)
+	return f.owner, f.ownerErr
+}
+
+func (f *fakeChainReader) GetApproved(_ *bind.CallOpts, _ common.Address, _ *big.Int) (common.Address, error) {
+	f.reads.Add(1)
+	return common.Address{}, nil
+}
+
+func (f *fakeChainReader) IsApprovedForAll(_ *bind.CallOpts, _ common.Address, _, _ common.Address) (bool, error) {
+	f.reads.Add(1)
+	return f.approved, nil
+}
+
+func (f *fakeChainReader) BalanceOf(_ *bind.CallOpts, _ common.Address, _ common.Address) (*big.Int, error) {
+	f.reads.Add(1)
+	return f.balance, nil
+}
+
+func (f *fakeChainReader) Allowance(_ *bind.CallOpts, _ common.Address, _, _ common.Address) (*big.Int, error) {
+	f.reads.Add(1)
+	return f.allowance, nil
+}
+
+func fillableReader() *fakeChainReader {
+	return &fakeChainReader{
+		owner:     testSeller,
+		approved:  true,
+		balance:   big.NewInt(1000),
+		allowance: big.NewInt(1000),
+		consumed:  map[common.Address]bool{},
+	}
+}
+
+func fillabilityPair() MatchPair {
+	return MatchPair{
+		Ask: Order{Maker: testSeller.Hex(), NFTAddress: "0x0000000000000000000000000000000000000002", TokenID: "1",
+			PaymentToken: "0x0000000000000000000000000000000000000003", Price: "500", Nonce: "1", Side: "ask", Hash: "0xask"},
+		Bid: Order{Maker: testBuyer.Hex(), NFTAddress: "0x0000000000000000000000000000000000000002", TokenID: "1",
+			PaymentToken: "0x0000000000000000000000000000000000000003", Price: "600", Nonce: "7", Side: "bid", Hash: "0xbid"},
+	}
+}
+
+// TestFillabilityChecker_Reasons validates each unfillable condition maps to the right order and reason.
+func TestFillabilityChecker_Reasons(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(*fakeChainReader)
+		hash   string
+		reason string
+	}{
+		{"seller moved token", func(f *fakeChainReader) { f.owner = testBuyer }, "0xask", ReasonSellerNotOwner},
+		{"token burned", func(f *fakeChainReader) { f.ownerErr = errors.New("execution reverted") }, "0xask", ReasonSellerNotOwner},
+		{"approval revoked", func(f *fakeChainReader) { f.approved = false }, "0xask", ReasonNFTNotApproved},
+		{"ask nonce consumed", func(f *fakeChainReader) { f.consumed[testSeller] = true }, "0xask", ReasonNonceConsumed},
+		{"bid nonce consumed", func(f *fakeChainReader) { f.consumed[testBuyer] = true }, "0xbid", ReasonNonceConsumed},
+		{"buyer balance too low", func(f *fakeChainReader) { f.balance = big.NewInt(499) }, "0xbid", ReasonInsufficientBalance},
+		{"buyer allowance too low", func(f *fakeChainReader) { f.allowance = big.NewInt(10) }, "0xbid", ReasonInsufficientAllowance},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := fillableReader()
+			tc.mutate(reader)
+			checker := NewFillabilityChecker(reader, testMarketplace, time.Minute)
+
+			result := checker.Check(context.Background(), []MatchPair{fillabilityPair()})
+			require.Len(t, result.Invalid, 1)
+			require.Equal(t, tc.reason, result.Invalid[tc.hash])
+			require.Empty(t, result.Unknown)
+		})
+	}
+}
+
+// TestFillabilityChecker_TransientErrorIsUnknown ensures RPC failures skip the pair without invalidating it.
+func TestFillabilityChecker_TransientErrorIsUnknown(t *testing.T) {
+	reader := fillableReader()
+	reader.ownerErr = errors.New("connection refused")
+	checker := NewFillabilityChecker(reader, testMarketplace, time.Minute)
+
+	result := checker.Check(context.Background(), []MatchPair{fillabilityPair()})
+	require.Empty(t, result.Invalid)
+	require.Contains(t, result.Unknown, "0xask")
+}
+
+// TestFillabilityChecker_CachesReads ensures repeated checks within the TTL reuse cached reads.
+func TestFillabilityChecker_CachesReads(t *testing.T) {
+	reader := fillableReader()
+	checker := NewFillabilityChecker(reader, testMarketplace, time.Minute)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	pair := fillabilityPair()
+	result := checker.Check(context.Background(), []MatchPair{pair, pair})
+	require.Empty(t, result.Invalid)
+	first := reader.reads.Load()
+	require.Equal(t, int32(7), first, "duplicate pairs share deduplicated reads")
+
+	checker.Check(context.Background(), []MatchPair{pair})
+	require.Equal(t, first, reader.reads.Load(), "reads within TTL are served from cache")
+
+	now = now.Add(2 * time.Minute)
+	checker.Check(context.Background(), []MatchPair{pair})
+	require.Equal(t, 2*first, reader.reads.Load())
+}
+
+// TestMatchOrders_InvalidatesUnfillableOrder ensures an unfillable order is dropped from the book
+// instead of being submitted and retried.
+func TestMatchOrders_InvalidatesUnfillableOrder(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	exec := newFailingExecutionServer(t, engine)
+	defer exec.Close()
+
+	ask, bid := seedMatchingPair(t, redisClient)
+	reader := fillableReader()
+	reader.owner = common.HexToAddress(bid.Maker)
+	reader.balance, _ = new(big.Int).SetString(bid.Price.String(), 10)
+	reader.allowance = reader.balance
+	engine.fillability = NewFillabilityChecker(reader, testMarketplace, time.Minute)
+
+	ctx := context.Background()
+	require.NoError(t, engine.matchOrders(ctx))
+
+	require.False(t, redisClient.HExists(ctx, "orders:active:ask", ask.Hash).Val(), "invalid ask removed from book")
+	require.True(t, redisClient.HExists(ctx, "orders:active:bid", bid.Hash).Val(), "counterparty stays live")
+	require.Empty(t, engine.failures.pairs, "invalid pairs are not submitted or retried")
+	require.Equal(t, uint64(1), engine.stats.invalidated)
+}
+
+// TestMatchOrders_UnfundedBidIsNotInvalidated keeps bids that fail only on funding
+// recoverable: they become unfunded, which the indexer reactivates, while an ask whose seller
+// moved the token becomes invalid.
+func TestMatchOrders_UnfundedBidIsNotInvalidated(t *testing.T) {
+	engine, redisClient, cleanup := setupTestMatchingEngine(t)
+	defer cleanup()
+
+	exec := newFailingExecutionServer(t, engine)
+	defer exec.Close()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&orders.Order{}))
+	engine.orderRepo = orders.NewRepository(db)
+
+	ask, bid := seedMatchingPair(t, redisClient)
+	require.NoError(t, db.Create([]orders.Order{
+		{Maker: ask.Maker, Nonce: "1", Side: "ask", Hash: ask.Hash, Status: orders.OrderStatusActive},
+		{Maker: bid.Maker, Nonce: "1", Side: "bid", Hash: bid.Hash, Status: orders.OrderStatusActive},
+	}).Error)
+
+	reader := fillableReader()
+	reader.owner = common.HexToAddress(ask.Maker)
+	reader.balance = big.NewInt(1)
+	engine.fillability = NewFillabilityChecker(reader, testMarketplace, time.Minute)
+
+	ctx := context.Background()
+	require.NoError(t, engine.matchOrders(ctx))
+
+	var stored orders.Order
+	require.NoError(t, db.Where("hash = ?", bid.Hash).First(&stored).Error)
+	require.Equal(t, orders.OrderStatusUnfunded, stored.Status)
+	require.Equal(t, ReasonInsufficientBalance, stored.InvalidReason)
+	require.False(t, redisClient.HExists(ctx, "orders:active:bid", bid.Hash).Val())
+
+	reader.owner = testBuyer
+	reader.balance = big.NewInt(0).Lsh(big.NewInt(1), 80)
+	seedMatchingPair(t, redisClient)
+	engine.fillability = NewFillabilityChecker(reader, testMarketplace, time.Minute)
+	require.NoError(t, db.Model(&orders.Order{}).Where("hash = ?", bid.Hash).Update("status", orders.OrderStatusActive).Error)
+	require.NoError(t, engine.matchOrders(ctx))
+
+	var storedAsk orders.Order
+	require.NoError(t, db.Where("hash = ?", ask.Hash).First(&storedAsk).Error)
+	require.Equal(t, orders.OrderStatusInvalid, storedAsk.Status)
+	require.Equal(t, ReasonSellerNotOwner, storedAsk.InvalidReason)
+}
@@ -0,0 +1,113 @@
+package matching
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/logger"
+)
+
+// inflightKey 是 Redis 中提交中订单对的哈希表键，field 为执行 ID
+const inflightKey = "matching:inflight"
+
+// inflightTrade 记录执行服务已受理（202）但尚未广播的订单对。
+// 提交期间订单保留在活跃订单簿中但不参与撮合，直到任务广播或最终失败。
+type inflightTrade struct {
+	ExecutionID uint64    `json:"executionId"`
+	PairKey     string    `json:"pairKey"`
+	Ask         Order     `json:"ask"`
+	Bid         Order     `json:"bid"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+// trackExecution 记录已入队的订单对，之后每轮撮合跟进其执行任务状态
+func (e *Engine) trackExecution(ctx context.Context, key string, match MatchPair, executionID uint64) error {
+	payload, err := json.Marshal(inflightTrade{
+		ExecutionID: executionID,
+		PairKey:     key,
+		Ask:         match.Ask,
+		Bid:         match.Bid,
+		SubmittedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return e.redisClient.HSet(ctx, inflightKey, strconv.FormatUint(executionID, 10), payload).Err()
+}
+
+// followExecutions 查询提交中订单对的执行任务：已广播的订单移出活跃订单簿；最终失败的订单留在订单簿中，
+// 按提交失败处理（计入退避，永久失败或次数超限时移入死信列表）。返回仍在执行中、本轮不参与撮合的订单哈希。
+func (e *Engine) followExecutions(ctx context.Context) (map[string]struct{}, error) {
+	entries, err := e.redisClient.HGetAll(ctx, inflightKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make(map[string]struct{}, 2*len(entries))
+	for field, payload := range entries {
+		var trade inflightTrade
+		if err := json.Unmarshal([]byte(payload), &trade); err != nil {
+			logger.Error("解析提交中订单对失败", err, "执行ID", field)
+			e.redisClient.HDel(ctx, inflightKey, field)
+			continue
+		}
+		match := MatchPair{Ask: trade.Ask, Bid: trade.Bid}
+
+		job, err := e.executor.Job(ctx, trade.ExecutionID)
+		var execErr *ExecutionError
+		switch {
+		case errors.As(err, &execErr) && execErr.Status == http.StatusNotFound:
+			// 任务记录已不存在，订单重新参与撮合
+			logger.Warn("执行任务不存在，订单对重新参与撮合", "执行ID", trade.ExecutionID, "pairKey", trade.PairKey)
+		case err != nil:
+			// 查询失败时保持提交中状态，下一轮再查
+			logger.Error("查询执行任务状态失败", err, "执行ID", trade.ExecutionID)
+			busy[trade.Ask.Hash] = struct{}{}
+			busy[trade.Bid.Hash] = struct{}{}
+			continue
+		case job.Status == JobStatusSubmitted:
+			e.failures.reset(trade.PairKey)
+
+			// 从 Redis 删除已广播的订单，避免重复撮合
+			// 注意：订单状态最终由索引服务更新，这里只是从缓存中移除
+			e.redisClient.HDel(ctx, "orders:active:ask", match.Ask.Hash)
+			e.redisClient.HDel(ctx, "orders:active:bid", match.Bid.Hash)
+			logger.Info("执行任务已广播，已从 Redis 缓存中移除订单",
+				"执行ID", trade.ExecutionID,
+				"交易哈希", job.TxHash,
+				"pairKey", trade.PairKey,
+			)
+		case job.Status == JobStatusFailed:
+			e.handleSubmitFailure(ctx, trade.PairKey, match, &JobError{
+				ExecutionID: trade.ExecutionID,
+				Code:        job.ErrorCode,
+				Message:     job.Error,
+				Permanent:   job.Permanent,
+			})
+		default:
+			busy[trade.Ask.Hash] = struct{}{}
+			busy[trade.Bid.Hash] = struct{}{}
+			continue
+		}
+		e.redisClient.HDel(ctx, inflightKey, field)
+	}
+	return busy, nil
+}
+
+// excludeOrders 过滤掉哈希在 hashes 中的订单
+func excludeOrders(orders []Order, hashes map[string]struct{}) []Order {
+	if len(hashes) == 0 {
+		return orders
+	}
+	result := orders[:0]
+	for _, ord := range orders {
+		if _, ok := hashes[ord.Hash]; !ok {
+			result = append(result, ord)
+		}
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package matching
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// MatchPolicy 决定 ask 与 bid 的配对方式
+type MatchPolicy string
+
+const (
+	// PolicyFirstFit 按订单 ID 顺序为每个 ask 选择第一个兼容的 bid（引擎原有行为）
+	PolicyFirstFit MatchPolicy = "first-fit"
+	// PolicyPriceTime 价格-时间优先：ask 按价格升序处理，每个 ask 匹配出价最高、最早的 bid，每个 bid 最多成交一次
+	PolicyPriceTime MatchPolicy = "price-time"
+)
+
+// ParseMatchPolicy 解析撮合策略名称，空字符串返回默认策略
+func ParseMatchPolicy(name string) (MatchPolicy, error) {
+	switch MatchPolicy(name) {
+	case "":
+		return PolicyFirstFit, nil
+	case PolicyFirstFit, PolicyPriceTime:
+		return MatchPolicy(name), nil
+	default:
+		return "", fmt.Errorf("unknown match policy %q", name)
+	}
+}
+
+// matchWith 按指定策略撮合订单。输入先按订单 ID 排序，保证相同输入得到相同结果。
+// excluded 中的订单对（pairKey，如死信列表）视为不兼容，ask 会继续尝试下一个 bid。
+func matchWith(policy MatchPolicy, asks []Order, bids []Order, excluded map[string]struct{}) []MatchPair {
+	asks = sortedByID(asks)
+	bids = sortedByID(bids)
+	compatible := func(ask, bid Order) bool {
+		if _, skip := excluded[pairKey(MatchPair{Ask: ask, Bid: bid})]; skip {
+			return false
+		}
+		return isMatch(ask, bid)
+	}
+
+	switch policy {
+	case PolicyPriceTime:
+		return matchPriceTime(asks, bids, compatible)
+	default:
+		return matchFirstFit(asks, bids, compatible)
+	}
+}
+
+// matchFirstFit 实现简单的 O(n*m) 撮合：每个 ask 最多匹配一个 bid
+func matchFirstFit(asks []Order, bids []Order, compatible func(ask, bid Order) bool) []MatchPair {
+	matches := make([]MatchPair, 0)
+	for _, ask := range asks {
+		for _, bid := range bids {
+			if compatible(ask, bid) {
+				matches = append(matches, MatchPair{Ask: ask, Bid: bid})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// matchPriceTime 实现价格-时间优先撮合
+func matchPriceTime(asks []Order, bids []Order, compatible func(ask, bid Order) bool) []MatchPair {
+	sort.SliceStable(asks, func(i, j int) bool { return comparePrice(asks[i], asks[j]) < 0 })
+	sort.SliceStable(bids, func(i, j int) bool { return comparePrice(bids[i], bids[j]) > 0 })
+
+	used := make([]bool, len(bids))
+	matches := make([]MatchPair, 0)
+	for _, ask := range asks {
+		for i, bid := range bids {
+			if used[i] || !compatible(ask, bid) {
+				continue
+			}
+			used[i] = true
+			matches = append(matches, MatchPair{Ask: ask, Bid: bid})
+			break
+		}
+	}
+	return matches
+}
+
+// sortedByID 返回按订单 ID（其次按哈希）排序的副本
+func sortedByID(orders []Order) []Order {
+	sorted := make([]Order, len(orders))
+	copy(sorted, orders)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ID != sorted[j].ID {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].Hash < sorted[j].Hash
+	})
+	return sorted
+}
+
+// isMatch 判断 ask 和 bid 订单是否兼容可执行
+func isMatch(ask Order, bid Order) bool {
+	// 必须是相同的 NFT
+	if ask.NFTAddress != bid.NFTAddress || ask.TokenID.String() != bid.TokenID.String() {
+		return false
+	}
+
+	// 必须使用相同的支付代币
+	if ask.PaymentToken != bid.PaymentToken {
+		return false
+	}
+
+	// Bid 价格必须达到或超过 ask 价格（买方愿意支付 >= 卖方要价）
+	// 这是标准订单簿撮合规则: bid >= ask
+	// MVP 中我们按 ask 价格执行（maker 获得其要求的价格）
+	// TODO: [撮合优化] - 实现价格改善逻辑或中间价执行
+	askPrice, askOk := new(big.Int).SetString(ask.Price.String(), 10)
+	bidPrice, bidOk := new(big.Int).SetString(bid.Price.String(), 10)
+	if !askOk || !bidOk {
+		return false
+	}
+
+	// Bid 必须 >= ask 才能形成有效匹配
+	return bidPrice.Cmp(askPrice) >= 0
+}
@@ -0,0 +1,148 @@
+package matching
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"gorm.io/gorm"
+)
+
+// Snapshot 是用于离线回放的订单簿快照
+type Snapshot struct {
+	Asks []Order
+	Bids []Order
+}
+
+// ReplayResult 是一次回放的输出
+type ReplayResult struct {
+	Policy  MatchPolicy `json:"policy"`
+	At      time.Time   `json:"at"`
+	Asks    int         `json:"asks"`
+	Bids    int         `json:"bids"`
+	Matches []MatchPair `json:"matches"`
+}
+
+// LoadSnapshot 解析 Redis 订单簿的 JSON 导出。
+// 格式为 {"orders:active:ask": {<hash>: <order>}, "orders:active:bid": {...}}，
+// 其中 <order> 可以是 HGETALL 导出的 JSON 字符串，也可以是 JSON 对象。
+func LoadSnapshot(r io.Reader) (*Snapshot, error) {
+	var dump map[string]map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&dump); err != nil {
+		return nil, fmt.Errorf("解析快照失败: %w", err)
+	}
+
+	snap := &Snapshot{}
+	for key, entries := range dump {
+		side := strings.TrimPrefix(key, "orders:active:")
+		if side != "ask" && side != "bid" {
+			return nil, fmt.Errorf("快照中存在未知的键: %s", key)
+		}
+
+		for hash, raw := range entries {
+			ord, err := decodeSnapshotOrder(raw)
+			if err != nil {
+				return nil, fmt.Errorf("解析订单 %s 失败: %w", hash, err)
+			}
+			if ord.Hash == "" {
+				ord.Hash = hash
+			}
+			if side == "ask" {
+				snap.Asks = append(snap.Asks, ord)
+			} else {
+				snap.Bids = append(snap.Bids, ord)
+			}
+		}
+	}
+	return snap, nil
+}
+
+// decodeSnapshotOrder 解析快照中的单个订单（字符串或对象）
+func decodeSnapshotOrder(raw json.RawMessage) (Order, error) {
+	var ord Order
+	var payload string
+	if err := json.Unmarshal(raw, &payload); err == nil {
+		raw = json.RawMessage(payload)
+	}
+	err := json.Unmarshal(raw, &ord)
+	return ord, err
+}
+
+// LoadSnapshotFromDB 从 Postgres 重建 [from, to] 窗口内存在过的订单簿：取窗口终点之前创建、
+// 且未在窗口起点之前离开订单簿的订单。结算中、成交、取消、失效和资金不足的订单以 updated_at
+// 作为离开时间；失效和资金不足的订单之后可能恢复为 active，但那样 updated_at 会随之更新，
+// 仍处于这两种状态且 updated_at 早于窗口的订单在整个窗口内都不可成交。
+// statuses 为空时包含所有状态，便于解释已成交或已取消订单当时的撮合结果。
+func LoadSnapshotFromDB(ctx context.Context, db *gorm.DB, from, to time.Time, statuses []string) (*Snapshot, error) {
+	finished := []orders.OrderStatus{
+		orders.OrderStatusSettling, orders.OrderStatusFilled, orders.OrderStatusCancelled,
+		orders.OrderStatusInvalid, orders.OrderStatusUnfunded,
+	}
+	query := db.WithContext(ctx).
+		Where("created_at <= ?", to).
+		Where("status NOT IN ? OR updated_at >= ?", finished, from)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
+	var rows []orders.Order
+	if err := query.Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{}
+	for _, row := range rows {
+		ord := Order{
+			ID:           row.ID,
+			Maker:        row.Maker,
+			NFTAddress:   row.NFTAddress,
+			TokenID:      FlexString(row.TokenID),
+			PaymentToken: row.PaymentToken,
+			Price:        FlexString(row.Price),
+			Expiry:       CustomTime{row.Expiry},
+			Nonce:        FlexString(row.Nonce),
+			Side:         row.Side,
+			Status:       string(row.Status),
+			Signature:    row.Signature,
+			Hash:         row.Hash,
+		}
+		switch row.Side {
+		case "ask":
+			snap.Asks = append(snap.Asks, ord)
+		case "bid":
+			snap.Bids = append(snap.Bids, ord)
+		}
+	}
+	return snap, nil
+}
+
+// Replay 以指定策略对快照进行撮合，只计算结果，不提交执行。
+// at 之前过期的订单会像在线引擎一样被过滤。
+func Replay(snap *Snapshot, policy MatchPolicy, at time.Time) *ReplayResult {
+	asks := filterExpired(snap.Asks, at)
+	bids := filterExpired(snap.Bids, at)
+
+	return &ReplayResult{
+		Policy:  policy,
+		At:      at,
+		Asks:    len(asks),
+		Bids:    len(bids),
+		Matches: matchWith(policy, asks, bids, nil),
+	}
+}
+
+// filterExpired 过滤在指定时间点已过期的订单
+func filterExpired(orders []Order, at time.Time) []Order {
+	result := make([]Order, 0, len(orders))
+	for _, ord := range orders {
+		if ord.Expiry.Time.Before(at) {
+			continue
+		}
+		result = append(result, ord)
+	}
+	return result
+}
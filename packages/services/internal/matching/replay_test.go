@@ -0,0 +1,92 @@
+package matching
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oeasy-NFT/services/internal/orders"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// replaySnapshot mixes HGETALL-style string payloads and plain JSON objects.
+const replaySnapshot = `{
+  "orders:active:ask": {
+    "0xask1": "{\"id\":1,\"maker\":\"0xaaa\",\"nftAddress\":\"0xnft\",\"tokenId\":\"1\",\"paymentToken\":\"0xusdc\",\"price\":\"100\",\"expiry\":\"2030-01-01T00:00:00Z\",\"nonce\":\"1\",\"side\":\"ask\"}"
+  },
+  "orders:active:bid": {
+    "0xbid2": {"id":2,"maker":"0xbbb","nftAddress":"0xnft","tokenId":1,"paymentToken":"0xusdc","price":"100","expiry":"2030-01-01T00:00:00Z","nonce":"1","side":"bid","hash":"0xbid2"},
+    "0xbid3": {"id":3,"maker":"0xccc","nftAddress":"0xnft","tokenId":"1","paymentToken":"0xusdc","price":"150","expiry":"2030-01-01T00:00:00Z","nonce":"1","side":"bid","hash":"0xbid3"},
+    "0xbid4": {"id":4,"maker":"0xddd","nftAddress":"0xnft","tokenId":"1","paymentToken":"0xusdc","price":"500","expiry":"2020-01-01T00:00:00Z","nonce":"1","side":"bid","hash":"0xbid4"}
+  }
+}`
+
+// TestReplay_PoliciesAreDeterministic validates snapshot parsing and policy-specific pairing.
+func TestReplay_PoliciesAreDeterministic(t *testing.T) {
+	snap, err := LoadSnapshot(strings.NewReader(replaySnapshot))
+	require.NoError(t, err)
+	require.Len(t, snap.Asks, 1)
+	require.Len(t, snap.Bids, 3)
+	require.Equal(t, "0xask1", snap.Asks[0].Hash, "hash falls back to the redis field")
+
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		result := Replay(snap, PolicyFirstFit, at)
+		require.Equal(t, 2, result.Bids, "expired bid is filtered out")
+		require.Len(t, result.Matches, 1)
+		require.Equal(t, "0xbid2", result.Matches[0].Bid.Hash, "first-fit picks the earliest bid")
+	}
+
+	result := Replay(snap, PolicyPriceTime, at)
+	require.Len(t, result.Matches, 1)
+	require.Equal(t, "0xbid3", result.Matches[0].Bid.Hash, "price-time picks the highest live bid")
+}
+
+// TestLoadSnapshotFromDB validates reconstructing the book from a Postgres time window.
+func TestLoadSnapshotFromDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:replay?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&orders.Order{}))
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []orders.Order{
+		{Maker: "0xaaa", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "100", Nonce: "1", Side: "ask", Status: orders.OrderStatusFilled, Hash: "0xa", Expiry: base.Add(48 * time.Hour), CreatedAt: base},
+		{Maker: "0xbbb", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "120", Nonce: "1", Side: "bid", Status: orders.OrderStatusFilled, Hash: "0xb", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(time.Hour)},
+		{Maker: "0xccc", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "130", Nonce: "1", Side: "bid", Status: orders.OrderStatusActive, Hash: "0xc", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(72 * time.Hour)},
+		// Created before the window: still on the book, finished before it, and finished inside it.
+		{Maker: "0xddd", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "200", Nonce: "1", Side: "ask", Status: orders.OrderStatusActive, Hash: "0xd", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(-24 * time.Hour), UpdatedAt: base.Add(-24 * time.Hour)},
+		{Maker: "0xeee", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "110", Nonce: "1", Side: "bid", Status: orders.OrderStatusCancelled, Hash: "0xe", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(-24 * time.Hour), UpdatedAt: base.Add(-12 * time.Hour)},
+		{Maker: "0xfff", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "90", Nonce: "1", Side: "bid", Status: orders.OrderStatusFilled, Hash: "0xf", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(-24 * time.Hour), UpdatedAt: base.Add(30 * time.Minute)},
+		// Invalid or unfunded since before the window, and invalidated inside it.
+		{Maker: "0x111", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "105", Nonce: "1", Side: "ask", Status: orders.OrderStatusInvalid, Hash: "0x10", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(-24 * time.Hour), UpdatedAt: base.Add(-12 * time.Hour)},
+		{Maker: "0x222", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "150", Nonce: "1", Side: "bid", Status: orders.OrderStatusUnfunded, Hash: "0x11", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(-24 * time.Hour), UpdatedAt: base.Add(-12 * time.Hour)},
+		{Maker: "0x333", NFTAddress: "0xnft", TokenID: "1", PaymentToken: "0xusdc", Price: "300", Nonce: "1", Side: "ask", Status: orders.OrderStatusInvalid, Hash: "0x12", Expiry: base.Add(48 * time.Hour), CreatedAt: base.Add(-24 * time.Hour), UpdatedAt: base.Add(time.Hour)},
+	}
+	require.NoError(t, db.Create(&rows).Error)
+
+	snap, err := LoadSnapshotFromDB(context.Background(), db, base, base.Add(2*time.Hour), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0xa", "0xd", "0x12"}, orderHashes(snap.Asks))
+	require.Equal(t, []string{"0xb", "0xf"}, orderHashes(snap.Bids))
+
+	result := Replay(snap, PolicyFirstFit, base.Add(2*time.Hour))
+	require.Len(t, result.Matches, 1)
+	require.Equal(t, "0xb", result.Matches[0].Bid.Hash)
+
+	snap, err = LoadSnapshotFromDB(context.Background(), db, base, base.Add(2*time.Hour), []string{"active"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"0xd"}, orderHashes(snap.Asks))
+	require.Len(t, snap.Bids, 0)
+}
+
+func orderHashes(list []Order) []string {
+	hashes := make([]string, 0, len(list))
+	for _, ord := range list {
+		hashes = append(hashes, ord.Hash)
+	}
+	return hashes
+}
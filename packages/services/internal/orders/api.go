@@ -37,20 +37,21 @@ type cancelOrderRequest struct {
 }
 
 type orderResponse struct {
-	ID           uint      `json:"id"`
-	Maker        string    `json:"maker"`
-	NFTAddress   string    `json:"nftAddress"`
-	TokenID      string    `json:"tokenId"`
-	PaymentToken string    `json:"paymentToken"`
-	Price        string    `json:"price"`
-	Expiry       time.Time `json:"expiry"`
-	Nonce        string    `json:"nonce"`
-	Side         string    `json:"side"`
-	Status       string    `json:"status"`
-	Signature    string    `json:"signature"`
-	Hash         string    `json:"hash"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID            uint      `json:"id"`
+	Maker         string    `json:"maker"`
+	NFTAddress    string    `json:"nftAddress"`
+	TokenID       string    `json:"tokenId"`
+	PaymentToken  string    `json:"paymentToken"`
+	Price         string    `json:"price"`
+	Expiry        time.Time `json:"expiry"`
+	Nonce         string    `json:"nonce"`
+	Side          string    `json:"side"`
+	Status        string    `json:"status"`
+	Signature     string    `json:"signature"`
+	Hash          string    `json:"hash"`
+	InvalidReason string    `json:"invalidReason,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
 // RegisterRoutes 注册订单相关的HTTP路由端点
@@ -98,7 +99,7 @@ func (s *Service) listOrders(c *gin.Context) {
 		// 默认或明确要求 active：使用原有逻辑
 		orders, err = s.repository.ListActive(c.Request.Context(), side, collection)
 	} else {
-		// 查询特定状态的订单（filled, cancelled）
+		// 查询特定状态的订单（settling=成交待确认, filled, cancelled, invalid, unfunded=买方资金不足）
 		orders, err = s.repository.ListByStatus(c.Request.Context(), status, side, collection)
 	}
 
@@ -202,20 +203,16 @@ func (s *Service) processCreateOrder(ctx context.Context, req *createOrderReques
 		return nil, ErrInvalidOrderPayload
 	}
 
-	message := apitypes.TypedDataMessage{
-		"maker":        strings.ToLower(makerAddr.Hex()),
-		"nft":          strings.ToLower(nftAddr.Hex()),
-		"tokenId":      tokenID,
-		"paymentToken": strings.ToLower(paymentTokenAddr.Hex()),
-		"price":        price,
-		"expiry":       big.NewInt(req.Expiry),
-		"nonce":        nonce,
-		"side":         big.NewInt(int64(sideValue)),
-	}
-
-	td := s.typedData
-	td.Message = message
-	digest, _, err := apitypes.TypedDataAndHash(td)
+	digest, err := HashOrder(s.typedData, OrderFields{
+		Maker:        makerAddr,
+		NFT:          nftAddr,
+		TokenID:      tokenID,
+		PaymentToken: paymentTokenAddr,
+		Price:        price,
+		Expiry:       big.NewInt(req.Expiry),
+		Nonce:        nonce,
+		Side:         sideValue,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -235,7 +232,7 @@ func (s *Service) processCreateOrder(ctx context.Context, req *createOrderReques
 		Side:         strings.ToLower(req.Side),
 		Status:       OrderStatusActive,
 		Signature:    strings.ToLower(req.Signature),
-		Hash:         hexutil.Encode(digest),
+		Hash:         hexutil.Encode(digest[:]),
 	}
 
 	if err := s.repository.Create(ctx, order); err != nil {
@@ -255,7 +252,8 @@ func (s *Service) processCancelOrder(ctx context.Context, id uint64, payload *ca
 		return err
 	}
 
-	if ord.Status != OrderStatusActive {
+	// 资金不足（unfunded）的买单也允许取消，否则资金恢复后会重新变为 active
+	if ord.Status != OrderStatusActive && ord.Status != OrderStatusUnfunded {
 		return ErrInvalidOrderPayload
 	}
 
@@ -379,19 +377,20 @@ func verifySignature(digest []byte, sig []byte, expected common.Address) bool {
 
 func toOrderResponse(ord *Order) orderResponse {
 	return orderResponse{
-		ID:           ord.ID,
-		Maker:        ord.Maker,
-		NFTAddress:   ord.NFTAddress,
-		TokenID:      ord.TokenID,
-		PaymentToken: ord.PaymentToken,
-		Price:        ord.Price,
-		Expiry:       ord.Expiry,
-		Nonce:        ord.Nonce,
-		Side:         ord.Side,
-		Status:       string(ord.Status),
-		Signature:    ord.Signature,
-		Hash:         ord.Hash,
-		CreatedAt:    ord.CreatedAt,
-		UpdatedAt:    ord.UpdatedAt,
+		ID:            ord.ID,
+		Maker:         ord.Maker,
+		NFTAddress:    ord.NFTAddress,
+		TokenID:       ord.TokenID,
+		PaymentToken:  ord.PaymentToken,
+		Price:         ord.Price,
+		Expiry:        ord.Expiry,
+		Nonce:         ord.Nonce,
+		Side:          ord.Side,
+		Status:        string(ord.Status),
+		Signature:     ord.Signature,
+		Hash:          ord.Hash,
+		InvalidReason: ord.InvalidReason,
+		CreatedAt:     ord.CreatedAt,
+		UpdatedAt:     ord.UpdatedAt,
 	}
 }
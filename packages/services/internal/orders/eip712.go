@@ -0,0 +1,83 @@
+package orders
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	mathhex "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// NewTypedData builds the EIP-712 typed data template matching the marketplace contract domain.
+// Callers copy the template and set PrimaryType/Message per request.
+func NewTypedData(chainID uint64, marketplace common.Address) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: map[string][]apitypes.Type{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Order": {
+				{Name: "maker", Type: "address"},
+				{Name: "nft", Type: "address"},
+				{Name: "tokenId", Type: "uint256"},
+				{Name: "paymentToken", Type: "address"},
+				{Name: "price", Type: "uint256"},
+				{Name: "expiry", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "side", Type: "uint8"},
+			},
+			"Cancel": {
+				{Name: "maker", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Order",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Oeasy Marketplace",
+			Version:           "1",
+			ChainId:           mathhex.NewHexOrDecimal256(int64(chainID)),
+			VerifyingContract: marketplace.Hex(),
+		},
+	}
+}
+
+// OrderFields holds the signed fields of an order in contract form.
+type OrderFields struct {
+	Maker        common.Address
+	NFT          common.Address
+	TokenID      *big.Int
+	PaymentToken common.Address
+	Price        *big.Int
+	Expiry       *big.Int
+	Nonce        *big.Int
+	Side         uint8
+}
+
+// HashOrder computes the EIP-712 digest of an order exactly as the contract's hashOrder does.
+// The digest doubles as the order hash stored in the orders table.
+func HashOrder(template apitypes.TypedData, fields OrderFields) (common.Hash, error) {
+	td := apitypes.TypedData{
+		Types:       template.Types,
+		PrimaryType: "Order",
+		Domain:      template.Domain,
+		Message: apitypes.TypedDataMessage{
+			"maker":        strings.ToLower(fields.Maker.Hex()),
+			"nft":          strings.ToLower(fields.NFT.Hex()),
+			"tokenId":      fields.TokenID,
+			"paymentToken": strings.ToLower(fields.PaymentToken.Hex()),
+			"price":        fields.Price,
+			"expiry":       fields.Expiry,
+			"nonce":        fields.Nonce,
+			"side":         big.NewInt(int64(fields.Side)),
+		},
+	}
+	digest, _, err := apitypes.TypedDataAndHash(td)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(digest), nil
+}
@@ -9,24 +9,35 @@ const (
 	OrderStatusActive    OrderStatus = "active"
 	OrderStatusCancelled OrderStatus = "cancelled"
 	OrderStatusFilled    OrderStatus = "filled"
+	// OrderStatusSettling marks orders filled by a trade that has not yet reached the
+	// indexer's confirmation depth; it becomes filled once confirmed, or active again
+	// if the trade is reorged out.
+	OrderStatusSettling OrderStatus = "settling"
+	// OrderStatusInvalid marks orders that can no longer be settled on-chain
+	// (nonce consumed, NFT moved or unapproved); see InvalidReason.
+	OrderStatusInvalid OrderStatus = "invalid"
+	// OrderStatusUnfunded marks bids whose maker's payment token balance or allowance no
+	// longer covers the price; the indexer reactivates them once funding is restored.
+	OrderStatusUnfunded OrderStatus = "unfunded"
 )
 
 // Order models a signed order stored off-chain.
 type Order struct {
-	ID           uint        `gorm:"primaryKey" json:"id"`
-	Maker        string      `gorm:"type:varchar(66);index:idx_orders_maker_nonce,unique" json:"maker"`
-	NFTAddress   string      `gorm:"type:varchar(66);index;column:nft_address" json:"nftAddress"`
-	TokenID      string      `gorm:"type:numeric;column:token_id" json:"tokenId"`
-	PaymentToken string      `gorm:"type:varchar(66);column:payment_token" json:"paymentToken"`
-	Price        string      `gorm:"type:numeric" json:"price"`
-	Expiry       time.Time   `gorm:"index" json:"expiry"`
-	Nonce        string      `gorm:"type:numeric;index:idx_orders_maker_nonce,unique" json:"nonce"`
-	Side         string      `gorm:"type:varchar(4);index" json:"side"`
-	Status       OrderStatus `gorm:"type:varchar(16);index" json:"status"`
-	Signature    string      `gorm:"type:varchar(132)" json:"signature"`
-	Hash         string      `gorm:"type:varchar(66);index" json:"hash"`
-	CreatedAt    time.Time   `json:"createdAt"`
-	UpdatedAt    time.Time   `json:"updatedAt"`
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	Maker         string      `gorm:"type:varchar(66);index:idx_orders_maker_nonce,unique" json:"maker"`
+	NFTAddress    string      `gorm:"type:varchar(66);index;column:nft_address" json:"nftAddress"`
+	TokenID       string      `gorm:"type:numeric;column:token_id" json:"tokenId"`
+	PaymentToken  string      `gorm:"type:varchar(66);column:payment_token" json:"paymentToken"`
+	Price         string      `gorm:"type:numeric" json:"price"`
+	Expiry        time.Time   `gorm:"index" json:"expiry"`
+	Nonce         string      `gorm:"type:numeric;index:idx_orders_maker_nonce,unique" json:"nonce"`
+	Side          string      `gorm:"type:varchar(4);index" json:"side"`
+	Status        OrderStatus `gorm:"type:varchar(16);index" json:"status"`
+	Signature     string      `gorm:"type:varchar(132)" json:"signature"`
+	Hash          string      `gorm:"type:varchar(66);index" json:"hash"`
+	InvalidReason string      `gorm:"type:varchar(255);column:invalid_reason" json:"invalidReason,omitempty"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	UpdatedAt     time.Time   `json:"updatedAt"`
 }
 
 // TableName overrides default table name.
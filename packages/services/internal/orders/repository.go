@@ -26,6 +26,26 @@ func (r *Repository) UpdateStatus(ctx context.Context, id uint, status OrderStat
 	return r.db.WithContext(ctx).Model(&Order{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// MarkInvalid flags an active order as no longer fillable and records why.
+// Orders that already left the active state are left untouched.
+func (r *Repository) MarkInvalid(ctx context.Context, hash string, reason string) (bool, error) {
+	return r.deactivate(ctx, hash, OrderStatusInvalid, reason)
+}
+
+// MarkUnfunded flags an active bid whose maker cannot currently pay for it and records why.
+// Unlike invalid orders, the indexer reactivates it once the funding is restored.
+func (r *Repository) MarkUnfunded(ctx context.Context, hash string, reason string) (bool, error) {
+	return r.deactivate(ctx, hash, OrderStatusUnfunded, reason)
+}
+
+// deactivate moves an active order to status with the given reason.
+func (r *Repository) deactivate(ctx context.Context, hash string, status OrderStatus, reason string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&Order{}).
+		Where("hash = ? AND status = ?", hash, OrderStatusActive).
+		Updates(map[string]any{"status": status, "invalid_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
+
 // ListActive returns orders matching filter criteria.
 func (r *Repository) ListActive(ctx context.Context, side string, collection string) ([]Order, error) {
 	var result []Order
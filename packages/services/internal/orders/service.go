@@ -13,7 +13,6 @@ import (
 	"github.com/Oeasy-NFT/services/internal/postgres"
 	redisutil "github.com/Oeasy-NFT/services/internal/redis"
 	"github.com/ethereum/go-ethereum/common"
-	mathhex "github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/gin-gonic/gin"
 	redis "github.com/redis/go-redis/v9"
@@ -75,37 +74,7 @@ func NewService(cfg *config.Config) (*Service, error) {
 	chainID := new(big.Int).SetUint64(cfg.ChainID)
 	marketplaceAddr := common.HexToAddress(cfg.MarketplaceAddr)
 
-	typedData := apitypes.TypedData{
-		Types: map[string][]apitypes.Type{
-			"EIP712Domain": {
-				{Name: "name", Type: "string"},
-				{Name: "version", Type: "string"},
-				{Name: "chainId", Type: "uint256"},
-				{Name: "verifyingContract", Type: "address"},
-			},
-			"Order": {
-				{Name: "maker", Type: "address"},
-				{Name: "nft", Type: "address"},
-				{Name: "tokenId", Type: "uint256"},
-				{Name: "paymentToken", Type: "address"},
-				{Name: "price", Type: "uint256"},
-				{Name: "expiry", Type: "uint256"},
-				{Name: "nonce", Type: "uint256"},
-				{Name: "side", Type: "uint8"},
-			},
-			"Cancel": {
-				{Name: "maker", Type: "address"},
-				{Name: "nonce", Type: "uint256"},
-			},
-		},
-		PrimaryType: "Order",
-		Domain: apitypes.TypedDataDomain{
-			Name:              "Oeasy Marketplace",
-			Version:           "1",
-			ChainId:           mathhex.NewHexOrDecimal256(int64(cfg.ChainID)),
-			VerifyingContract: marketplaceAddr.Hex(),
-		},
-	}
+	typedData := NewTypedData(cfg.ChainID, marketplaceAddr)
 
 	service := &Service{
 		cfg:         cfg,